@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"gophermart/internal/accrualsim"
+	"gophermart/internal/closer"
+	"gophermart/internal/log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	ctx, cancel := signal.NotifyContext(
+		context.Background(),
+		os.Interrupt,
+		syscall.SIGTERM,
+	)
+	defer cancel()
+
+	cfg, err := accrualsim.NewConfig()
+	if err != nil {
+		log.Fatal(ctx, "failed to read config", err)
+	}
+
+	log.InitDefault(cfg.LogLevel)
+
+	server := accrualsim.New(cfg, accrualsim.NewStorage())
+	server.Start(ctx)
+
+	<-ctx.Done()
+	log.Info(ctx, "received shutdown signal")
+	closer.CloseAll()
+}
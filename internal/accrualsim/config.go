@@ -0,0 +1,38 @@
+package accrualsim
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/caarlos0/env/v11"
+	"github.com/spf13/pflag"
+)
+
+type Config struct {
+	HTTPAddress            string        `env:"RUN_ADDRESS"                          envDefault:"localhost:8080"`
+	LogLevel               string        `env:"LOG_LEVEL"                            envDefault:"info"`
+	RegisteredDelay        time.Duration `env:"ACCRUAL_SIM_REGISTERED_DELAY"         envDefault:"1s"`
+	ProcessingDelay        time.Duration `env:"ACCRUAL_SIM_PROCESSING_DELAY"         envDefault:"2s"`
+	RateLimitProbability   float64       `env:"ACCRUAL_SIM_RATE_LIMIT_PROBABILITY"   envDefault:"0"`
+	ServerErrorProbability float64       `env:"ACCRUAL_SIM_SERVER_ERROR_PROBABILITY" envDefault:"0"`
+	RetryAfter             time.Duration `env:"ACCRUAL_SIM_RETRY_AFTER"              envDefault:"60s"`
+}
+
+func NewConfig() (*Config, error) {
+	cfg := &Config{}
+
+	if err := env.Parse(cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse simulator envs: %w", err)
+	}
+
+	pflag.StringVarP(&cfg.HTTPAddress, "address", "a", cfg.HTTPAddress, "http address")
+	pflag.StringVarP(&cfg.LogLevel, "log_level", "l", cfg.LogLevel, "level for logging")
+	pflag.DurationVar(&cfg.RegisteredDelay, "registered_delay", cfg.RegisteredDelay, "time an order stays REGISTERED")
+	pflag.DurationVar(&cfg.ProcessingDelay, "processing_delay", cfg.ProcessingDelay, "time an order stays PROCESSING")
+	pflag.Float64Var(&cfg.RateLimitProbability, "rate_limit_probability", cfg.RateLimitProbability, "share of order requests answered with 429")
+	pflag.Float64Var(&cfg.ServerErrorProbability, "server_error_probability", cfg.ServerErrorProbability, "share of order requests answered with 500")
+	pflag.DurationVar(&cfg.RetryAfter, "retry_after", cfg.RetryAfter, "Retry-After sent with 429")
+	pflag.Parse()
+
+	return cfg, nil
+}
@@ -0,0 +1,39 @@
+package accrualsim
+
+import (
+	"gophermart/internal/types"
+	"gophermart/pkg/clients/accrual"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	RewardPercent = "%"
+	RewardPoints  = "pt"
+)
+
+// Reward returns the accrual for the goods of an order. Every item is rewarded
+// by the first rule whose match is a substring of its description.
+// ok is false when no item matches any rule, such orders become INVALID.
+func Reward(rules []accrual.GoodsCreate, goods []accrual.Goods) (amount types.Money, ok bool) {
+	for _, item := range goods {
+		for _, rule := range rules {
+			if !strings.Contains(item.Description, rule.Match) {
+				continue
+			}
+
+			ok = true
+			switch rule.RewardType {
+			case RewardPercent:
+				ratio := decimal.New(int64(rule.Reward), -2)
+				amount = amount.Add(types.MoneyFromInt(int64(item.Price)).MulRatio(ratio))
+			case RewardPoints:
+				amount = amount.Add(types.MoneyFromInt(int64(rule.Reward)))
+			}
+			break
+		}
+	}
+
+	return amount.Round(2), ok
+}
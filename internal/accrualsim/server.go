@@ -0,0 +1,154 @@
+package accrualsim
+
+import (
+	"context"
+	"encoding/json"
+	"gophermart/internal/closer"
+	"gophermart/internal/log"
+	"gophermart/pkg/clients/accrual"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+const StatusRegistered = "REGISTERED"
+
+type Server struct {
+	cfg     *Config
+	srv     *http.Server
+	storage *Storage
+	logger  log.HTTPLogger
+}
+
+func New(cfg *Config, storage *Storage) *Server {
+	return &Server{
+		cfg:     cfg,
+		srv:     &http.Server{Addr: cfg.HTTPAddress},
+		storage: storage,
+		logger:  log.NewHTTPLogger("AccrualSimulator"),
+	}
+}
+
+func (s *Server) Routes() *mux.Router {
+	m := mux.NewRouter()
+
+	m.HandleFunc("/api/goods", s.CreateGoods).
+		Methods(http.MethodPost)
+	m.HandleFunc("/api/orders", s.CreateOrder).
+		Methods(http.MethodPost)
+	m.HandleFunc("/api/orders/{number}", s.GetOrder).
+		Methods(http.MethodGet)
+
+	return m
+}
+
+func (s *Server) Start(ctx context.Context) {
+	s.srv.Handler = s.Routes()
+
+	go func() {
+		log.Info(ctx, "starting accrual simulator at "+s.cfg.HTTPAddress)
+		if err := s.srv.ListenAndServe(); err != http.ErrServerClosed {
+			log.Fatal(ctx, "error start accrual simulator", err)
+		}
+	}()
+
+	closer.Add(s.Close)
+}
+
+func (s *Server) Close() error {
+	ctx := context.TODO()
+	if err := s.srv.Shutdown(ctx); err != nil {
+		return errors.Wrapf(err, "failed to shutdown accrual simulator")
+	}
+
+	log.Info(ctx, "accrual simulator shutdown done")
+
+	return nil
+}
+
+func (s *Server) CreateGoods(w http.ResponseWriter, r *http.Request) {
+	var rule accrual.GoodsCreate
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		s.logger.Debug(r, "failed to parse goods: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if rule.Match == "" || rule.Reward < 0 ||
+		(rule.RewardType != RewardPercent && rule.RewardType != RewardPoints) {
+		s.logger.Debug(r, "wrong goods rule: %+v", rule)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err := s.storage.AddRule(rule); err != nil {
+		s.logger.Debug(r, "failed to add goods rule: %s", err)
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) CreateOrder(w http.ResponseWriter, r *http.Request) {
+	var schema accrual.OrderCreate
+	if err := json.NewDecoder(r.Body).Decode(&schema); err != nil {
+		s.logger.Debug(r, "failed to parse order: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if schema.Order == "" {
+		s.logger.Debug(r, "order number is empty")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err := s.storage.AddOrder(schema, time.Now()); err != nil {
+		s.logger.Debug(r, "failed to add order: %s", err)
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) GetOrder(w http.ResponseWriter, r *http.Request) {
+	switch p := rand.Float64(); {
+	case p < s.cfg.RateLimitProbability:
+		w.Header().Set("Retry-After", strconv.Itoa(int(s.cfg.RetryAfter.Seconds())))
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte("No more than N requests per minute allowed"))
+		return
+	case p < s.cfg.RateLimitProbability+s.cfg.ServerErrorProbability:
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	number := mux.Vars(r)["number"]
+
+	orderRead, ok := s.storage.Order(
+		number,
+		time.Now(),
+		s.cfg.RegisteredDelay,
+		s.cfg.ProcessingDelay,
+	)
+	if !ok {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(orderRead); err != nil {
+		s.logger.Error(r, "failed to encode response json", err)
+		return
+	}
+
+	s.logger.Debug(r, "order=%s status=%s", orderRead.Order, orderRead.Status)
+}
@@ -0,0 +1,101 @@
+package accrualsim
+
+import (
+	"gophermart/internal/types"
+	"gophermart/pkg/clients/accrual"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrRuleExists = errors.New("goods rule already registered")
+var ErrOrderExists = errors.New("order already registered")
+
+type order struct {
+	number       string
+	registeredAt time.Time
+	accrual      types.Money
+	valid        bool
+}
+
+type Storage struct {
+	mu     sync.RWMutex
+	rules  []accrual.GoodsCreate
+	orders map[string]*order
+}
+
+func NewStorage() *Storage {
+	return &Storage{
+		rules:  make([]accrual.GoodsCreate, 0),
+		orders: make(map[string]*order),
+	}
+}
+
+func (s *Storage) AddRule(rule accrual.GoodsCreate) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, r := range s.rules {
+		if r.Match == rule.Match {
+			return ErrRuleExists
+		}
+	}
+	s.rules = append(s.rules, rule)
+
+	return nil
+}
+
+// AddOrder registers an order and computes its reward with the rules known at that moment.
+func (s *Storage) AddOrder(schema accrual.OrderCreate, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.orders[schema.Order]; ok {
+		return ErrOrderExists
+	}
+
+	amount, valid := Reward(s.rules, schema.Goods)
+	s.orders[schema.Order] = &order{
+		number:       schema.Order,
+		registeredAt: now,
+		accrual:      amount,
+		valid:        valid,
+	}
+
+	return nil
+}
+
+// Order returns the order as the accrual system reports it at now: REGISTERED
+// for registeredDelay, then PROCESSING for processingDelay, then PROCESSED or INVALID.
+func (s *Storage) Order(
+	number string,
+	now time.Time,
+	registeredDelay time.Duration,
+	processingDelay time.Duration,
+) (*accrual.OrderRead, bool) {
+	s.mu.RLock()
+	o, ok := s.orders[number]
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil, false
+	}
+
+	elapsed := now.Sub(o.registeredAt)
+	orderRead := &accrual.OrderRead{Order: o.number}
+
+	switch {
+	case elapsed < registeredDelay:
+		orderRead.Status = StatusRegistered
+	case elapsed < registeredDelay+processingDelay:
+		orderRead.Status = types.OrderProcessing.String()
+	case !o.valid:
+		orderRead.Status = types.OrderInvalid.String()
+	default:
+		orderRead.Status = types.OrderProcessed.String()
+		orderRead.Accrual = o.accrual
+	}
+
+	return orderRead, true
+}
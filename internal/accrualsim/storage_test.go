@@ -0,0 +1,84 @@
+package accrualsim
+
+import (
+	"gophermart/internal/types"
+	"gophermart/pkg/clients/accrual"
+	"testing"
+	"time"
+)
+
+func TestStorage_Order(t *testing.T) {
+	storage := NewStorage()
+	for _, rule := range []accrual.GoodsCreate{
+		{Match: "Bork", Reward: 10, RewardType: RewardPercent},
+		{Match: "LG", Reward: 50, RewardType: RewardPoints},
+	} {
+		if err := storage.AddRule(rule); err != nil {
+			t.Fatalf("failed to add rule: %s", err)
+		}
+	}
+
+	registeredAt := time.Now()
+	orders := []accrual.OrderCreate{
+		{
+			Order: "12345678903",
+			Goods: []accrual.Goods{
+				{Description: "Чайник Bork", Price: 7000},
+				{Description: "Телевизор LG", Price: 50000},
+				{Description: "Стул", Price: 1000},
+			},
+		},
+		{
+			Order: "2377225624",
+			Goods: []accrual.Goods{{Description: "Стул", Price: 1000}},
+		},
+	}
+	for _, order := range orders {
+		if err := storage.AddOrder(order, registeredAt); err != nil {
+			t.Fatalf("failed to add order: %s", err)
+		}
+	}
+
+	if err := storage.AddOrder(orders[0], registeredAt); err != ErrOrderExists {
+		t.Errorf("duplicate order accepted: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		number      string
+		elapsed     time.Duration
+		wantStatus  string
+		wantAccrual types.Money
+	}{
+		{"Test #1 Registered", "12345678903", 0, StatusRegistered, types.Money{}},
+		{"Test #2 Processing", "12345678903", 2 * time.Second, types.OrderProcessing.String(), types.Money{}},
+		{"Test #3 Processed", "12345678903", 5 * time.Second, types.OrderProcessed.String(), types.MoneyFromInt(750)},
+		{"Test #4 Invalid", "2377225624", 5 * time.Second, types.OrderInvalid.String(), types.Money{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orderRead, ok := storage.Order(
+				tt.number,
+				registeredAt.Add(tt.elapsed),
+				time.Second,
+				2*time.Second,
+			)
+			if !ok {
+				t.Fatalf("order %s not found", tt.number)
+			}
+			if orderRead.Status != tt.wantStatus || !orderRead.Accrual.Equal(tt.wantAccrual) {
+				t.Errorf(
+					"orders are different: got=%s/%s want=%s/%s",
+					orderRead.Status,
+					orderRead.Accrual,
+					tt.wantStatus,
+					tt.wantAccrual,
+				)
+			}
+		})
+	}
+
+	if _, ok := storage.Order("0", registeredAt, 0, 0); ok {
+		t.Error("unknown order found")
+	}
+}
@@ -13,9 +13,15 @@ import (
 	"gophermart/internal/crypto"
 	"gophermart/internal/handlers"
 	"gophermart/internal/http"
+	"gophermart/internal/jobs"
 	"gophermart/internal/log"
+	"gophermart/internal/loyalty"
+	"gophermart/internal/middlewares"
+	"gophermart/internal/models"
 	"gophermart/internal/pipelines"
 	"gophermart/internal/repository"
+	"gophermart/internal/withdrawals"
+	"gophermart/pkg/clients/accrual"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -42,26 +48,91 @@ func Run(ctx context.Context, cfg *config.Config) (err error) {
 		log.Fatal(ctx, "failed to init repos", err)
 	}
 
+	tiers, err := loyalty.ParseTiers(cfg.LoyaltyTiers)
+	if err != nil {
+		log.Fatal(ctx, "failed to parse loyalty tiers", err)
+	}
+	repos.Loyalty = loyalty.NewProgram(tiers, cfg.LoyaltyWindow)
+	repos.Referrals = models.ReferralTerms{
+		Bonus:          cfg.ReferralBonus,
+		MaxRewards:     cfg.ReferralMaxRewards,
+		MinReferrerAge: cfg.ReferralMinReferrerAge,
+		DailySignups:   cfg.ReferralDailySignups,
+	}
+	repos.ClawbackPolicy = cfg.ClawbackPolicy
+	repos.WithdrawalPolicy = withdrawals.Policy{
+		Min:             cfg.WithdrawalMin,
+		Max:             cfg.WithdrawalMax,
+		DailySum:        cfg.WithdrawalDailySum,
+		MonthlySum:      cfg.WithdrawalMonthlySum,
+		FeePercent:      cfg.WithdrawalFeePercent,
+		FeeFixed:        cfg.WithdrawalFeeFixed,
+		ReviewThreshold: cfg.WithdrawalReviewThreshold,
+	}
+
 	// Pipelines
-	pipelines.InitAccrualPipeline(
+	accrualClient := accrual.NewAccrualClient(
 		ctx,
 		cfg.AccrualBaseURL,
 		cfg.AccrualRetryCount,
 		cfg.AccrualRetryWaitTime,
 		cfg.AccrualRetryMaxWaitTime,
-		repos,
-		cfg.AccrualPipelineBufferSize,
-		cfg.AccrualPipelineNumberOfWorkers,
+		accrual.WithRateLimit(cfg.AccrualRateLimit, cfg.AccrualRateLimitBurst),
+		accrual.WithCircuitBreaker(cfg.AccrualBreakerThreshold, cfg.AccrualBreakerOpenTimeout),
 	)
 
+	pipelines.InitAccrualPipeline(cfg, repos, accrualClient)
+
 	pipelines.AccrualPipeline.Start(ctx)
 
+	// Jobs
+	scheduler := jobs.NewScheduler()
+	scheduler.Add(
+		"idempotency keys cleanup",
+		cfg.IdempotencyCleanupInterval,
+		jobs.CleanupIdempotencyKeys(repos.IdempotencyRepo),
+	)
+	scheduler.Add(
+		"expired holds release",
+		cfg.HoldsExpiryInterval,
+		jobs.ReleaseExpiredHolds(repos.HoldsRepo, cfg.HoldsExpiryBatchSize),
+	)
+	if cfg.PointsExpiryMonths > 0 {
+		scheduler.Add(
+			"points expiry",
+			cfg.PointsExpiryInterval,
+			jobs.ExpirePoints(repos.PointLotsRepo, cfg.PointsExpiryMonths, cfg.PointsExpiryBatchSize),
+		)
+	}
+	scheduler.Start(ctx)
+
 	// Handlers bindings
 	healthHandlers := handlers.NewHealthHandlers(repos)
+	healthHandlers.SetAccrualStateProvider(accrualClient)
 	authHandlers := handlers.NewAuthHandlers(repos)
-	balanceHandlers := handlers.NewBalanceHandlers(repos)
+	balanceHandlers := handlers.NewBalanceHandlers(
+		repos,
+		cfg.PointsExpiryMonths,
+		cfg.PointsExpiringSoonWindow,
+		models.TransferLimits{
+			DailySum:   cfg.TransferDailySum,
+			DailyCount: cfg.TransferDailyCount,
+		},
+	)
 	ordersHandlers := handlers.NewOrdersHandlers(repos)
-	withdrawalsHandlers := handlers.NewWithdrawalsHandlers(repos)
+	withdrawalsHandlers := handlers.NewWithdrawalsHandlers(
+		repos,
+		cfg.WithdrawalReversalWindow,
+		cfg.WithdrawalHoldTTL,
+	)
+	deadLettersHandlers := handlers.NewDeadLettersHandlers(repos)
+	campaignsHandlers := handlers.NewCampaignsHandlers(repos)
+	referralsHandlers := handlers.NewReferralsHandlers(repos)
+	callbacksHandlers := handlers.NewCallbacksHandlers(
+		repos,
+		cfg.Security.AccrualCallbackSecret,
+		cfg.Security.AccrualCallbackTolerance,
+	)
 
 	httpServer := http.New(
 		cfg,
@@ -70,6 +141,12 @@ func Run(ctx context.Context, cfg *config.Config) (err error) {
 		balanceHandlers,
 		ordersHandlers,
 		withdrawalsHandlers,
+		deadLettersHandlers,
+		callbacksHandlers,
+		campaignsHandlers,
+		referralsHandlers,
+		middlewares.NewAdminMiddleware(repos.UsersRepo),
+		middlewares.NewIdempotencyMiddleware(repos.IdempotencyRepo, cfg.IdempotencyKeyTTL),
 	)
 
 	// Server start
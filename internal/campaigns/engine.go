@@ -0,0 +1,102 @@
+package campaigns
+
+import (
+	"gophermart/internal/models"
+	"gophermart/internal/types"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// Facts describe the accrual campaigns are evaluated against.
+// OrdersCount counts the processed orders of the user including
+// the credited one.
+type Facts struct {
+	Now         time.Time
+	Amount      types.Money
+	OrdersCount int
+	Tier        string
+}
+
+type Bonus struct {
+	CampaignID string
+	Amount     types.Money
+}
+
+// Evaluate returns the bonuses of campaigns matching facts. Every matching
+// campaign adds its own bonus computed from the accrual system amount,
+// so campaigns never multiply each other.
+func Evaluate(campaigns []models.Campaign, facts Facts) ([]Bonus, error) {
+	bonuses := []Bonus{}
+	for _, campaign := range campaigns {
+		ok, err := matches(campaign, facts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to match campaign %s", campaign.ID)
+		}
+		if !ok {
+			continue
+		}
+
+		amount := bonusAmount(campaign, facts.Amount)
+		if !amount.IsPositive() {
+			continue
+		}
+
+		bonuses = append(bonuses, Bonus{CampaignID: campaign.ID, Amount: amount})
+	}
+
+	return bonuses, nil
+}
+
+func matches(campaign models.Campaign, facts Facts) (bool, error) {
+	if !campaign.Active {
+		return false, nil
+	}
+
+	startsAt, err := time.Parse(time.RFC3339Nano, campaign.StartsAt)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to parse starts_at")
+	}
+	if facts.Now.Before(startsAt) {
+		return false, nil
+	}
+
+	if campaign.EndsAt != nil {
+		endsAt, err := time.Parse(time.RFC3339Nano, *campaign.EndsAt)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to parse ends_at")
+		}
+		if !facts.Now.Before(endsAt) {
+			return false, nil
+		}
+	}
+
+	if campaign.MaxOrders != nil && facts.OrdersCount > *campaign.MaxOrders {
+		return false, nil
+	}
+
+	if campaign.Tier != nil && *campaign.Tier != facts.Tier {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+func bonusAmount(campaign models.Campaign, amount types.Money) types.Money {
+	switch types.CampaignKind(campaign.Kind) {
+	case types.CampaignMultiplier:
+		if campaign.Multiplier == nil {
+			return types.Money{}
+		}
+		ratio := campaign.Multiplier.Decimal().Sub(decimal.NewFromInt(1))
+		return amount.MulRatio(ratio).Round(2)
+	case types.CampaignFixed:
+		if campaign.Points == nil {
+			return types.Money{}
+		}
+		return *campaign.Points
+	default:
+		return types.Money{}
+	}
+}
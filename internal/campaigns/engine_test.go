@@ -0,0 +1,81 @@
+package campaigns
+
+import (
+	"gophermart/internal/models"
+	"gophermart/internal/types"
+	"testing"
+	"time"
+)
+
+func TestEvaluate(t *testing.T) {
+	now := time.Date(2024, 6, 8, 12, 0, 0, 0, time.UTC)
+	weekendStart := now.Add(-12 * time.Hour).Format(time.RFC3339Nano)
+	weekendEnd := now.Add(36 * time.Hour).Format(time.RFC3339Nano)
+	past := now.Add(-time.Hour).Format(time.RFC3339Nano)
+	future := now.Add(time.Hour).Format(time.RFC3339Nano)
+
+	double := types.MoneyFromInt(2)
+	hundred := types.MoneyFromInt(100)
+	first := 1
+	gold := "GOLD"
+
+	doublePoints := models.Campaign{
+		ID: "double", Kind: types.CampaignMultiplier.String(), Multiplier: &double,
+		StartsAt: weekendStart, EndsAt: &weekendEnd, Active: true,
+	}
+	firstOrder := models.Campaign{
+		ID: "first", Kind: types.CampaignFixed.String(), Points: &hundred,
+		StartsAt: weekendStart, MaxOrders: &first, Active: true,
+	}
+	goldOnly := models.Campaign{
+		ID: "gold", Kind: types.CampaignFixed.String(), Points: &hundred,
+		StartsAt: weekendStart, Tier: &gold, Active: true,
+	}
+	ended := doublePoints
+	ended.ID, ended.EndsAt = "ended", &past
+	upcoming := doublePoints
+	upcoming.ID, upcoming.StartsAt = "upcoming", future
+	inactive := firstOrder
+	inactive.ID, inactive.Active = "inactive", false
+
+	campaigns := []models.Campaign{doublePoints, firstOrder, goldOnly, ended, upcoming, inactive}
+
+	tests := []struct {
+		name  string
+		facts Facts
+		want  map[string]types.Money
+	}{
+		{
+			"Test #1 First order",
+			Facts{Now: now, Amount: types.NewMoney(5050, -2), OrdersCount: 1, Tier: "BRONZE"},
+			map[string]types.Money{"double": types.NewMoney(5050, -2), "first": hundred},
+		},
+		{
+			"Test #2 Gold repeat order",
+			Facts{Now: now, Amount: types.MoneyFromInt(10), OrdersCount: 3, Tier: "GOLD"},
+			map[string]types.Money{"double": types.MoneyFromInt(10), "gold": hundred},
+		},
+		{
+			"Test #3 After the weekend",
+			Facts{Now: now.Add(48 * time.Hour), Amount: types.MoneyFromInt(10), OrdersCount: 2},
+			map[string]types.Money{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bonuses, err := Evaluate(campaigns, tt.facts)
+			if err != nil {
+				t.Fatalf("failed to evaluate: %s", err)
+			}
+			if len(bonuses) != len(tt.want) {
+				t.Fatalf("bonuses are different: got=%v want=%v", bonuses, tt.want)
+			}
+			for _, bonus := range bonuses {
+				want, ok := tt.want[bonus.CampaignID]
+				if !ok || !bonus.Amount.Equal(want) {
+					t.Errorf("bonus of %s is different: got=%s want=%s", bonus.CampaignID, bonus.Amount, want)
+				}
+			}
+		})
+	}
+}
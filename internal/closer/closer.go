@@ -29,9 +29,11 @@ func (c *Closer) Add(f ...func() error) {
 	c.funcs = append(c.funcs, f...)
 }
 
+// CloseAll runs the funcs in reverse order of adding, so everything
+// is closed before the resources it was started on, e.g. the database.
 func (c *Closer) CloseAll() {
-	for _, f := range c.funcs {
-		if err := f(); err != nil {
+	for i := len(c.funcs) - 1; i >= 0; i-- {
+		if err := c.funcs[i](); err != nil {
 			log.Error(context.TODO(), "error close", err)
 		}
 	}
@@ -2,9 +2,11 @@ package config
 
 import (
 	"fmt"
+	"gophermart/internal/types"
 	"time"
 
 	"github.com/caarlos0/env/v11"
+	"github.com/shopspring/decimal"
 )
 
 type Environment string
@@ -24,23 +26,64 @@ type Postgres struct {
 }
 
 type Security struct {
-	JWTSecretKey string        `env:"JWT_SECRET_KEY" envDefault:"unsecured_key"`
-	JWTExpire    time.Duration `env:"JWT_EXPIRE" envDefault:"12h"`
+	JWTSecretKey             string        `env:"JWT_SECRET_KEY" envDefault:"unsecured_key"`
+	JWTExpire                time.Duration `env:"JWT_EXPIRE" envDefault:"12h"`
+	AccrualCallbackSecret    string        `env:"ACCRUAL_CALLBACK_SECRET" envDefault:""`
+	AccrualCallbackTolerance time.Duration `env:"ACCRUAL_CALLBACK_TOLERANCE" envDefault:"5m"`
 }
 
 type Config struct {
-	AppEnv                         Environment   `env:"APP_ENVIRONMENT" envDefault:"local" flag:"mode" flagShort:"m" flagDescription:"environment"`
-	HTTPAddress                    string        `env:"RUN_ADDRESS" envDefault:"localhost:8081" flag:"address" flagShort:"a" flagDescription:"http address"`
-	LogLevel                       string        `env:"LOG_LEVEL" envDefault:"info" flag:"log_level" flagShort:"l" flagDescription:"level for logging"`
-	LogFile                        string        `env:"LOG_FILE" envDefault:"logs/logs.jsonl" flag:"log_file"  flagShort:"w" flagDescription:"filepath for logs"`
-	Postgres                       Postgres      `envPrefix:"DATABASE_" flag:"pg_dsn" flagShort:"d" flagDescription:"database dsn"`
-	Security                       Security      `envPrefix:"SECURITY_" flag:"jwt_secret_key" flagShort:"j" flagDescription:"jwt secret key"`
-	AccrualBaseURL                 string        `env:"ACCRUAL_SYSTEM_ADDRESS" envDefault:"localhost:8080" flag:"accrual_address" flagShort:"r" flagDescription:"accrual address"`
-	AccrualRetryCount              int           `env:"ACCRUAL_RETRY_COUNT" envDefault:"3"`
-	AccrualRetryWaitTime           time.Duration `env:"ACCRUAL_RETRY_WAIT_TIME" envDefault:"1s"`
-	AccrualRetryMaxWaitTime        time.Duration `env:"ACCRUAL_RETRY_MAX_WAIT_TIME" envDefault:"10s"`
-	AccrualPipelineBufferSize      int           `env:"ACCRUAL_PIPELINE_BUFFER_SIZE" envDefault:"10"`
-	AccrualPipelineNumberOfWorkers int           `env:"ACCRUAL_PIPELINE_NUMBER_OF_WORKERS" envDefault:"10"`
+	AppEnv                         Environment          `env:"APP_ENVIRONMENT" envDefault:"local" flag:"mode" flagShort:"m" flagDescription:"environment"`
+	HTTPAddress                    string               `env:"RUN_ADDRESS" envDefault:"localhost:8081" flag:"address" flagShort:"a" flagDescription:"http address"`
+	LogLevel                       string               `env:"LOG_LEVEL" envDefault:"info" flag:"log_level" flagShort:"l" flagDescription:"level for logging"`
+	LogFile                        string               `env:"LOG_FILE" envDefault:"logs/logs.jsonl" flag:"log_file"  flagShort:"w" flagDescription:"filepath for logs"`
+	Postgres                       Postgres             `envPrefix:"DATABASE_" flag:"pg_dsn" flagShort:"d" flagDescription:"database dsn"`
+	Security                       Security             `envPrefix:"SECURITY_" flag:"jwt_secret_key" flagShort:"j" flagDescription:"jwt secret key"`
+	AccrualBaseURL                 string               `env:"ACCRUAL_SYSTEM_ADDRESS" envDefault:"localhost:8080" flag:"accrual_address" flagShort:"r" flagDescription:"accrual address"`
+	AccrualRetryCount              int                  `env:"ACCRUAL_RETRY_COUNT" envDefault:"3"`
+	AccrualRetryWaitTime           time.Duration        `env:"ACCRUAL_RETRY_WAIT_TIME" envDefault:"1s"`
+	AccrualRetryMaxWaitTime        time.Duration        `env:"ACCRUAL_RETRY_MAX_WAIT_TIME" envDefault:"10s"`
+	AccrualRateLimit               float64              `env:"ACCRUAL_RATE_LIMIT" envDefault:"50"`
+	AccrualRateLimitBurst          int                  `env:"ACCRUAL_RATE_LIMIT_BURST" envDefault:"10"`
+	AccrualBreakerThreshold        int                  `env:"ACCRUAL_BREAKER_THRESHOLD" envDefault:"5"`
+	AccrualBreakerOpenTimeout      time.Duration        `env:"ACCRUAL_BREAKER_OPEN_TIMEOUT" envDefault:"30s"`
+	AccrualPipelineBufferSize      int                  `env:"ACCRUAL_PIPELINE_BUFFER_SIZE" envDefault:"10"`
+	AccrualPipelineNumberOfWorkers int                  `env:"ACCRUAL_PIPELINE_NUMBER_OF_WORKERS" envDefault:"10"`
+	AccrualPipelineSweepInterval   time.Duration        `env:"ACCRUAL_PIPELINE_SWEEP_INTERVAL" envDefault:"1s"`
+	AccrualPipelineSweepBatchSize  int                  `env:"ACCRUAL_PIPELINE_SWEEP_BATCH_SIZE" envDefault:"100"`
+	AccrualPipelineBackoffBase     time.Duration        `env:"ACCRUAL_PIPELINE_BACKOFF_BASE" envDefault:"1s"`
+	AccrualPipelineBackoffMax      time.Duration        `env:"ACCRUAL_PIPELINE_BACKOFF_MAX" envDefault:"5m"`
+	AccrualPipelineMaxAge          time.Duration        `env:"ACCRUAL_PIPELINE_MAX_AGE" envDefault:"72h"`
+	AccrualPipelineReplicaID       string               `env:"ACCRUAL_PIPELINE_REPLICA_ID" envDefault:""`
+	AccrualPipelineLeaseTTL        time.Duration        `env:"ACCRUAL_PIPELINE_LEASE_TTL" envDefault:"1m"`
+	AccrualPipelineMaxAttempts     int                  `env:"ACCRUAL_PIPELINE_MAX_ATTEMPTS" envDefault:"20"`
+	AccrualPipelineDrainTimeout    time.Duration        `env:"ACCRUAL_PIPELINE_DRAIN_TIMEOUT" envDefault:"30s"`
+	IdempotencyKeyTTL              time.Duration        `env:"IDEMPOTENCY_KEY_TTL" envDefault:"24h"`
+	IdempotencyCleanupInterval     time.Duration        `env:"IDEMPOTENCY_CLEANUP_INTERVAL" envDefault:"1h"`
+	WithdrawalReversalWindow       time.Duration        `env:"WITHDRAWAL_REVERSAL_WINDOW" envDefault:"24h"`
+	WithdrawalHoldTTL              time.Duration        `env:"WITHDRAWAL_HOLD_TTL" envDefault:"15m"`
+	HoldsExpiryInterval            time.Duration        `env:"HOLDS_EXPIRY_INTERVAL" envDefault:"1m"`
+	HoldsExpiryBatchSize           int                  `env:"HOLDS_EXPIRY_BATCH_SIZE" envDefault:"100"`
+	PointsExpiryMonths             int                  `env:"POINTS_EXPIRY_MONTHS" envDefault:"0"`
+	PointsExpiringSoonWindow       time.Duration        `env:"POINTS_EXPIRING_SOON_WINDOW" envDefault:"720h"`
+	PointsExpiryInterval           time.Duration        `env:"POINTS_EXPIRY_INTERVAL" envDefault:"1h"`
+	PointsExpiryBatchSize          int                  `env:"POINTS_EXPIRY_BATCH_SIZE" envDefault:"100"`
+	LoyaltyTiers                   string               `env:"LOYALTY_TIERS" envDefault:"BRONZE:0:1,SILVER:1000:1.05,GOLD:5000:1.1"`
+	LoyaltyWindow                  time.Duration        `env:"LOYALTY_WINDOW" envDefault:"8760h"`
+	TransferDailySum               types.Money          `env:"TRANSFER_DAILY_SUM" envDefault:"1000"`
+	TransferDailyCount             int                  `env:"TRANSFER_DAILY_COUNT" envDefault:"10"`
+	ReferralBonus                  types.Money          `env:"REFERRAL_BONUS" envDefault:"100"`
+	ReferralMaxRewards             int                  `env:"REFERRAL_MAX_REWARDS" envDefault:"50"`
+	ReferralMinReferrerAge         time.Duration        `env:"REFERRAL_MIN_REFERRER_AGE" envDefault:"24h"`
+	ReferralDailySignups           int                  `env:"REFERRAL_DAILY_SIGNUPS" envDefault:"10"`
+	ClawbackPolicy                 types.ClawbackPolicy `env:"CLAWBACK_POLICY" envDefault:"ALLOW_DEBT"`
+	WithdrawalMin                  types.Money          `env:"WITHDRAWAL_MIN" envDefault:"0"`
+	WithdrawalMax                  types.Money          `env:"WITHDRAWAL_MAX" envDefault:"0"`
+	WithdrawalDailySum             types.Money          `env:"WITHDRAWAL_DAILY_SUM" envDefault:"0"`
+	WithdrawalMonthlySum           types.Money          `env:"WITHDRAWAL_MONTHLY_SUM" envDefault:"0"`
+	WithdrawalFeePercent           decimal.Decimal      `env:"WITHDRAWAL_FEE_PERCENT" envDefault:"0"`
+	WithdrawalFeeFixed             types.Money          `env:"WITHDRAWAL_FEE_FIXED" envDefault:"0"`
+	WithdrawalReviewThreshold      types.Money          `env:"WITHDRAWAL_REVIEW_THRESHOLD" envDefault:"0"`
 }
 
 func NewConfig() (*Config, error) {
@@ -47,6 +47,9 @@ func ParseFlags(cfg *Config) {
 		flagName := field.Tag.Get("flag")
 		flagShort := field.Tag.Get("flagShort")
 		flagDescription := field.Tag.Get("flagDescription")
+		if flagName == "" {
+			continue
+		}
 
 		fieldNames[field.Name] = flagName
 
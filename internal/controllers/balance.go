@@ -2,8 +2,12 @@ package controllers
 
 import (
 	"context"
+	"encoding/json"
+	"gophermart/internal/exceptions"
 	"gophermart/internal/models"
 	"gophermart/internal/repository"
+	"gophermart/internal/types"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -27,3 +31,143 @@ func (c *BalanceControllerImpl) GetForUser(
 
 	return balance, nil
 }
+
+// ExpiringSoon returns the points expiring within window under the policy
+// of months, nothing when points never expire.
+func (c *BalanceControllerImpl) ExpiringSoon(
+	ctx context.Context,
+	userID string,
+	months int,
+	window time.Duration,
+) (*models.ExpiringPoints, error) {
+	if months <= 0 {
+		return &models.ExpiringPoints{}, nil
+	}
+
+	expiring, err := c.repos.PointLotsRepo.Expiring(ctx, userID, months, time.Now().Add(window))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get expiring points")
+	}
+
+	return expiring, nil
+}
+
+func (c *BalanceControllerImpl) Ledger(
+	ctx context.Context,
+	userID string,
+	page *models.Page,
+) (*[]models.LedgerEntry, error) {
+	entries, err := c.repos.LedgerRepo.UserEntries(ctx, userID, page.Limit, page.Offset)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get ledger entries")
+	}
+
+	return entries, nil
+}
+
+// Tier shows the tier the points accrued by the user within the program
+// window reach, which is the one their next accrual is credited in, and
+// how far they are from the next one.
+func (c *BalanceControllerImpl) Tier(
+	ctx context.Context,
+	userID string,
+) (*models.TierRead, error) {
+	program := c.repos.Loyalty
+	if program == nil {
+		return nil, exceptions.ErrLoyaltyDisabled
+	}
+
+	userTier, err := c.repos.LoyaltyRepo.UserTier(ctx, userID, program.Window())
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get user tier")
+	}
+
+	tier := program.Classify(userTier.Accrued)
+
+	tierRead := &models.TierRead{
+		Tier:       tier.Name,
+		Multiplier: json.Number(tier.Multiplier.String()),
+		Accrued:    userTier.Accrued,
+	}
+
+	if next, ok := program.Next(tier.Name); ok {
+		toNext := next.Threshold.Sub(userTier.Accrued)
+		if toNext.IsNegative() {
+			toNext = types.Money{}
+		}
+
+		tierRead.NextTier = &next.Name
+		tierRead.NextThreshold = &next.Threshold
+		tierRead.ToNextTier = &toNext
+	}
+
+	return tierRead, nil
+}
+
+func (c *BalanceControllerImpl) Transfer(
+	ctx context.Context,
+	transfer *models.Transfer,
+	recipientLogin string,
+	limits models.TransferLimits,
+) (*models.Transfer, error) {
+	created, err := c.repos.TransfersRepo.Create(ctx, transfer, recipientLogin, limits)
+	if err != nil {
+		switch {
+		case errors.Is(err, exceptions.ErrRecipientNotFound),
+			errors.Is(err, exceptions.ErrTransferToSelf),
+			errors.Is(err, exceptions.ErrBalanceIsNegative),
+			errors.Is(err, exceptions.ErrWithdrawalsBlocked),
+			errors.Is(err, exceptions.ErrTransferLimitExceeded):
+			return nil, err
+		default:
+			return nil, errors.Wrapf(err, "failed to create transfer")
+		}
+	}
+
+	return created, nil
+}
+
+func (c *BalanceControllerImpl) Transfers(
+	ctx context.Context,
+	userID string,
+	page *models.Page,
+) (*[]models.TransferRead, error) {
+	transfers, err := c.repos.TransfersRepo.UserTransfers(ctx, userID, page.Limit, page.Offset)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get transfers")
+	}
+
+	return transfers, nil
+}
+
+func (c *BalanceControllerImpl) Adjust(
+	ctx context.Context,
+	adjustment *models.Adjustment,
+) (*models.Adjustment, error) {
+	adjustment, err := c.repos.AdjustmentsRepo.Create(ctx, adjustment)
+	if err != nil {
+		switch {
+		case errors.Is(err, exceptions.ErrUserNotFound):
+			return nil, exceptions.ErrUserNotFound
+		case errors.Is(err, exceptions.ErrBalanceIsNegative):
+			return nil, exceptions.ErrBalanceIsNegative
+		default:
+			return nil, errors.Wrapf(err, "failed to adjust balance")
+		}
+	}
+
+	return adjustment, nil
+}
+
+func (c *BalanceControllerImpl) Adjustments(
+	ctx context.Context,
+	filter *models.AdjustmentsFilter,
+	page *models.Page,
+) (*[]models.Adjustment, error) {
+	adjustments, err := c.repos.AdjustmentsRepo.List(ctx, *filter, page.Limit, page.Offset)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get adjustments")
+	}
+
+	return adjustments, nil
+}
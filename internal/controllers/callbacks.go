@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"context"
+	"gophermart/internal/exceptions"
+	"gophermart/internal/log"
+	"gophermart/internal/models"
+	"gophermart/internal/pipelines"
+	"gophermart/internal/repository"
+	"gophermart/internal/types"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// forgetTimeout bounds forgetting a callback that failed to apply, which
+// outlives the request.
+const forgetTimeout = 5 * time.Second
+
+type CallbacksControllerImpl struct {
+	repos     *repository.Repos
+	tolerance time.Duration
+}
+
+func NewCallbacksController(repos *repository.Repos, tolerance time.Duration) *CallbacksControllerImpl {
+	return &CallbacksControllerImpl{repos: repos, tolerance: tolerance}
+}
+
+func (c *CallbacksControllerImpl) Apply(
+	ctx context.Context,
+	callback *models.AccrualCallback,
+) (*models.Order, error) {
+	order, err := c.repos.OrdersRepo.GetByNumber(ctx, callback.Order.Order)
+	if err != nil {
+		if errors.Is(err, exceptions.ErrOrderNotFound) {
+			return nil, exceptions.ErrOrderNotFound
+		}
+		return nil, errors.Wrapf(err, "failed to get order")
+	}
+
+	// Signatures cover the timestamp, so a replay within the tolerance
+	// window is the only one left to catch, twice the window is enough.
+	err = c.repos.CallbacksRepo.Register(ctx, callback.Signature, order.Number, 2*c.tolerance)
+	if err != nil {
+		if errors.Is(err, exceptions.ErrCallbackReplayed) {
+			return nil, exceptions.ErrCallbackReplayed
+		}
+		return nil, errors.Wrapf(err, "failed to register callback")
+	}
+
+	if err := pipelines.AccrualPipeline.ApplyStatus(ctx, order, &callback.Order, types.SourceWebhook); err != nil {
+		// Applying a status is idempotent, so the accrual system may
+		// safely retry this delivery.
+		forgetCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), forgetTimeout)
+		defer cancel()
+		if err := c.repos.CallbacksRepo.Forget(forgetCtx, callback.Signature); err != nil {
+			log.Error(ctx, "failed to forget callback", err)
+		}
+		return nil, errors.Wrapf(err, "failed to apply callback")
+	}
+
+	return order, nil
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"context"
+	"gophermart/internal/exceptions"
+	"gophermart/internal/models"
+	"gophermart/internal/repository"
+
+	"github.com/pkg/errors"
+)
+
+type CampaignsControllerImpl struct {
+	repos *repository.Repos
+}
+
+func NewCampaignsController(repos *repository.Repos) *CampaignsControllerImpl {
+	return &CampaignsControllerImpl{repos: repos}
+}
+
+func (c *CampaignsControllerImpl) Create(
+	ctx context.Context,
+	campaign *models.Campaign,
+) (*models.Campaign, error) {
+	created, err := c.repos.CampaignsRepo.Create(ctx, campaign)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create campaign")
+	}
+
+	return created, nil
+}
+
+func (c *CampaignsControllerImpl) List(
+	ctx context.Context,
+	page *models.Page,
+) (*[]models.Campaign, error) {
+	campaigns, err := c.repos.CampaignsRepo.List(ctx, page.Limit, page.Offset)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get campaigns")
+	}
+
+	return campaigns, nil
+}
+
+func (c *CampaignsControllerImpl) Get(
+	ctx context.Context,
+	campaignID string,
+) (*models.Campaign, error) {
+	campaign, err := c.repos.CampaignsRepo.Get(ctx, campaignID)
+	if err != nil {
+		if errors.Is(err, exceptions.ErrCampaignNotFound) {
+			return nil, err
+		}
+		return nil, errors.Wrapf(err, "failed to get campaign")
+	}
+
+	return campaign, nil
+}
+
+func (c *CampaignsControllerImpl) Update(
+	ctx context.Context,
+	campaign *models.Campaign,
+) (*models.Campaign, error) {
+	updated, err := c.repos.CampaignsRepo.Update(ctx, campaign)
+	if err != nil {
+		if errors.Is(err, exceptions.ErrCampaignNotFound) {
+			return nil, err
+		}
+		return nil, errors.Wrapf(err, "failed to update campaign")
+	}
+
+	return updated, nil
+}
+
+func (c *CampaignsControllerImpl) Delete(
+	ctx context.Context,
+	campaignID string,
+) error {
+	err := c.repos.CampaignsRepo.Delete(ctx, campaignID)
+	if err != nil {
+		switch {
+		case errors.Is(err, exceptions.ErrCampaignNotFound),
+			errors.Is(err, exceptions.ErrCampaignInUse):
+			return err
+		default:
+			return errors.Wrapf(err, "failed to delete campaign")
+		}
+	}
+
+	return nil
+}
+
+func (c *CampaignsControllerImpl) Bonuses(
+	ctx context.Context,
+	campaignID string,
+	page *models.Page,
+) (*[]models.CampaignBonus, error) {
+	bonuses, err := c.repos.CampaignsRepo.Bonuses(ctx, campaignID, page.Limit, page.Offset)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get campaign bonuses")
+	}
+
+	return bonuses, nil
+}
+
+func (c *CampaignsControllerImpl) ReverseBonus(
+	ctx context.Context,
+	bonusID string,
+) (*models.CampaignBonus, error) {
+	bonus, err := c.repos.CampaignsRepo.ReverseBonus(ctx, bonusID)
+	if err != nil {
+		switch {
+		case errors.Is(err, exceptions.ErrCampaignBonusNotFound),
+			errors.Is(err, exceptions.ErrCampaignBonusAlreadyReversed),
+			errors.Is(err, exceptions.ErrBalanceIsNegative):
+			return nil, err
+		default:
+			return nil, errors.Wrapf(err, "failed to reverse campaign bonus")
+		}
+	}
+
+	return bonus, nil
+}
@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"context"
+	"gophermart/internal/exceptions"
+	"gophermart/internal/models"
+	"gophermart/internal/pipelines"
+	"gophermart/internal/repository"
+
+	"github.com/pkg/errors"
+)
+
+type DeadLettersControllerImpl struct {
+	repos *repository.Repos
+}
+
+func NewDeadLettersController(repos *repository.Repos) *DeadLettersControllerImpl {
+	return &DeadLettersControllerImpl{repos: repos}
+}
+
+func (c *DeadLettersControllerImpl) List(
+	ctx context.Context,
+	page *models.Page,
+) (*[]models.DeadLetter, error) {
+	deadLetters, err := c.repos.DeadLettersRepo.List(ctx, page.Limit, page.Offset)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get dead letters")
+	}
+
+	return deadLetters, nil
+}
+
+func (c *DeadLettersControllerImpl) Replay(
+	ctx context.Context,
+	deadLetterID string,
+) (*models.Order, error) {
+	order, err := c.repos.DeadLettersRepo.Replay(ctx, deadLetterID)
+	if err != nil {
+		switch {
+		case errors.Is(err, exceptions.ErrDeadLetterNotFound):
+			return nil, exceptions.ErrDeadLetterNotFound
+		case errors.Is(err, exceptions.ErrOrderIsFinal):
+			return nil, exceptions.ErrOrderIsFinal
+		default:
+			return nil, errors.Wrapf(err, "failed to replay dead letter")
+		}
+	}
+
+	pipelines.AccrualPipeline.RegisterOrder(ctx, order)
+
+	return order, nil
+}
+
+func (c *DeadLettersControllerImpl) Discard(
+	ctx context.Context,
+	deadLetterID string,
+) (*models.DeadLetter, error) {
+	deadLetter, err := c.repos.DeadLettersRepo.Discard(ctx, deadLetterID)
+	if err != nil {
+		if errors.Is(err, exceptions.ErrDeadLetterNotFound) {
+			return nil, exceptions.ErrDeadLetterNotFound
+		}
+		return nil, errors.Wrapf(err, "failed to discard dead letter")
+	}
+
+	return deadLetter, nil
+}
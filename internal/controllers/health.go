@@ -3,12 +3,19 @@ package controllers
 import (
 	"context"
 	"gophermart/internal/repository"
+	"gophermart/pkg/clients/accrual"
 	"sync"
 )
 
+type AccrualStateProvider interface {
+	BreakerState() accrual.BreakerState
+}
+
 type HealthControllerImpl struct {
 	repos *repository.Repos
 
+	accrualState AccrualStateProvider
+
 	readinessMu sync.RWMutex
 	readiness   bool
 	livenessMu  sync.RWMutex
@@ -31,6 +38,19 @@ func (h *HealthControllerImpl) SetLiveness(state bool) {
 	h.liveness = state
 }
 
+func (h *HealthControllerImpl) SetAccrualStateProvider(provider AccrualStateProvider) {
+	h.accrualState = provider
+}
+
+// AccrualDegraded reports whether calls to the accrual system are being cut off.
+func (h *HealthControllerImpl) AccrualDegraded() bool {
+	if h.accrualState == nil {
+		return false
+	}
+
+	return h.accrualState.BreakerState() != accrual.BreakerClosed
+}
+
 func (h *HealthControllerImpl) ReadinessState() bool {
 	h.readinessMu.RLock()
 	defer h.readinessMu.RUnlock()
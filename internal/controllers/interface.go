@@ -3,11 +3,14 @@ package controllers
 import (
 	"context"
 	"gophermart/internal/models"
+	"time"
 )
 
 type HealthController interface {
 	SetReadiness(state bool)
 	SetLiveness(state bool)
+	SetAccrualStateProvider(provider AccrualStateProvider)
+	AccrualDegraded() bool
 	ReadinessState() bool
 	LivenessState() bool
 	PingDB() bool
@@ -22,13 +25,63 @@ type OrdersController interface {
 	Create(ctx context.Context, schema *models.Order) (*models.Order, error)
 	UserOrders(ctx context.Context, userID string) (*[]models.Order, error)
 	GetUserOrderByNumber(ctx context.Context, userID string, orderNumber uint64) (*models.Order, error)
+	UserOrderHistory(ctx context.Context, userID string, orderNumber uint64) (*[]models.OrderStatusHistory, error)
+	Revoke(ctx context.Context, orderNumber uint64) (*models.Clawback, error)
 }
 
 type BalanceController interface {
 	GetForUser(ctx context.Context, userID string) (*models.Balance, error)
+	ExpiringSoon(ctx context.Context, userID string, months int, window time.Duration) (*models.ExpiringPoints, error)
+	Tier(ctx context.Context, userID string) (*models.TierRead, error)
+	Transfer(
+		ctx context.Context,
+		transfer *models.Transfer,
+		recipientLogin string,
+		limits models.TransferLimits,
+	) (*models.Transfer, error)
+	Transfers(ctx context.Context, userID string, page *models.Page) (*[]models.TransferRead, error)
+	Ledger(ctx context.Context, userID string, page *models.Page) (*[]models.LedgerEntry, error)
+	Adjust(ctx context.Context, adjustment *models.Adjustment) (*models.Adjustment, error)
+	Adjustments(ctx context.Context, filter *models.AdjustmentsFilter, page *models.Page) (*[]models.Adjustment, error)
 }
 
 type WithdrawalController interface {
 	Create(ctx context.Context, schema *models.Withdrawal) (*models.Withdrawal, error)
 	UserWithdrawals(ctx context.Context, userID string) (*[]models.Withdrawal, error)
+	Reverse(
+		ctx context.Context,
+		reversal *models.WithdrawalReversal,
+		ownerID string,
+		window time.Duration,
+	) (*models.Withdrawal, error)
+	PendingReviews(ctx context.Context, page *models.Page) (*[]models.Withdrawal, error)
+	Approve(ctx context.Context, review *models.WithdrawalReview) (*models.Withdrawal, error)
+	Reject(ctx context.Context, review *models.WithdrawalReview) (*models.Withdrawal, error)
+	CreateHold(ctx context.Context, hold *models.Hold) (*models.Hold, error)
+	CaptureHold(ctx context.Context, holdID string, userID string) (*models.Hold, error)
+	ReleaseHold(ctx context.Context, holdID string, userID string) (*models.Hold, error)
+}
+
+type CampaignsController interface {
+	Create(ctx context.Context, campaign *models.Campaign) (*models.Campaign, error)
+	List(ctx context.Context, page *models.Page) (*[]models.Campaign, error)
+	Get(ctx context.Context, campaignID string) (*models.Campaign, error)
+	Update(ctx context.Context, campaign *models.Campaign) (*models.Campaign, error)
+	Delete(ctx context.Context, campaignID string) error
+	Bonuses(ctx context.Context, campaignID string, page *models.Page) (*[]models.CampaignBonus, error)
+	ReverseBonus(ctx context.Context, bonusID string) (*models.CampaignBonus, error)
+}
+
+type ReferralsController interface {
+	UserReferrals(ctx context.Context, userID string, page *models.Page) (*models.ReferralsRead, error)
+}
+
+type DeadLettersController interface {
+	List(ctx context.Context, page *models.Page) (*[]models.DeadLetter, error)
+	Replay(ctx context.Context, deadLetterID string) (*models.Order, error)
+	Discard(ctx context.Context, deadLetterID string) (*models.DeadLetter, error)
+}
+
+type CallbacksController interface {
+	Apply(ctx context.Context, callback *models.AccrualCallback) (*models.Order, error)
 }
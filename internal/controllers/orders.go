@@ -6,12 +6,14 @@ import (
 	"gophermart/internal/models"
 	"gophermart/internal/pipelines"
 	"gophermart/internal/repository"
+	"gophermart/internal/types"
+	"strconv"
 
 	"github.com/pkg/errors"
 )
 
 type AccrualPipeline interface {
-	RegisterOrder(order *models.Order)
+	RegisterOrder(ctx context.Context, order *models.Order)
 }
 
 type OrdersControllerImpl struct {
@@ -47,7 +49,7 @@ func (c *OrdersControllerImpl) Create(
 		return nil, errors.Wrapf(err, "failed to create order")
 	}
 
-	pipelines.AccrualPipeline.RegisterOrder(order)
+	pipelines.AccrualPipeline.RegisterOrder(ctx, order)
 
 	return order, nil
 }
@@ -84,3 +86,47 @@ func (c *OrdersControllerImpl) GetUserOrderByNumber(
 
 	return order, nil
 }
+
+func (c *OrdersControllerImpl) UserOrderHistory(
+	ctx context.Context,
+	userID string,
+	orderNumber uint64,
+) (*[]models.OrderStatusHistory, error) {
+	order, err := c.GetUserOrderByNumber(ctx, userID, orderNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := c.repos.OrderHistoryRepo.List(ctx, order.ID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get order history")
+	}
+
+	return history, nil
+}
+
+// Revoke takes back the accrual of a processed order on behalf of an admin.
+func (c *OrdersControllerImpl) Revoke(
+	ctx context.Context,
+	orderNumber uint64,
+) (*models.Clawback, error) {
+	clawback, err := c.repos.OrdersRepo.Revoke(ctx, models.RevokeRecord{
+		Number: strconv.FormatUint(orderNumber, 10),
+		Change: models.StatusChange{Source: types.SourceAdmin},
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, exceptions.ErrOrderNotFound),
+			errors.Is(err, exceptions.ErrOrderNotProcessed):
+			return nil, err
+		default:
+			return nil, errors.Wrapf(err, "failed to revoke order")
+		}
+	}
+
+	if clawback == nil {
+		return nil, exceptions.ErrOrderAlreadyRevoked
+	}
+
+	return clawback, nil
+}
@@ -0,0 +1,30 @@
+package controllers
+
+import (
+	"context"
+	"gophermart/internal/models"
+	"gophermart/internal/repository"
+
+	"github.com/pkg/errors"
+)
+
+type ReferralsControllerImpl struct {
+	repos *repository.Repos
+}
+
+func NewReferralsController(repos *repository.Repos) *ReferralsControllerImpl {
+	return &ReferralsControllerImpl{repos: repos}
+}
+
+func (c *ReferralsControllerImpl) UserReferrals(
+	ctx context.Context,
+	userID string,
+	page *models.Page,
+) (*models.ReferralsRead, error) {
+	referrals, err := c.repos.ReferralsRepo.UserReferrals(ctx, userID, page.Limit, page.Offset)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get referrals")
+	}
+
+	return referrals, nil
+}
@@ -5,6 +5,9 @@ import (
 	"gophermart/internal/exceptions"
 	"gophermart/internal/models"
 	"gophermart/internal/repository"
+	"gophermart/internal/types"
+	"gophermart/internal/withdrawals"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -17,15 +20,28 @@ func NewWithdrawalsController(repos *repository.Repos) *WithdrawalsControllerImp
 	return &WithdrawalsControllerImpl{repos: repos}
 }
 
+// Create checks the withdrawal against the withdrawal policy before
+// making it. Broken rules come back as *withdrawals.Violation. Large
+// withdrawals come back PENDING_REVIEW with their points held.
 func (c *WithdrawalsControllerImpl) Create(
 	ctx context.Context,
 	schema *models.Withdrawal,
 ) (*models.Withdrawal, error) {
+	if err := c.repos.WithdrawalPolicy.Check(schema.Sum); err != nil {
+		return nil, err
+	}
+
 	withdrawal, err := c.repos.WithdrawalsRepo.Create(ctx, schema)
 	if err != nil {
-		if errors.Is(err, exceptions.ErrBalanceIsNegative) {
+		var violation *withdrawals.Violation
+		switch {
+		case errors.As(err, &violation):
+			return nil, violation
+		case errors.Is(err, exceptions.ErrBalanceIsNegative):
 			return nil, exceptions.ErrBalanceIsNegative
-		} else {
+		case errors.Is(err, exceptions.ErrWithdrawalsBlocked):
+			return nil, exceptions.ErrWithdrawalsBlocked
+		default:
 			return nil, errors.Wrapf(err, "failed to create withdrawal record")
 		}
 	}
@@ -44,3 +60,146 @@ func (c *WithdrawalsControllerImpl) UserWithdrawals(
 
 	return withdrawals, nil
 }
+
+func (c *WithdrawalsControllerImpl) Reverse(
+	ctx context.Context,
+	reversal *models.WithdrawalReversal,
+	ownerID string,
+	window time.Duration,
+) (*models.Withdrawal, error) {
+	withdrawal, err := c.repos.WithdrawalsRepo.Reverse(ctx, reversal, ownerID, window)
+	if err != nil {
+		switch {
+		case errors.Is(err, exceptions.ErrWithdrawalNotFound),
+			errors.Is(err, exceptions.ErrWithdrawalAlreadyReversed),
+			errors.Is(err, exceptions.ErrWithdrawalIsNotSettled),
+			errors.Is(err, exceptions.ErrReversalExceedsWithdrawal),
+			errors.Is(err, exceptions.ErrReversalWindowExpired):
+			return nil, err
+		default:
+			return nil, errors.Wrapf(err, "failed to reverse withdrawal")
+		}
+	}
+
+	return withdrawal, nil
+}
+
+func (c *WithdrawalsControllerImpl) PendingReviews(
+	ctx context.Context,
+	page *models.Page,
+) (*[]models.Withdrawal, error) {
+	pending, err := c.repos.WithdrawalsRepo.PendingReviews(ctx, page.Limit, page.Offset)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get pending withdrawals")
+	}
+
+	return pending, nil
+}
+
+// Approve settles a PENDING_REVIEW withdrawal.
+func (c *WithdrawalsControllerImpl) Approve(
+	ctx context.Context,
+	review *models.WithdrawalReview,
+) (*models.Withdrawal, error) {
+	review.Status = types.WithdrawalCompleted
+
+	return c.review(ctx, review)
+}
+
+// Reject returns the points of a PENDING_REVIEW withdrawal to the user.
+func (c *WithdrawalsControllerImpl) Reject(
+	ctx context.Context,
+	review *models.WithdrawalReview,
+) (*models.Withdrawal, error) {
+	review.Status = types.WithdrawalRejected
+
+	return c.review(ctx, review)
+}
+
+func (c *WithdrawalsControllerImpl) review(
+	ctx context.Context,
+	review *models.WithdrawalReview,
+) (*models.Withdrawal, error) {
+	withdrawal, err := c.repos.WithdrawalsRepo.Review(ctx, review)
+	if err != nil {
+		switch {
+		case errors.Is(err, exceptions.ErrWithdrawalNotFound),
+			errors.Is(err, exceptions.ErrWithdrawalIsNotPending):
+			return nil, err
+		default:
+			return nil, errors.Wrapf(err, "failed to review withdrawal")
+		}
+	}
+
+	return withdrawal, nil
+}
+
+// CreateHold checks the hold against the withdrawal policy, as Create
+// does, since a captured hold becomes a withdrawal.
+func (c *WithdrawalsControllerImpl) CreateHold(
+	ctx context.Context,
+	hold *models.Hold,
+) (*models.Hold, error) {
+	if err := c.repos.WithdrawalPolicy.Check(hold.Sum); err != nil {
+		return nil, err
+	}
+
+	created, err := c.repos.HoldsRepo.Create(ctx, hold)
+	if err != nil {
+		var violation *withdrawals.Violation
+		switch {
+		case errors.As(err, &violation):
+			return nil, violation
+		case errors.Is(err, exceptions.ErrBalanceIsNegative):
+			return nil, exceptions.ErrBalanceIsNegative
+		case errors.Is(err, exceptions.ErrWithdrawalsBlocked):
+			return nil, exceptions.ErrWithdrawalsBlocked
+		}
+		return nil, errors.Wrapf(err, "failed to create hold")
+	}
+
+	return created, nil
+}
+
+func (c *WithdrawalsControllerImpl) CaptureHold(
+	ctx context.Context,
+	holdID string,
+	userID string,
+) (*models.Hold, error) {
+	hold, err := c.repos.HoldsRepo.Capture(ctx, holdID, userID)
+	if err != nil {
+		var violation *withdrawals.Violation
+		switch {
+		case errors.As(err, &violation):
+			return nil, violation
+		case errors.Is(err, exceptions.ErrHoldNotFound),
+			errors.Is(err, exceptions.ErrHoldIsNotActive),
+			errors.Is(err, exceptions.ErrHoldExpired),
+			errors.Is(err, exceptions.ErrWithdrawalsBlocked):
+			return nil, err
+		default:
+			return nil, errors.Wrapf(err, "failed to capture hold")
+		}
+	}
+
+	return hold, nil
+}
+
+func (c *WithdrawalsControllerImpl) ReleaseHold(
+	ctx context.Context,
+	holdID string,
+	userID string,
+) (*models.Hold, error) {
+	hold, err := c.repos.HoldsRepo.Release(ctx, holdID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, exceptions.ErrHoldNotFound),
+			errors.Is(err, exceptions.ErrHoldIsNotActive):
+			return nil, err
+		default:
+			return nil, errors.Wrapf(err, "failed to release hold")
+		}
+	}
+
+	return hold, nil
+}
@@ -0,0 +1,31 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// SignCallback signs an accrual callback as hex(HMAC-SHA256(secret, timestamp + "." + body)).
+func SignCallback(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func VerifyCallback(secret string, timestamp string, body []byte, signature string) bool {
+	expected, err := hex.DecodeString(SignCallback(secret, timestamp, body))
+	if err != nil {
+		return false
+	}
+
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	return hmac.Equal(expected, got)
+}
@@ -0,0 +1,31 @@
+package crypto
+
+import "testing"
+
+func TestVerifyCallback(t *testing.T) {
+	body := []byte(`{"order":"12345678903","status":"PROCESSED","accrual":500}`)
+	signature := SignCallback("secret", "1700000000", body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      []byte
+		signature string
+		want      bool
+	}{
+		{"Test #1 Valid", "secret", "1700000000", body, signature, true},
+		{"Test #2 Wrong secret", "other", "1700000000", body, signature, false},
+		{"Test #3 Wrong timestamp", "secret", "1700000001", body, signature, false},
+		{"Test #4 Tampered body", "secret", "1700000000", []byte(`{}`), signature, false},
+		{"Test #5 Malformed signature", "secret", "1700000000", body, "zz", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := VerifyCallback(tt.secret, tt.timestamp, tt.body, tt.signature)
+			if got != tt.want {
+				t.Errorf("verification is different: got=%t want=%t", got, tt.want)
+			}
+		})
+	}
+}
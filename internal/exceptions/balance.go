@@ -4,3 +4,4 @@ import "github.com/pkg/errors"
 
 var ErrBalanceNotFound = errors.New("balance hasn't been found")
 var ErrBalanceIsNegative = errors.New("balance is negative")
+var ErrBalanceAlreadyExists = errors.New("balance already exists")
@@ -0,0 +1,8 @@
+package exceptions
+
+import "github.com/pkg/errors"
+
+var ErrCallbacksDisabled = errors.New("accrual callbacks are disabled")
+var ErrWrongCallbackSignature = errors.New("wrong accrual callback signature")
+var ErrCallbackExpired = errors.New("accrual callback timestamp is out of tolerance")
+var ErrCallbackReplayed = errors.New("accrual callback already received")
@@ -0,0 +1,8 @@
+package exceptions
+
+import "github.com/pkg/errors"
+
+var ErrCampaignNotFound = errors.New("campaign doesn't exist")
+var ErrCampaignInUse = errors.New("campaign has applied bonuses")
+var ErrCampaignBonusNotFound = errors.New("campaign bonus doesn't exist")
+var ErrCampaignBonusAlreadyReversed = errors.New("campaign bonus is already reversed")
@@ -0,0 +1,5 @@
+package exceptions
+
+import "github.com/pkg/errors"
+
+var ErrDeadLetterNotFound = errors.New("dead letter hasn't been found")
@@ -0,0 +1,5 @@
+package exceptions
+
+import "github.com/pkg/errors"
+
+var ErrLoyaltyDisabled = errors.New("loyalty tiers are disabled")
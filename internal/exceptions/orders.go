@@ -6,3 +6,7 @@ var ErrOrderAlreadyRegistered = errors.New("order already registered")
 var ErrOrderAlreadyAccepted = errors.New("order already registered by user")
 var ErrOrderNotFound = errors.New("order doesn't exist")
 var ErrWrongOrderNumber = errors.New("wrong order number")
+var ErrOrderIsLeased = errors.New("order is leased by another worker")
+var ErrOrderIsFinal = errors.New("order is already in a final status")
+var ErrOrderNotProcessed = errors.New("order isn't processed")
+var ErrOrderAlreadyRevoked = errors.New("order is already revoked")
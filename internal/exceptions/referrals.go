@@ -0,0 +1,6 @@
+package exceptions
+
+import "github.com/pkg/errors"
+
+var ErrReferralCodeNotFound = errors.New("referral code doesn't exist")
+var ErrReferralNotAllowed = errors.New("referral code is not allowed now")
@@ -0,0 +1,7 @@
+package exceptions
+
+import "github.com/pkg/errors"
+
+var ErrTransferToSelf = errors.New("transfer to oneself")
+var ErrRecipientNotFound = errors.New("transfer recipient doesn't exist")
+var ErrTransferLimitExceeded = errors.New("daily transfer limit is exceeded")
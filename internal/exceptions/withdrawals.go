@@ -1 +1,14 @@
 package exceptions
+
+import "github.com/pkg/errors"
+
+var ErrWithdrawalNotFound = errors.New("withdrawal doesn't exist")
+var ErrWithdrawalAlreadyReversed = errors.New("withdrawal is already reversed")
+var ErrReversalExceedsWithdrawal = errors.New("reversal exceeds the withdrawal sum")
+var ErrReversalWindowExpired = errors.New("withdrawal reversal window has expired")
+var ErrHoldNotFound = errors.New("hold doesn't exist")
+var ErrHoldIsNotActive = errors.New("hold is not active")
+var ErrHoldExpired = errors.New("hold has expired")
+var ErrWithdrawalsBlocked = errors.New("withdrawals are blocked until clawbacks are recovered")
+var ErrWithdrawalIsNotPending = errors.New("withdrawal is not pending review")
+var ErrWithdrawalIsNotSettled = errors.New("withdrawal is not settled")
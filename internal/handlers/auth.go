@@ -40,10 +40,15 @@ func (h *AuthHandlers) Register(w http.ResponseWriter, r *http.Request) {
 
 	user, err := h.controller.Register(ctx, authUser)
 	if err != nil {
-		if errors.Is(err, exceptions.ErrLoginAlreadyTaken) {
+		switch {
+		case errors.Is(err, exceptions.ErrLoginAlreadyTaken):
 			h.logger.Debug(r, "failed to register user: %s", err)
 			w.WriteHeader(http.StatusConflict)
-		} else {
+		case errors.Is(err, exceptions.ErrReferralCodeNotFound),
+			errors.Is(err, exceptions.ErrReferralNotAllowed):
+			h.logger.Debug(r, "failed to register user: %s", err)
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		default:
 			h.logger.Error(r, "failed to register user", err)
 			w.WriteHeader(http.StatusInternalServerError)
 		}
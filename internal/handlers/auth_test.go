@@ -24,6 +24,7 @@ func (m *MockedAuthController) Register(
 	ctx context.Context,
 	schema *models.AuthUser,
 ) (*models.User, error) {
+	crypto.InitJWTSigner("test_secure_key", 10*time.Second)
 	if schema.Login == "test3" {
 		return nil, exceptions.ErrLoginAlreadyTaken
 	}
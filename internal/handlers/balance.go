@@ -3,25 +3,43 @@ package handlers
 import (
 	"encoding/json"
 	"gophermart/internal/controllers"
+	"gophermart/internal/exceptions"
 	"gophermart/internal/log"
 	"gophermart/internal/middlewares"
 	"gophermart/internal/models"
 	"gophermart/internal/repository"
 	"gophermart/internal/validators"
 	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
 )
 
 type BalanceHandlers struct {
-	validator  validators.BalanceValidator
-	controller controllers.BalanceController
-	logger     log.HTTPLogger
+	validator      validators.BalanceValidator
+	controller     controllers.BalanceController
+	logger         log.HTTPLogger
+	expiryMonths   int
+	expiringWindow time.Duration
+	transferLimits models.TransferLimits
 }
 
-func NewBalanceHandlers(repos *repository.Repos) *BalanceHandlers {
+// NewBalanceHandlers reports points expiring within expiringWindow when
+// points expire expiryMonths after accrual, zero months means never.
+// Transfers users send are capped by transferLimits.
+func NewBalanceHandlers(
+	repos *repository.Repos,
+	expiryMonths int,
+	expiringWindow time.Duration,
+	transferLimits models.TransferLimits,
+) *BalanceHandlers {
 	return &BalanceHandlers{
-		validator:  validators.NewBalanceValidator(),
-		controller: controllers.NewBalanceController(repos),
-		logger:     log.NewHTTPLogger("BalanceHandlers"),
+		validator:      validators.NewBalanceValidator(),
+		controller:     controllers.NewBalanceController(repos),
+		logger:         log.NewHTTPLogger("BalanceHandlers"),
+		expiryMonths:   expiryMonths,
+		expiringWindow: expiringWindow,
+		transferLimits: transferLimits,
 	}
 }
 
@@ -35,9 +53,6 @@ func (h *BalanceHandlers) GetForUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-
 	balance, err := h.controller.GetForUser(ctx, userID)
 	if err != nil {
 		h.logger.Error(r, "failed to get user balance", err)
@@ -45,9 +60,22 @@ func (h *BalanceHandlers) GetForUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	expiring, err := h.controller.ExpiringSoon(ctx, userID, h.expiryMonths, h.expiringWindow)
+	if err != nil {
+		h.logger.Error(r, "failed to get expiring points", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
 	balanceOut := models.BalanceRead{
-		Current:   balance.Current,
-		Withdrawn: balance.Withdrawn,
+		Current:      balance.Current,
+		Withdrawn:    balance.Withdrawn,
+		Held:         balance.Held,
+		ExpiringSoon: expiring.Amount,
+		ExpiringAt:   expiring.ExpiresAt,
 	}
 	if err := json.NewEncoder(w).Encode(&balanceOut); err != nil {
 		h.logger.Error(r, "failed to encode response json", err)
@@ -55,3 +83,270 @@ func (h *BalanceHandlers) GetForUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+func (h *BalanceHandlers) Ledger(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	rawUserID := ctx.Value(middlewares.UserIDKey)
+	userID, ok := rawUserID.(string)
+	if !ok || userID == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	h.writeLedger(w, r, userID)
+}
+
+// UserLedger lets admins audit the ledger of any user.
+func (h *BalanceHandlers) UserLedger(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.validator.ValidateUserIDFromPath(r)
+	if err != nil {
+		h.logger.Debug(r, "failed to parse user id: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	h.writeLedger(w, r, userID)
+}
+
+func (h *BalanceHandlers) writeLedger(w http.ResponseWriter, r *http.Request, userID string) {
+	ctx := r.Context()
+
+	page, err := h.validator.ValidatePage(r)
+	if err != nil {
+		h.logger.Debug(r, "failed to validate page: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	entries, err := h.controller.Ledger(ctx, userID, page)
+	if err != nil {
+		h.logger.Error(r, "failed to get ledger", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(&entries); err != nil {
+		h.logger.Error(r, "failed to encode response json", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *BalanceHandlers) Tier(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	rawUserID := ctx.Value(middlewares.UserIDKey)
+	userID, ok := rawUserID.(string)
+	if !ok || userID == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	tier, err := h.controller.Tier(ctx, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, exceptions.ErrLoyaltyDisabled):
+			h.logger.Debug(r, "loyalty tiers are disabled: %s", err)
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			h.logger.Error(r, "failed to get user tier", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(&tier); err != nil {
+		h.logger.Error(r, "failed to encode response json", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *BalanceHandlers) Transfer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	rawUserID := ctx.Value(middlewares.UserIDKey)
+	userID, ok := rawUserID.(string)
+	if !ok || userID == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	transferIn, recipientLogin, err := h.validator.ValidateTransfer(userID, r.Body)
+	if err != nil {
+		h.logger.Debug(r, "failed to validate transfer body: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	transfer, err := h.controller.Transfer(ctx, transferIn, recipientLogin, h.transferLimits)
+	if err != nil {
+		switch {
+		case errors.Is(err, exceptions.ErrRecipientNotFound):
+			h.logger.Debug(r, "failed to find recipient: %s", err)
+			w.WriteHeader(http.StatusNotFound)
+		case errors.Is(err, exceptions.ErrTransferToSelf):
+			h.logger.Debug(r, "transfer to oneself: %s", err)
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		case errors.Is(err, exceptions.ErrBalanceIsNegative):
+			h.logger.Debug(r, "balance is negative: %s", err)
+			w.WriteHeader(http.StatusPaymentRequired)
+		case errors.Is(err, exceptions.ErrWithdrawalsBlocked):
+			h.logger.Debug(r, "withdrawals are blocked: %s", err)
+			w.WriteHeader(http.StatusForbidden)
+		case errors.Is(err, exceptions.ErrTransferLimitExceeded):
+			h.logger.Debug(r, "transfer limit is exceeded: %s", err)
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			h.logger.Error(r, "failed to transfer points", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(&transfer); err != nil {
+		h.logger.Error(r, "failed to encode response json", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *BalanceHandlers) Transfers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	rawUserID := ctx.Value(middlewares.UserIDKey)
+	userID, ok := rawUserID.(string)
+	if !ok || userID == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	page, err := h.validator.ValidatePage(r)
+	if err != nil {
+		h.logger.Debug(r, "failed to validate page: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	transfers, err := h.controller.Transfers(ctx, userID, page)
+	if err != nil {
+		h.logger.Error(r, "failed to get transfers", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(&transfers); err != nil {
+		h.logger.Error(r, "failed to encode response json", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *BalanceHandlers) Adjust(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	rawUserID := ctx.Value(middlewares.UserIDKey)
+	adminID, ok := rawUserID.(string)
+	if !ok || adminID == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	adjustmentIn, err := h.validator.ValidateAdjustment(adminID, r)
+	if err != nil {
+		h.logger.Debug(r, "failed to validate adjustment: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	adjustment, err := h.controller.Adjust(ctx, adjustmentIn)
+	if err != nil {
+		switch {
+		case errors.Is(err, exceptions.ErrUserNotFound):
+			h.logger.Debug(r, "failed to find user: %s", err)
+			w.WriteHeader(http.StatusNotFound)
+		case errors.Is(err, exceptions.ErrBalanceIsNegative):
+			h.logger.Debug(r, "adjustment exceeds balance: %s", err)
+			w.WriteHeader(http.StatusPaymentRequired)
+		default:
+			h.logger.Error(r, "failed to adjust balance", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	if err := json.NewEncoder(w).Encode(&adjustment); err != nil {
+		h.logger.Error(r, "failed to encode response json", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *BalanceHandlers) Adjustments(w http.ResponseWriter, r *http.Request) {
+	filter, err := h.validator.ValidateAdjustmentsFilter(r)
+	if err != nil {
+		h.logger.Debug(r, "failed to validate adjustments filter: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	h.writeAdjustments(w, r, filter)
+}
+
+func (h *BalanceHandlers) UserAdjustments(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.validator.ValidateUserIDFromPath(r)
+	if err != nil {
+		h.logger.Debug(r, "failed to parse user id: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	h.writeAdjustments(w, r, &models.AdjustmentsFilter{UserID: userID})
+}
+
+func (h *BalanceHandlers) writeAdjustments(
+	w http.ResponseWriter,
+	r *http.Request,
+	filter *models.AdjustmentsFilter,
+) {
+	ctx := r.Context()
+
+	page, err := h.validator.ValidatePage(r)
+	if err != nil {
+		h.logger.Debug(r, "failed to validate page: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	adjustments, err := h.controller.Adjustments(ctx, filter, page)
+	if err != nil {
+		h.logger.Error(r, "failed to get adjustments", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(&adjustments); err != nil {
+		h.logger.Error(r, "failed to encode response json", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
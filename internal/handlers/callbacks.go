@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"gophermart/internal/controllers"
+	"gophermart/internal/exceptions"
+	"gophermart/internal/log"
+	"gophermart/internal/repository"
+	"gophermart/internal/validators"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type CallbacksHandlers struct {
+	validator  validators.CallbacksValidator
+	controller controllers.CallbacksController
+	logger     log.HTTPLogger
+}
+
+func NewCallbacksHandlers(
+	repos *repository.Repos,
+	secret string,
+	tolerance time.Duration,
+) *CallbacksHandlers {
+	return &CallbacksHandlers{
+		validator:  validators.NewCallbacksValidator(secret, tolerance),
+		controller: controllers.NewCallbacksController(repos, tolerance),
+		logger:     log.NewHTTPLogger("CallbacksHandlers"),
+	}
+}
+
+// Accrual receives status updates pushed by the accrual system.
+// The endpoint answers 404 while no callback secret is configured.
+func (h *CallbacksHandlers) Accrual(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	callback, err := h.validator.ValidateCallback(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, exceptions.ErrCallbacksDisabled):
+			w.WriteHeader(http.StatusNotFound)
+		case errors.Is(err, exceptions.ErrWrongCallbackSignature),
+			errors.Is(err, exceptions.ErrCallbackExpired):
+			h.logger.Warn(r, "rejected accrual callback: %s", err)
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			h.logger.Debug(r, "failed to validate accrual callback: %s", err)
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		return
+	}
+
+	_, err = h.controller.Apply(ctx, callback)
+	if err != nil {
+		switch {
+		case errors.Is(err, exceptions.ErrOrderNotFound):
+			h.logger.Debug(r, "failed to find order: %s", err)
+			w.WriteHeader(http.StatusNotFound)
+		case errors.Is(err, exceptions.ErrCallbackReplayed):
+			h.logger.Warn(r, "replayed accrual callback: %s", err)
+			w.WriteHeader(http.StatusConflict)
+		default:
+			h.logger.Error(r, "failed to apply accrual callback", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"encoding/json"
+	"gophermart/internal/controllers"
+	"gophermart/internal/exceptions"
+	"gophermart/internal/log"
+	"gophermart/internal/repository"
+	"gophermart/internal/validators"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+type CampaignsHandlers struct {
+	validator  validators.CampaignsValidator
+	controller controllers.CampaignsController
+	logger     log.HTTPLogger
+}
+
+func NewCampaignsHandlers(repos *repository.Repos) *CampaignsHandlers {
+	return &CampaignsHandlers{
+		validator:  validators.NewCampaignsValidator(),
+		controller: controllers.NewCampaignsController(repos),
+		logger:     log.NewHTTPLogger("CampaignsHandlers"),
+	}
+}
+
+func (h *CampaignsHandlers) Create(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	campaignIn, err := h.validator.ValidateCampaign(r.Body)
+	if err != nil {
+		h.logger.Debug(r, "failed to validate campaign body: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	campaign, err := h.controller.Create(ctx, campaignIn)
+	if err != nil {
+		h.logger.Error(r, "failed to create campaign", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	h.writeJSON(w, r, http.StatusCreated, campaign)
+}
+
+func (h *CampaignsHandlers) List(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	page, err := h.validator.ValidatePage(r)
+	if err != nil {
+		h.logger.Debug(r, "failed to validate page: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	campaigns, err := h.controller.List(ctx, page)
+	if err != nil {
+		h.logger.Error(r, "failed to get campaigns", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	h.writeJSON(w, r, http.StatusOK, campaigns)
+}
+
+func (h *CampaignsHandlers) Get(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	campaignID, err := h.validator.ValidateIDFromPath(r)
+	if err != nil {
+		h.logger.Debug(r, "failed to parse campaign id: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	campaign, err := h.controller.Get(ctx, campaignID)
+	if err != nil {
+		switch {
+		case errors.Is(err, exceptions.ErrCampaignNotFound):
+			h.logger.Debug(r, "failed to find campaign: %s", err)
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			h.logger.Error(r, "failed to get campaign", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.writeJSON(w, r, http.StatusOK, campaign)
+}
+
+func (h *CampaignsHandlers) Update(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	campaignID, err := h.validator.ValidateIDFromPath(r)
+	if err != nil {
+		h.logger.Debug(r, "failed to parse campaign id: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	campaignIn, err := h.validator.ValidateCampaign(r.Body)
+	if err != nil {
+		h.logger.Debug(r, "failed to validate campaign body: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	campaignIn.ID = campaignID
+
+	campaign, err := h.controller.Update(ctx, campaignIn)
+	if err != nil {
+		switch {
+		case errors.Is(err, exceptions.ErrCampaignNotFound):
+			h.logger.Debug(r, "failed to find campaign: %s", err)
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			h.logger.Error(r, "failed to update campaign", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.writeJSON(w, r, http.StatusOK, campaign)
+}
+
+func (h *CampaignsHandlers) Delete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	campaignID, err := h.validator.ValidateIDFromPath(r)
+	if err != nil {
+		h.logger.Debug(r, "failed to parse campaign id: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	err = h.controller.Delete(ctx, campaignID)
+	if err != nil {
+		switch {
+		case errors.Is(err, exceptions.ErrCampaignNotFound):
+			h.logger.Debug(r, "failed to find campaign: %s", err)
+			w.WriteHeader(http.StatusNotFound)
+		case errors.Is(err, exceptions.ErrCampaignInUse):
+			h.logger.Debug(r, "campaign is in use: %s", err)
+			w.WriteHeader(http.StatusConflict)
+		default:
+			h.logger.Error(r, "failed to delete campaign", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *CampaignsHandlers) Bonuses(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	campaignID, err := h.validator.ValidateIDFromPath(r)
+	if err != nil {
+		h.logger.Debug(r, "failed to parse campaign id: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.validator.ValidatePage(r)
+	if err != nil {
+		h.logger.Debug(r, "failed to validate page: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	bonuses, err := h.controller.Bonuses(ctx, campaignID, page)
+	if err != nil {
+		h.logger.Error(r, "failed to get campaign bonuses", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	h.writeJSON(w, r, http.StatusOK, bonuses)
+}
+
+func (h *CampaignsHandlers) ReverseBonus(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	bonusID, err := h.validator.ValidateIDFromPath(r)
+	if err != nil {
+		h.logger.Debug(r, "failed to parse bonus id: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	bonus, err := h.controller.ReverseBonus(ctx, bonusID)
+	if err != nil {
+		switch {
+		case errors.Is(err, exceptions.ErrCampaignBonusNotFound):
+			h.logger.Debug(r, "failed to find campaign bonus: %s", err)
+			w.WriteHeader(http.StatusNotFound)
+		case errors.Is(err, exceptions.ErrCampaignBonusAlreadyReversed):
+			h.logger.Debug(r, "campaign bonus is already reversed: %s", err)
+			w.WriteHeader(http.StatusConflict)
+		case errors.Is(err, exceptions.ErrBalanceIsNegative):
+			h.logger.Debug(r, "campaign bonus is already spent: %s", err)
+			w.WriteHeader(http.StatusPaymentRequired)
+		default:
+			h.logger.Error(r, "failed to reverse campaign bonus", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.writeJSON(w, r, http.StatusOK, bonus)
+}
+
+func (h *CampaignsHandlers) writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		h.logger.Error(r, "failed to encode response json", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/json"
+	"gophermart/internal/controllers"
+	"gophermart/internal/exceptions"
+	"gophermart/internal/log"
+	"gophermart/internal/repository"
+	"gophermart/internal/validators"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+type DeadLettersHandlers struct {
+	validator  validators.DeadLettersValidator
+	controller controllers.DeadLettersController
+	logger     log.HTTPLogger
+}
+
+func NewDeadLettersHandlers(repos *repository.Repos) *DeadLettersHandlers {
+	return &DeadLettersHandlers{
+		validator:  validators.NewDeadLettersValidator(),
+		controller: controllers.NewDeadLettersController(repos),
+		logger:     log.NewHTTPLogger("DeadLettersHandlers"),
+	}
+}
+
+func (h *DeadLettersHandlers) List(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	page, err := h.validator.ValidatePage(r)
+	if err != nil {
+		h.logger.Debug(r, "failed to validate page: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	deadLetters, err := h.controller.List(ctx, page)
+	if err != nil {
+		h.logger.Error(r, "failed to get dead letters", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(&deadLetters); err != nil {
+		h.logger.Error(r, "failed to encode response json", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *DeadLettersHandlers) Replay(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	deadLetterID, err := h.validator.ValidateIDFromPath(r)
+	if err != nil {
+		h.logger.Debug(r, "failed to parse dead letter id: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.controller.Replay(ctx, deadLetterID)
+	if err != nil {
+		switch {
+		case errors.Is(err, exceptions.ErrDeadLetterNotFound):
+			h.logger.Debug(r, "failed to find dead letter: %s", err)
+			w.WriteHeader(http.StatusNotFound)
+		case errors.Is(err, exceptions.ErrOrderIsFinal):
+			h.logger.Debug(r, "order is already final: %s", err)
+			w.WriteHeader(http.StatusConflict)
+		default:
+			h.logger.Error(r, "failed to replay dead letter", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+
+	if err := json.NewEncoder(w).Encode(&order); err != nil {
+		h.logger.Error(r, "failed to encode response json", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *DeadLettersHandlers) Discard(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	deadLetterID, err := h.validator.ValidateIDFromPath(r)
+	if err != nil {
+		h.logger.Debug(r, "failed to parse dead letter id: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	_, err = h.controller.Discard(ctx, deadLetterID)
+	if err != nil {
+		switch {
+		case errors.Is(err, exceptions.ErrDeadLetterNotFound):
+			h.logger.Debug(r, "failed to find dead letter: %s", err)
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			h.logger.Error(r, "failed to discard dead letter", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
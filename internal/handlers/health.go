@@ -25,9 +25,19 @@ func (h *HealthHandlers) SetLiveness(state bool) {
 	h.controller.SetLiveness(state)
 }
 
+func (h *HealthHandlers) SetAccrualStateProvider(provider controllers.AccrualStateProvider) {
+	h.controller.SetAccrualStateProvider(provider)
+}
+
+// ReadinessState stays 200 while the accrual system is unavailable:
+// orders are still accepted and polled once it recovers.
 func (h *HealthHandlers) ReadinessState(w http.ResponseWriter, r *http.Request) {
 	if h.controller.ReadinessState() {
 		w.WriteHeader(http.StatusOK)
+		if h.controller.AccrualDegraded() {
+			_, _ = w.Write([]byte("accrual degraded"))
+			return
+		}
 		_, _ = w.Write([]byte("OK"))
 		return
 	}
@@ -141,3 +141,80 @@ func (h *OrdersHandlers) UserOrderByNumber(w http.ResponseWriter, r *http.Reques
 		return
 	}
 }
+
+func (h *OrdersHandlers) UserOrderHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	rawUserID := ctx.Value(middlewares.UserIDKey)
+	userID, ok := rawUserID.(string)
+	if !ok || userID == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	orderNumber, err := h.validator.ValidateOrderFromPath(r)
+	if err != nil {
+		h.logger.Debug(r, "failed to parse order: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	history, err := h.controller.UserOrderHistory(ctx, userID, *orderNumber)
+	if err != nil {
+		switch {
+		case errors.Is(err, exceptions.ErrOrderNotFound):
+			h.logger.Debug(r, "failed to find order: %s", err)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			h.logger.Error(r, "failed to get order history", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(&history); err != nil {
+		h.logger.Error(r, "failed to encode response json", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *OrdersHandlers) Revoke(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	orderNumber, err := h.validator.ValidateOrderFromPath(r)
+	if err != nil {
+		h.logger.Debug(r, "failed to parse order: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	clawback, err := h.controller.Revoke(ctx, *orderNumber)
+	if err != nil {
+		switch {
+		case errors.Is(err, exceptions.ErrOrderNotFound):
+			h.logger.Debug(r, "failed to find order: %s", err)
+			w.WriteHeader(http.StatusNotFound)
+		case errors.Is(err, exceptions.ErrOrderNotProcessed),
+			errors.Is(err, exceptions.ErrOrderAlreadyRevoked):
+			h.logger.Debug(r, "failed to revoke order: %s", err)
+			w.WriteHeader(http.StatusConflict)
+		default:
+			h.logger.Error(r, "failed to revoke order", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(&clawback); err != nil {
+		h.logger.Error(r, "failed to encode response json", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"encoding/json"
+	"gophermart/internal/controllers"
+	"gophermart/internal/log"
+	"gophermart/internal/middlewares"
+	"gophermart/internal/repository"
+	"gophermart/internal/validators"
+	"net/http"
+)
+
+type ReferralsHandlers struct {
+	validator  validators.ReferralsValidator
+	controller controllers.ReferralsController
+	logger     log.HTTPLogger
+}
+
+func NewReferralsHandlers(repos *repository.Repos) *ReferralsHandlers {
+	return &ReferralsHandlers{
+		validator:  validators.NewReferralsValidator(),
+		controller: controllers.NewReferralsController(repos),
+		logger:     log.NewHTTPLogger("ReferralsHandlers"),
+	}
+}
+
+func (h *ReferralsHandlers) UserReferrals(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	rawUserID := ctx.Value(middlewares.UserIDKey)
+	userID, ok := rawUserID.(string)
+	if !ok || userID == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	page, err := h.validator.ValidatePage(r)
+	if err != nil {
+		h.logger.Debug(r, "failed to validate page: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	referrals, err := h.controller.UserReferrals(ctx, userID, page)
+	if err != nil {
+		h.logger.Error(r, "failed to get referrals", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(&referrals); err != nil {
+		h.logger.Error(r, "failed to encode response json", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
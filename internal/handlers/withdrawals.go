@@ -1,29 +1,44 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"gophermart/internal/controllers"
 	"gophermart/internal/exceptions"
 	"gophermart/internal/log"
 	"gophermart/internal/middlewares"
+	"gophermart/internal/models"
 	"gophermart/internal/repository"
 	"gophermart/internal/validators"
+	"gophermart/internal/withdrawals"
 	"net/http"
+	"time"
 
 	"github.com/pkg/errors"
 )
 
 type WithdrawalsHandlers struct {
-	validator  validators.WithdrawalsValidator
-	controller controllers.WithdrawalController
-	logger     log.HTTPLogger
+	validator      validators.WithdrawalsValidator
+	controller     controllers.WithdrawalController
+	logger         log.HTTPLogger
+	reversalWindow time.Duration
+	holdTTL        time.Duration
 }
 
-func NewWithdrawalsHandlers(repos *repository.Repos) *WithdrawalsHandlers {
+// NewWithdrawalsHandlers lets users reverse their own withdrawals only
+// within reversalWindow after they were made; admins are not limited.
+// Holds not captured or released within holdTTL expire.
+func NewWithdrawalsHandlers(
+	repos *repository.Repos,
+	reversalWindow time.Duration,
+	holdTTL time.Duration,
+) *WithdrawalsHandlers {
 	return &WithdrawalsHandlers{
-		validator:  validators.NewWithdrawalsValidator(),
-		controller: controllers.NewWithdrawalsController(repos),
-		logger:     log.NewHTTPLogger("WithdrawalsHandlers"),
+		validator:      validators.NewWithdrawalsValidator(),
+		controller:     controllers.NewWithdrawalsController(repos),
+		logger:         log.NewHTTPLogger("WithdrawalsHandlers"),
+		reversalWindow: reversalWindow,
+		holdTTL:        holdTTL,
 	}
 }
 
@@ -56,10 +71,17 @@ func (h *WithdrawalsHandlers) Create(w http.ResponseWriter, r *http.Request) {
 
 	withdrawal, err := h.controller.Create(ctx, withdrawalIn)
 	if err != nil {
+		var violation *withdrawals.Violation
 		switch {
+		case errors.As(err, &violation):
+			h.logger.Debug(r, "withdrawal violates policy: %s", err)
+			h.writeViolation(w, r, violation)
 		case errors.Is(err, exceptions.ErrBalanceIsNegative):
 			h.logger.Debug(r, "balance is negative: %s", err)
 			w.WriteHeader(http.StatusPaymentRequired)
+		case errors.Is(err, exceptions.ErrWithdrawalsBlocked):
+			h.logger.Debug(r, "withdrawals are blocked: %s", err)
+			w.WriteHeader(http.StatusForbidden)
 		default:
 			h.logger.Error(r, "failed to auth user", err)
 			w.WriteHeader(http.StatusInternalServerError)
@@ -67,7 +89,11 @@ func (h *WithdrawalsHandlers) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	if withdrawal.IsPendingReview() {
+		w.WriteHeader(http.StatusAccepted)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
 
 	if err := json.NewEncoder(w).Encode(&withdrawal); err != nil {
 		h.logger.Error(r, "failed to encode response json", err)
@@ -108,3 +134,298 @@ func (h *WithdrawalsHandlers) UserWithdrawals(w http.ResponseWriter, r *http.Req
 		return
 	}
 }
+
+func (h *WithdrawalsHandlers) Reverse(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	rawUserID := ctx.Value(middlewares.UserIDKey)
+	userID, ok := rawUserID.(string)
+	if !ok || userID == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	h.reverse(w, r, userID, userID, h.reversalWindow)
+}
+
+func (h *WithdrawalsHandlers) AdminReverse(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	rawUserID := ctx.Value(middlewares.UserIDKey)
+	adminID, ok := rawUserID.(string)
+	if !ok || adminID == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	h.reverse(w, r, adminID, "", 0)
+}
+
+func (h *WithdrawalsHandlers) reverse(
+	w http.ResponseWriter,
+	r *http.Request,
+	actorID string,
+	ownerID string,
+	window time.Duration,
+) {
+	ctx := r.Context()
+
+	reversalIn, err := h.validator.ValidateReversal(actorID, r)
+	if err != nil {
+		h.logger.Debug(r, "failed to validate reversal: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	withdrawal, err := h.controller.Reverse(ctx, reversalIn, ownerID, window)
+	if err != nil {
+		switch {
+		case errors.Is(err, exceptions.ErrWithdrawalNotFound):
+			h.logger.Debug(r, "failed to find withdrawal: %s", err)
+			w.WriteHeader(http.StatusNotFound)
+		case errors.Is(err, exceptions.ErrWithdrawalAlreadyReversed):
+			h.logger.Debug(r, "withdrawal is already reversed: %s", err)
+			w.WriteHeader(http.StatusConflict)
+		case errors.Is(err, exceptions.ErrWithdrawalIsNotSettled):
+			h.logger.Debug(r, "withdrawal is not settled: %s", err)
+			w.WriteHeader(http.StatusConflict)
+		case errors.Is(err, exceptions.ErrReversalExceedsWithdrawal):
+			h.logger.Debug(r, "reversal exceeds withdrawal: %s", err)
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		case errors.Is(err, exceptions.ErrReversalWindowExpired):
+			h.logger.Debug(r, "reversal window has expired: %s", err)
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			h.logger.Error(r, "failed to reverse withdrawal", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(&withdrawal); err != nil {
+		h.logger.Error(r, "failed to encode response json", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *WithdrawalsHandlers) PendingReviews(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	page, err := h.validator.ValidatePage(r)
+	if err != nil {
+		h.logger.Debug(r, "failed to validate page: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	pending, err := h.controller.PendingReviews(ctx, page)
+	if err != nil {
+		h.logger.Error(r, "failed to get pending withdrawals", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(&pending); err != nil {
+		h.logger.Error(r, "failed to encode response json", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *WithdrawalsHandlers) Approve(w http.ResponseWriter, r *http.Request) {
+	h.review(w, r, h.controller.Approve)
+}
+
+func (h *WithdrawalsHandlers) Reject(w http.ResponseWriter, r *http.Request) {
+	h.review(w, r, h.controller.Reject)
+}
+
+func (h *WithdrawalsHandlers) review(
+	w http.ResponseWriter,
+	r *http.Request,
+	decide func(ctx context.Context, review *models.WithdrawalReview) (*models.Withdrawal, error),
+) {
+	ctx := r.Context()
+
+	rawUserID := ctx.Value(middlewares.UserIDKey)
+	adminID, ok := rawUserID.(string)
+	if !ok || adminID == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	reviewIn, err := h.validator.ValidateReview(adminID, r)
+	if err != nil {
+		h.logger.Debug(r, "failed to validate review: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	withdrawal, err := decide(ctx, reviewIn)
+	if err != nil {
+		switch {
+		case errors.Is(err, exceptions.ErrWithdrawalNotFound):
+			h.logger.Debug(r, "failed to find withdrawal: %s", err)
+			w.WriteHeader(http.StatusNotFound)
+		case errors.Is(err, exceptions.ErrWithdrawalIsNotPending):
+			h.logger.Debug(r, "withdrawal is not pending review: %s", err)
+			w.WriteHeader(http.StatusConflict)
+		default:
+			h.logger.Error(r, "failed to review withdrawal", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(&withdrawal); err != nil {
+		h.logger.Error(r, "failed to encode response json", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *WithdrawalsHandlers) CreateHold(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	rawUserID := ctx.Value(middlewares.UserIDKey)
+	userID, ok := rawUserID.(string)
+	if !ok || userID == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	holdIn, err := h.validator.ValidateHoldCreate(userID, r.Body, h.holdTTL)
+	if err != nil {
+		switch {
+		case errors.Is(err, exceptions.ErrWrongOrderNumber):
+			h.logger.Debug(r, "wrong order number: %s", err)
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		default:
+			h.logger.Debug(r, "failed to validate hold body: %s", err)
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		return
+	}
+
+	hold, err := h.controller.CreateHold(ctx, holdIn)
+	if err != nil {
+		var violation *withdrawals.Violation
+		switch {
+		case errors.As(err, &violation):
+			h.logger.Debug(r, "hold violates withdrawal policy: %s", err)
+			h.writeViolation(w, r, violation)
+		case errors.Is(err, exceptions.ErrBalanceIsNegative):
+			h.logger.Debug(r, "balance is negative: %s", err)
+			w.WriteHeader(http.StatusPaymentRequired)
+		case errors.Is(err, exceptions.ErrWithdrawalsBlocked):
+			h.logger.Debug(r, "withdrawals are blocked: %s", err)
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			h.logger.Error(r, "failed to create hold", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.writeHold(w, r, hold)
+}
+
+func (h *WithdrawalsHandlers) CaptureHold(w http.ResponseWriter, r *http.Request) {
+	h.finishHold(w, r, h.controller.CaptureHold)
+}
+
+func (h *WithdrawalsHandlers) ReleaseHold(w http.ResponseWriter, r *http.Request) {
+	h.finishHold(w, r, h.controller.ReleaseHold)
+}
+
+func (h *WithdrawalsHandlers) finishHold(
+	w http.ResponseWriter,
+	r *http.Request,
+	finish func(ctx context.Context, holdID string, userID string) (*models.Hold, error),
+) {
+	ctx := r.Context()
+
+	rawUserID := ctx.Value(middlewares.UserIDKey)
+	userID, ok := rawUserID.(string)
+	if !ok || userID == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	holdID, err := h.validator.ValidateHoldIDFromPath(r)
+	if err != nil {
+		h.logger.Debug(r, "failed to parse hold id: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	hold, err := finish(ctx, holdID, userID)
+	if err != nil {
+		var violation *withdrawals.Violation
+		switch {
+		case errors.As(err, &violation):
+			h.logger.Debug(r, "capture violates withdrawal policy: %s", err)
+			h.writeViolation(w, r, violation)
+		case errors.Is(err, exceptions.ErrWithdrawalsBlocked):
+			h.logger.Debug(r, "withdrawals are blocked: %s", err)
+			w.WriteHeader(http.StatusForbidden)
+		case errors.Is(err, exceptions.ErrHoldNotFound):
+			h.logger.Debug(r, "failed to find hold: %s", err)
+			w.WriteHeader(http.StatusNotFound)
+		case errors.Is(err, exceptions.ErrHoldIsNotActive):
+			h.logger.Debug(r, "hold is not active: %s", err)
+			w.WriteHeader(http.StatusConflict)
+		case errors.Is(err, exceptions.ErrHoldExpired):
+			h.logger.Debug(r, "hold has expired: %s", err)
+			w.WriteHeader(http.StatusGone)
+		default:
+			h.logger.Error(r, "failed to finish hold", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.writeHold(w, r, hold)
+}
+
+func (h *WithdrawalsHandlers) writeHold(w http.ResponseWriter, r *http.Request, hold *models.Hold) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(&hold); err != nil {
+		h.logger.Error(r, "failed to encode response json", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// writeViolation tells the client which withdrawal rule was broken.
+// Caps on usage are reported as 429, the amount itself as 422.
+func (h *WithdrawalsHandlers) writeViolation(
+	w http.ResponseWriter,
+	r *http.Request,
+	violation *withdrawals.Violation,
+) {
+	status := http.StatusUnprocessableEntity
+	if violation.IsLimit() {
+		status = http.StatusTooManyRequests
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(violation); err != nil {
+		h.logger.Error(r, "failed to encode response json", err)
+		return
+	}
+}
@@ -21,6 +21,10 @@ func (s *Server) setupRoutes() *mux.Router {
 	m.HandleFunc("/api/user/login", s.auth.Login).
 		Methods(http.MethodPost)
 
+	// Callback handlers, authenticated by signature
+	m.HandleFunc("/api/internal/accrual/callback", s.callbacks.Accrual).
+		Methods(http.MethodPost)
+
 	userAuth := m.PathPrefix("/api/user").Subrouter()
 
 	//  Orders handlers
@@ -30,19 +34,94 @@ func (s *Server) setupRoutes() *mux.Router {
 		Methods(http.MethodGet)
 	userAuth.HandleFunc("/orders/{number}", s.orders.UserOrderByNumber).
 		Methods(http.MethodGet)
+	userAuth.HandleFunc("/orders/{number}/history", s.orders.UserOrderHistory).
+		Methods(http.MethodGet)
 
 	// Balance handlers
 	userAuth.HandleFunc("/balance", s.balance.GetForUser).
 		Methods(http.MethodGet)
+	userAuth.HandleFunc("/balance/ledger", s.balance.Ledger).
+		Methods(http.MethodGet)
+	userAuth.HandleFunc("/balance/tier", s.balance.Tier).
+		Methods(http.MethodGet)
+	userAuth.HandleFunc("/balance/transfer", s.balance.Transfer).
+		Methods(http.MethodPost)
+	userAuth.HandleFunc("/balance/transfers", s.balance.Transfers).
+		Methods(http.MethodGet)
+
+	// Referrals handlers
+	userAuth.HandleFunc("/referrals", s.referrals.UserReferrals).
+		Methods(http.MethodGet)
 
 	// Withdrawals handlers
 	userAuth.HandleFunc("/balance/withdraw", s.withdrawals.Create).
 		Methods(http.MethodPost)
+	userAuth.HandleFunc("/balance/holds", s.withdrawals.CreateHold).
+		Methods(http.MethodPost)
+	userAuth.HandleFunc("/balance/holds/{id}/capture", s.withdrawals.CaptureHold).
+		Methods(http.MethodPost)
+	userAuth.HandleFunc("/balance/holds/{id}/release", s.withdrawals.ReleaseHold).
+		Methods(http.MethodPost)
 	userAuth.HandleFunc("/withdrawals", s.withdrawals.UserWithdrawals).
 		Methods(http.MethodGet)
+	userAuth.HandleFunc("/withdrawals/{id}/reversal", s.withdrawals.Reverse).
+		Methods(http.MethodPost)
+
+	adminAuth := m.PathPrefix("/api/admin").Subrouter()
+
+	// Dead letters handlers
+	adminAuth.HandleFunc("/accrual/dead-letters", s.deadLetters.List).
+		Methods(http.MethodGet)
+	adminAuth.HandleFunc("/accrual/dead-letters/{id}/replay", s.deadLetters.Replay).
+		Methods(http.MethodPost)
+	adminAuth.HandleFunc("/accrual/dead-letters/{id}", s.deadLetters.Discard).
+		Methods(http.MethodDelete)
+
+	// Orders handlers
+	adminAuth.HandleFunc("/orders/{number}/revocation", s.orders.Revoke).
+		Methods(http.MethodPost)
+
+	// Ledger handlers
+	adminAuth.HandleFunc("/users/{id}/ledger", s.balance.UserLedger).
+		Methods(http.MethodGet)
+
+	// Adjustments handlers
+	adminAuth.HandleFunc("/users/{id}/adjustments", s.balance.Adjust).
+		Methods(http.MethodPost)
+	adminAuth.HandleFunc("/users/{id}/adjustments", s.balance.UserAdjustments).
+		Methods(http.MethodGet)
+	adminAuth.HandleFunc("/adjustments", s.balance.Adjustments).
+		Methods(http.MethodGet)
+
+	// Campaigns handlers
+	adminAuth.HandleFunc("/campaigns", s.campaigns.List).
+		Methods(http.MethodGet)
+	adminAuth.HandleFunc("/campaigns", s.campaigns.Create).
+		Methods(http.MethodPost)
+	adminAuth.HandleFunc("/campaigns/{id}", s.campaigns.Get).
+		Methods(http.MethodGet)
+	adminAuth.HandleFunc("/campaigns/{id}", s.campaigns.Update).
+		Methods(http.MethodPut)
+	adminAuth.HandleFunc("/campaigns/{id}", s.campaigns.Delete).
+		Methods(http.MethodDelete)
+	adminAuth.HandleFunc("/campaigns/{id}/bonuses", s.campaigns.Bonuses).
+		Methods(http.MethodGet)
+	adminAuth.HandleFunc("/campaign-bonuses/{id}/reversal", s.campaigns.ReverseBonus).
+		Methods(http.MethodPost)
+
+	// Withdrawals handlers
+	adminAuth.HandleFunc("/withdrawals/{id}/reversal", s.withdrawals.AdminReverse).
+		Methods(http.MethodPost)
+	adminAuth.HandleFunc("/withdrawals/reviews", s.withdrawals.PendingReviews).
+		Methods(http.MethodGet)
+	adminAuth.HandleFunc("/withdrawals/{id}/approval", s.withdrawals.Approve).
+		Methods(http.MethodPost)
+	adminAuth.HandleFunc("/withdrawals/{id}/rejection", s.withdrawals.Reject).
+		Methods(http.MethodPost)
 
 	// Middlewares
-	userAuth.Use(middlewares.AuthorizationMiddleware)
+	userAuth.Use(middlewares.AuthorizationMiddleware, s.idempotency)
+	adminAuth.Use(middlewares.AuthorizationMiddleware, s.adminAuth)
 	m.Use(middlewares.GzipMiddleware)
 
 	return m
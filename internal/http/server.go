@@ -19,6 +19,12 @@ type Server struct {
 	balance     *handlers.BalanceHandlers
 	orders      *handlers.OrdersHandlers
 	withdrawals *handlers.WithdrawalsHandlers
+	deadLetters *handlers.DeadLettersHandlers
+	callbacks   *handlers.CallbacksHandlers
+	campaigns   *handlers.CampaignsHandlers
+	referrals   *handlers.ReferralsHandlers
+	adminAuth   func(next http.Handler) http.Handler
+	idempotency func(next http.Handler) http.Handler
 }
 
 func New(
@@ -28,6 +34,12 @@ func New(
 	balanceHandlers *handlers.BalanceHandlers,
 	ordersHandlers *handlers.OrdersHandlers,
 	withdrawalsHandlers *handlers.WithdrawalsHandlers,
+	deadLettersHandlers *handlers.DeadLettersHandlers,
+	callbacksHandlers *handlers.CallbacksHandlers,
+	campaignsHandlers *handlers.CampaignsHandlers,
+	referralsHandlers *handlers.ReferralsHandlers,
+	adminAuth func(next http.Handler) http.Handler,
+	idempotency func(next http.Handler) http.Handler,
 ) *Server {
 	srv := &http.Server{
 		Addr: cfg.HTTPAddress,
@@ -41,6 +53,12 @@ func New(
 		balance:     balanceHandlers,
 		orders:      ordersHandlers,
 		withdrawals: withdrawalsHandlers,
+		deadLetters: deadLettersHandlers,
+		callbacks:   callbacksHandlers,
+		campaigns:   campaignsHandlers,
+		referrals:   referralsHandlers,
+		adminAuth:   adminAuth,
+		idempotency: idempotency,
 	}
 }
 
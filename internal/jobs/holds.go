@@ -0,0 +1,38 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"gophermart/internal/log"
+
+	"github.com/pkg/errors"
+)
+
+type HoldsRepo interface {
+	ReleaseExpired(ctx context.Context, limit int) (int64, error)
+}
+
+// ReleaseExpiredHolds returns points of expired holds to their users,
+// batch by batch until no expired hold is left.
+func ReleaseExpiredHolds(repo HoldsRepo, batchSize int) Func {
+	return func(ctx context.Context) error {
+		var total int64
+		for {
+			released, err := repo.ReleaseExpired(ctx, batchSize)
+			if err != nil {
+				return errors.Wrapf(err, "failed to release expired holds")
+			}
+			total += released
+
+			if released < int64(batchSize) {
+				break
+			}
+		}
+
+		if total > 0 {
+			log.Info(ctx, fmt.Sprintf("released %d expired holds", total))
+		}
+
+		return nil
+	}
+}
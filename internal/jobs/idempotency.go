@@ -0,0 +1,28 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"gophermart/internal/log"
+
+	"github.com/pkg/errors"
+)
+
+type IdempotencyKeysRepo interface {
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+func CleanupIdempotencyKeys(repo IdempotencyKeysRepo) Func {
+	return func(ctx context.Context) error {
+		deleted, err := repo.DeleteExpired(ctx)
+		if err != nil {
+			return errors.Wrapf(err, "failed to delete expired idempotency keys")
+		}
+
+		if deleted > 0 {
+			log.Info(ctx, fmt.Sprintf("deleted %d expired idempotency keys", deleted))
+		}
+
+		return nil
+	}
+}
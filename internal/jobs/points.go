@@ -0,0 +1,51 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"gophermart/internal/log"
+	"gophermart/internal/types"
+
+	"github.com/pkg/errors"
+)
+
+type PointLotsRepo interface {
+	DueUsers(ctx context.Context, months int, limit int) ([]string, error)
+	Expire(ctx context.Context, userID string, months int) (types.Money, error)
+}
+
+// ExpirePoints takes away points accrued more than months ago,
+// batch of users by batch until nobody has such points left.
+func ExpirePoints(repo PointLotsRepo, months int, batchSize int) Func {
+	return func(ctx context.Context) error {
+		var (
+			users int
+			total types.Money
+		)
+		for {
+			userIDs, err := repo.DueUsers(ctx, months, batchSize)
+			if err != nil {
+				return errors.Wrapf(err, "failed to get users with expired points")
+			}
+
+			for _, userID := range userIDs {
+				expired, err := repo.Expire(ctx, userID, months)
+				if err != nil {
+					return errors.Wrapf(err, "failed to expire points of user=%s", userID)
+				}
+				users++
+				total = total.Add(expired)
+			}
+
+			if len(userIDs) < batchSize {
+				break
+			}
+		}
+
+		if users > 0 {
+			log.Info(ctx, fmt.Sprintf("expired %s points of %d users", total, users))
+		}
+
+		return nil
+	}
+}
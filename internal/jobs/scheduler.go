@@ -0,0 +1,83 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"gophermart/internal/closer"
+	"gophermart/internal/log"
+	"sync"
+	"time"
+)
+
+// Func is a unit of periodic background work.
+type Func func(ctx context.Context) error
+
+type job struct {
+	name     string
+	interval time.Duration
+	run      Func
+}
+
+// Scheduler runs registered jobs every interval until it is closed.
+// A job run is never interrupted by the application shutting down,
+// Close waits for runs in progress.
+type Scheduler struct {
+	jobs   []job
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{}
+}
+
+func (s *Scheduler) Add(name string, interval time.Duration, run Func) {
+	s.jobs = append(s.jobs, job{name: name, interval: interval, run: run})
+}
+
+func (s *Scheduler) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(context.WithoutCancel(ctx))
+
+	for _, j := range s.jobs {
+		if j.interval <= 0 {
+			log.Warn(ctx, fmt.Sprintf("job %s is disabled", j.name))
+			continue
+		}
+
+		s.wg.Add(1)
+		go func(j job) {
+			defer s.wg.Done()
+			s.loop(ctx, j)
+		}(j)
+	}
+
+	closer.Add(s.Close)
+}
+
+func (s *Scheduler) loop(ctx context.Context, j job) {
+	log.Info(ctx, fmt.Sprintf("starting job %s every %s", j.name, j.interval))
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := j.run(ctx); err != nil {
+				log.Error(ctx, fmt.Sprintf("job %s failed", j.name), err)
+			}
+		case <-ctx.Done():
+			log.Info(ctx, fmt.Sprintf("job %s shutdown", j.name))
+			return
+		}
+	}
+}
+
+func (s *Scheduler) Close() error {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package loyalty
+
+import (
+	"gophermart/internal/types"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// Tier is reached once the points accrued within the program window
+// reach Threshold. Accruals of users in the tier are multiplied by
+// Multiplier, the part above the accrual system amount is a bonus.
+type Tier struct {
+	Name       string
+	Threshold  types.Money
+	Multiplier decimal.Decimal
+}
+
+// ParseTiers reads tiers written as NAME:THRESHOLD:MULTIPLIER separated
+// by commas, e.g. "BRONZE:0:1,SILVER:1000:1.05,GOLD:5000:1.1".
+func ParseTiers(raw string) ([]Tier, error) {
+	tiers := []Tier{}
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		parts := strings.Split(item, ":")
+		if len(parts) != 3 || parts[0] == "" {
+			return nil, errors.Errorf("wrong tier %q, want NAME:THRESHOLD:MULTIPLIER", item)
+		}
+
+		threshold, err := types.ParseMoney(parts[1])
+		if err != nil {
+			return nil, errors.Wrapf(err, "wrong threshold of tier %s", parts[0])
+		}
+		if threshold.IsNegative() {
+			return nil, errors.Errorf("threshold of tier %s is negative", parts[0])
+		}
+
+		multiplier, err := decimal.NewFromString(parts[2])
+		if err != nil {
+			return nil, errors.Wrapf(err, "wrong multiplier of tier %s", parts[0])
+		}
+		if multiplier.LessThan(decimal.NewFromInt(1)) {
+			return nil, errors.Errorf("multiplier of tier %s is less than 1", parts[0])
+		}
+
+		tiers = append(tiers, Tier{
+			Name:       strings.ToUpper(parts[0]),
+			Threshold:  threshold,
+			Multiplier: multiplier,
+		})
+	}
+
+	return tiers, nil
+}
+
+// Program places users into tiers by the points accrued within Window.
+type Program struct {
+	tiers  []Tier
+	window time.Duration
+}
+
+// NewProgram returns nil when there are no tiers, which disables the program.
+// Users below every threshold are in the lowest tier.
+func NewProgram(tiers []Tier, window time.Duration) *Program {
+	if len(tiers) == 0 {
+		return nil
+	}
+
+	sorted := make([]Tier, len(tiers))
+	copy(sorted, tiers)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Threshold.LessThan(sorted[j].Threshold)
+	})
+
+	return &Program{tiers: sorted, window: window}
+}
+
+func (p *Program) Window() time.Duration {
+	return p.window
+}
+
+// Classify returns the tier reached by accrued points.
+func (p *Program) Classify(accrued types.Money) Tier {
+	tier := p.tiers[0]
+	for _, t := range p.tiers[1:] {
+		if accrued.LessThan(t.Threshold) {
+			break
+		}
+		tier = t
+	}
+
+	return tier
+}
+
+// Tier returns the tier by name, unknown and empty names are the lowest tier.
+func (p *Program) Tier(name string) Tier {
+	for _, t := range p.tiers {
+		if t.Name == name {
+			return t
+		}
+	}
+
+	return p.tiers[0]
+}
+
+// Next returns the tier above the named one, false for the top tier.
+func (p *Program) Next(name string) (Tier, bool) {
+	current := p.Tier(name)
+	for i, t := range p.tiers {
+		if t.Name == current.Name && i+1 < len(p.tiers) {
+			return p.tiers[i+1], true
+		}
+	}
+
+	return Tier{}, false
+}
+
+// Bonus is what the tier adds to an accrual of amount.
+func (p *Program) Bonus(name string, amount types.Money) types.Money {
+	multiplier := p.Tier(name).Multiplier
+	bonus := amount.MulRatio(multiplier.Sub(decimal.NewFromInt(1)))
+
+	return bonus.Round(2)
+}
@@ -0,0 +1,76 @@
+package loyalty
+
+import (
+	"gophermart/internal/types"
+	"testing"
+	"time"
+)
+
+func TestParseTiers(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    int
+		wantErr bool
+	}{
+		{"Test #1 Tiers", "BRONZE:0:1, silver:1000:1.05,GOLD:5000:1.1", 3, false},
+		{"Test #2 Empty", "", 0, false},
+		{"Test #3 Missing multiplier", "GOLD:5000", 0, true},
+		{"Test #4 Wrong threshold", "GOLD:many:1.1", 0, true},
+		{"Test #5 Negative threshold", "GOLD:-1:1.1", 0, true},
+		{"Test #6 Penalty", "GOLD:5000:0.9", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tiers, err := ParseTiers(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error is different: got=%v wantErr=%v", err, tt.wantErr)
+			}
+			if len(tiers) != tt.want {
+				t.Errorf("tiers count is different: got=%d want=%d", len(tiers), tt.want)
+			}
+		})
+	}
+}
+
+func TestProgram(t *testing.T) {
+	tiers, err := ParseTiers("GOLD:5000:1.1,BRONZE:0:1,SILVER:1000:1.05")
+	if err != nil {
+		t.Fatalf("failed to parse tiers: %s", err)
+	}
+	program := NewProgram(tiers, time.Hour)
+
+	tests := []struct {
+		name      string
+		accrued   types.Money
+		wantTier  string
+		wantNext  string
+		wantBonus types.Money
+	}{
+		{"Test #1 Bronze", types.MoneyFromInt(999), "BRONZE", "SILVER", types.Money{}},
+		{"Test #2 Silver", types.MoneyFromInt(1000), "SILVER", "GOLD", types.NewMoney(5, 0)},
+		{"Test #3 Gold", types.MoneyFromInt(7000), "GOLD", "", types.NewMoney(10, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tier := program.Classify(tt.accrued)
+			if tier.Name != tt.wantTier {
+				t.Errorf("tier is different: got=%s want=%s", tier.Name, tt.wantTier)
+			}
+
+			next, ok := program.Next(tier.Name)
+			if next.Name != tt.wantNext || ok != (tt.wantNext != "") {
+				t.Errorf("next tier is different: got=%s want=%s", next.Name, tt.wantNext)
+			}
+
+			bonus := program.Bonus(tier.Name, types.MoneyFromInt(100))
+			if !bonus.Equal(tt.wantBonus) {
+				t.Errorf("bonus is different: got=%s want=%s", bonus, tt.wantBonus)
+			}
+		})
+	}
+
+	if NewProgram(nil, time.Hour) != nil {
+		t.Error("program without tiers is enabled")
+	}
+}
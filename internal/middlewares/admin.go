@@ -0,0 +1,45 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"gophermart/internal/log"
+	"net/http"
+)
+
+type AdminChecker interface {
+	IsAdmin(ctx context.Context, userID string) (bool, error)
+}
+
+// NewAdminMiddleware lets through only users flagged as admins.
+// It must run after AuthorizationMiddleware, which puts the user ID into the context.
+func NewAdminMiddleware(checker AdminChecker) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				ctx := r.Context()
+
+				userID, ok := ctx.Value(UserIDKey).(string)
+				if !ok || userID == "" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+
+				isAdmin, err := checker.IsAdmin(ctx, userID)
+				if err != nil {
+					log.Debug(ctx, fmt.Sprintf("failed to check admin user=%s: %s", userID, err))
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+
+				if !isAdmin {
+					log.Debug(ctx, fmt.Sprintf("user=%s is not an admin", userID))
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+
+				next.ServeHTTP(w, r)
+			},
+		)
+	}
+}
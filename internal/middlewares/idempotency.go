@@ -0,0 +1,178 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"gophermart/internal/log"
+	"gophermart/internal/models"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotencyReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+
+	// idempotencyStoreTimeout bounds storing the outcome of a request, which
+	// must happen even when the client has gone away.
+	idempotencyStoreTimeout = 5 * time.Second
+)
+
+type IdempotencyStore interface {
+	Acquire(ctx context.Context, userID string, key string, fingerprint string, ttl time.Duration) (*models.IdempotencyKey, bool, error)
+	Complete(ctx context.Context, userID string, key string, statusCode int, contentType string, response []byte) error
+	Release(ctx context.Context, userID string, key string) error
+}
+
+// NewIdempotencyMiddleware makes mutating requests sent with an Idempotency-Key
+// header safe to retry: the first response is stored for ttl and replayed for
+// the same key, a different request under a used key gets 422 and a request
+// still in progress gets 409. Server errors are not stored, so they can be retried.
+// It must run after AuthorizationMiddleware, keys are scoped per user.
+func NewIdempotencyMiddleware(store IdempotencyStore, ttl time.Duration) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				ctx := r.Context()
+
+				key := r.Header.Get(IdempotencyKeyHeader)
+				if key == "" || !isMutating(r.Method) {
+					next.ServeHTTP(w, r)
+					return
+				}
+
+				if len(key) > maxIdempotencyKeyLength {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+
+				userID, ok := ctx.Value(UserIDKey).(string)
+				if !ok || userID == "" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+
+				body, err := io.ReadAll(r.Body)
+				if err != nil {
+					log.Debug(ctx, fmt.Sprintf("failed to read body: %s", err))
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				r.Body = io.NopCloser(bytes.NewReader(body))
+
+				stored, acquired, err := store.Acquire(ctx, userID, key, fingerprint(r, body), ttl)
+				if err != nil {
+					log.Error(ctx, "failed to acquire idempotency key", err)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+
+				if !acquired {
+					replay(w, r, stored, body)
+					return
+				}
+
+				recorder := &responseRecorder{ResponseWriter: w}
+				next.ServeHTTP(recorder, r)
+
+				// The handler may have committed after the client went away,
+				// a key left in progress would answer its retries with 409.
+				storeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), idempotencyStoreTimeout)
+				defer cancel()
+
+				if recorder.statusCode() >= http.StatusInternalServerError {
+					if err := store.Release(storeCtx, userID, key); err != nil {
+						log.Error(ctx, "failed to release idempotency key", err)
+					}
+					return
+				}
+
+				err = store.Complete(
+					storeCtx,
+					userID,
+					key,
+					recorder.statusCode(),
+					recorder.Header().Get("Content-Type"),
+					recorder.body.Bytes(),
+				)
+				if err != nil {
+					log.Error(ctx, "failed to complete idempotency key", err)
+				}
+			},
+		)
+	}
+}
+
+func replay(w http.ResponseWriter, r *http.Request, stored *models.IdempotencyKey, body []byte) {
+	switch {
+	case stored.Fingerprint != fingerprint(r, body):
+		log.Debug(r.Context(), fmt.Sprintf("idempotency key %s reused for another request", stored.Key))
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	case !stored.Completed():
+		w.WriteHeader(http.StatusConflict)
+	default:
+		if stored.ContentType != nil && *stored.ContentType != "" {
+			w.Header().Set("Content-Type", *stored.ContentType)
+		}
+		w.Header().Set(IdempotencyReplayedHeader, "true")
+		w.WriteHeader(*stored.StatusCode)
+		if stored.Response != nil {
+			_, _ = w.Write([]byte(*stored.Response))
+		}
+	}
+}
+
+// fingerprint identifies the request a key was first used with.
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method))
+	h.Write([]byte(" "))
+	h.Write([]byte(r.URL.Path))
+	h.Write([]byte("\n"))
+	h.Write(body)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(statusCode int) {
+	if r.status == 0 {
+		r.status = statusCode
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(p)
+	return r.ResponseWriter.Write(p)
+}
+
+func (r *responseRecorder) statusCode() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
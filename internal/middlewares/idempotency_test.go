@@ -0,0 +1,166 @@
+package middlewares
+
+import (
+	"context"
+	"gophermart/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memoryIdempotencyStore struct {
+	mu   sync.Mutex
+	keys map[string]*models.IdempotencyKey
+}
+
+func (s *memoryIdempotencyStore) Acquire(
+	_ context.Context,
+	userID string,
+	key string,
+	fingerprint string,
+	_ time.Duration,
+) (*models.IdempotencyKey, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stored, ok := s.keys[userID+key]; ok {
+		return stored, false, nil
+	}
+
+	stored := &models.IdempotencyKey{UserID: userID, Key: key, Fingerprint: fingerprint}
+	s.keys[userID+key] = stored
+	return stored, true, nil
+}
+
+func (s *memoryIdempotencyStore) Complete(
+	ctx context.Context,
+	userID string,
+	key string,
+	statusCode int,
+	contentType string,
+	response []byte,
+) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	body := string(response)
+	stored := s.keys[userID+key]
+	stored.StatusCode = &statusCode
+	stored.ContentType = &contentType
+	stored.Response = &body
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(ctx context.Context, userID string, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, userID+key)
+	return nil
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	store := &memoryIdempotencyStore{keys: map[string]*models.IdempotencyKey{}}
+
+	calls := 0
+	status := http.StatusOK
+	handler := NewIdempotencyMiddleware(store, time.Hour)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(`{"call":1}`))
+		}),
+	)
+
+	send := func(key string, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/api/user/balance/withdraw", strings.NewReader(body))
+		r.Header.Set(IdempotencyKeyHeader, key)
+		r = r.WithContext(context.WithValue(r.Context(), UserIDKey, "user"))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	tests := []struct {
+		name      string
+		key       string
+		body      string
+		status    int
+		want      int
+		wantCalls int
+	}{
+		{"Test #1 First request", "a", `{"sum":1}`, http.StatusOK, http.StatusOK, 1},
+		{"Test #2 Replay", "a", `{"sum":1}`, http.StatusOK, http.StatusOK, 1},
+		{"Test #3 Reused key", "a", `{"sum":2}`, http.StatusOK, http.StatusUnprocessableEntity, 1},
+		{"Test #4 Server error", "b", `{"sum":1}`, http.StatusInternalServerError, http.StatusInternalServerError, 2},
+		{"Test #5 Retry after server error", "b", `{"sum":1}`, http.StatusPaymentRequired, http.StatusPaymentRequired, 3},
+		{"Test #6 Replay of client error", "b", `{"sum":1}`, http.StatusOK, http.StatusPaymentRequired, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status = tt.status
+			w := send(tt.key, tt.body)
+
+			if w.Code != tt.want {
+				t.Errorf("status codes are different: got=%d want=%d", w.Code, tt.want)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("handler calls are different: got=%d want=%d", calls, tt.wantCalls)
+			}
+		})
+	}
+
+	replayed := send("a", `{"sum":1}`)
+	if replayed.Header().Get(IdempotencyReplayedHeader) != "true" || replayed.Body.String() != `{"call":1}` {
+		t.Errorf("replay is different: headers=%v body=%s", replayed.Header(), replayed.Body.String())
+	}
+}
+
+func TestIdempotencyMiddleware_ClientGone(t *testing.T) {
+	store := &memoryIdempotencyStore{keys: map[string]*models.IdempotencyKey{}}
+
+	calls := 0
+	handler := NewIdempotencyMiddleware(store, time.Hour)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(http.StatusOK)
+		}),
+	)
+
+	send := func(cancelled bool) *httptest.ResponseRecorder {
+		ctx, cancel := context.WithCancel(context.WithValue(context.Background(), UserIDKey, "user"))
+		defer cancel()
+		if cancelled {
+			// The client disconnects once the handler has committed.
+			cancel()
+		}
+
+		r := httptest.NewRequest(http.MethodPost, "/api/user/balance/withdraw", strings.NewReader(`{"sum":1}`))
+		r.Header.Set(IdempotencyKeyHeader, "gone")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r.WithContext(ctx))
+		return w
+	}
+
+	send(true)
+	retried := send(false)
+
+	if retried.Code != http.StatusOK || retried.Header().Get(IdempotencyReplayedHeader) != "true" {
+		t.Errorf("retry is not replayed: status=%d headers=%v", retried.Code, retried.Header())
+	}
+	if calls != 1 {
+		t.Errorf("handler calls are different: got=%d want=1", calls)
+	}
+}
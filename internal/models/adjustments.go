@@ -0,0 +1,44 @@
+package models
+
+import (
+	"gophermart/internal/types"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Adjustment is a credit, when Amount is positive, or a debit an admin
+// made to the balance of a user.
+type Adjustment struct {
+	ID        string      `json:"adjustment_id" db:"adjustment_id"`
+	UserID    string      `json:"user_id"       db:"user_id"`
+	AdminID   string      `json:"admin_id"      db:"admin_id"`
+	Amount    types.Money `json:"amount"        db:"amount"`
+	Reason    string      `json:"reason"        db:"reason"`
+	Comment   string      `json:"comment"       db:"comment"`
+	CreatedAt string      `json:"created_at"    db:"created_at"`
+}
+
+func NewAdjustment(adminID string, userID string, schema *AdjustmentCreate) *Adjustment {
+	return &Adjustment{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		AdminID:   adminID,
+		Amount:    schema.Amount,
+		Reason:    schema.Reason,
+		Comment:   schema.Comment,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+type AdjustmentCreate struct {
+	Amount  types.Money `json:"amount"`
+	Reason  string      `json:"reason"  validate:"required,oneof=GOODWILL COMPENSATION CORRECTION FRAUD"`
+	Comment string      `json:"comment" validate:"required,max=1000"`
+}
+
+// AdjustmentsFilter narrows the audit list, empty fields match any.
+type AdjustmentsFilter struct {
+	UserID  string
+	AdminID string
+}
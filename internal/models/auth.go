@@ -1,6 +1,8 @@
 package models
 
 import (
+	"crypto/rand"
+	"encoding/base32"
 	"gophermart/internal/crypto"
 	"time"
 
@@ -9,12 +11,17 @@ import (
 )
 
 type AuthUser struct {
-	ID        string  `db:"user_id"`
-	Login     string  `db:"login"`
-	Password  string  `db:"hashed_password"`
-	CreatedAt string  `db:"created_at"`
-	UpdatedAt string  `db:"updated_at"`
-	DeletedAt *string `db:"deleted_at"`
+	ID       string `db:"user_id"`
+	Login    string `db:"login"`
+	Password string `db:"hashed_password"`
+	// ReferralCode is the code the user invites others with.
+	ReferralCode string  `db:"referral_code"`
+	CreatedAt    string  `db:"created_at"`
+	UpdatedAt    string  `db:"updated_at"`
+	DeletedAt    *string `db:"deleted_at"`
+
+	// ReferredWith is the referral code the user signed up with, if any.
+	ReferredWith string `db:"-"`
 }
 
 func NewAuthUser(
@@ -26,18 +33,35 @@ func NewAuthUser(
 		return nil, errors.Wrapf(err, "failed to hash password")
 	}
 
+	referralCode, err := newReferralCode()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to generate referral code")
+	}
+
 	return &AuthUser{
-		ID:        uuid.NewString(),
-		Login:     login,
-		Password:  hashedPassword,
-		CreatedAt: time.Now().UTC().Format(time.RFC3339),
-		UpdatedAt: time.Now().UTC().Format(time.RFC3339),
+		ID:           uuid.NewString(),
+		Login:        login,
+		Password:     hashedPassword,
+		ReferralCode: referralCode,
+		CreatedAt:    time.Now().UTC().Format(time.RFC3339),
+		UpdatedAt:    time.Now().UTC().Format(time.RFC3339),
 	}, nil
 }
 
+// newReferralCode returns 8 random characters of the base32 alphabet.
+func newReferralCode() (string, error) {
+	buf := make([]byte, 5)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return base32.StdEncoding.EncodeToString(buf), nil
+}
+
 type UserRegister struct {
-	Login    string `json:"login"`
-	Password string `json:"password"`
+	Login        string `json:"login"`
+	Password     string `json:"password"`
+	ReferralCode string `json:"referral_code"`
 }
 
 type UserLogin struct {
@@ -1,32 +1,41 @@
 package models
 
 import (
+	"gophermart/internal/types"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type Balance struct {
-	ID        string  `json:"balance_id" db:"balance_id"`
-	UserID    string  `json:"user_id"    db:"user_id"`
-	Current   float64 `json:"current"    db:"current"`
-	Withdrawn float64 `json:"withdrawn"  db:"withdrawn"`
-	CreatedAt string  `json:"created_at" db:"created_at"`
-	UpdatedAt string  `json:"updated_at" db:"updated_at"`
+	ID        string      `json:"balance_id" db:"balance_id"`
+	UserID    string      `json:"user_id"    db:"user_id"`
+	Current   types.Money `json:"current"    db:"current"`
+	Withdrawn types.Money `json:"withdrawn"  db:"withdrawn"`
+	Held      types.Money `json:"held"       db:"held"`
+	CreatedAt string      `json:"created_at" db:"created_at"`
+	UpdatedAt string      `json:"updated_at" db:"updated_at"`
 }
 
 func NewBalance(userID string) *Balance {
 	return &Balance{
 		ID:        uuid.NewString(),
 		UserID:    userID,
-		Current:   0,
-		Withdrawn: 0,
+		Current:   types.Money{},
+		Withdrawn: types.Money{},
+		Held:      types.Money{},
 		CreatedAt: time.Now().UTC().Format(time.RFC3339),
 		UpdatedAt: time.Now().UTC().Format(time.RFC3339),
 	}
 }
 
+// BalanceRead shows points reserved by active holds apart, Current is what
+// is available to spend. ExpiringSoon is the part of Current which expires
+// soon, ExpiringAt is when the first of it does.
 type BalanceRead struct {
-	Current   float64 `json:"current"`
-	Withdrawn float64 `json:"withdrawn"`
+	Current      types.Money `json:"current"`
+	Withdrawn    types.Money `json:"withdrawn"`
+	Held         types.Money `json:"held"`
+	ExpiringSoon types.Money `json:"expiring_soon"`
+	ExpiringAt   *string     `json:"expiring_at,omitempty"`
 }
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"gophermart/internal/types"
+	"gophermart/pkg/clients/accrual"
+)
+
+// AccrualCallback is a status update pushed by the accrual system.
+// Signature is the verified request signature in lower-case hex, it
+// identifies the delivery.
+type AccrualCallback struct {
+	Signature string
+	Order     accrual.OrderRead
+}
+
+type AccrualCallbackCreate struct {
+	Order   string      `json:"order"   validate:"required"`
+	Status  string      `json:"status"  validate:"required,oneof=REGISTERED PROCESSING INVALID PROCESSED REVOKED"`
+	Accrual types.Money `json:"accrual"`
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"gophermart/internal/types"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Campaign adds to accruals of orders credited between StartsAt and EndsAt.
+// MaxOrders limits it to the first orders of a user, e.g. 1 for the first
+// order, Tier limits it to users of the tier. Multiplier is set for
+// MULTIPLIER campaigns, Points for FIXED ones.
+type Campaign struct {
+	ID         string       `json:"campaign_id"          db:"campaign_id"`
+	Name       string       `json:"name"                 db:"name"`
+	Kind       string       `json:"kind"                 db:"kind"`
+	Multiplier *types.Money `json:"multiplier,omitempty" db:"multiplier"`
+	Points     *types.Money `json:"points,omitempty"     db:"points"`
+	StartsAt   string       `json:"starts_at"            db:"starts_at"`
+	EndsAt     *string      `json:"ends_at"              db:"ends_at"`
+	MaxOrders  *int         `json:"max_orders"           db:"max_orders"`
+	Tier       *string      `json:"tier"                 db:"tier"`
+	Active     bool         `json:"active"               db:"active"`
+	CreatedAt  string       `json:"created_at"           db:"created_at"`
+	UpdatedAt  string       `json:"updated_at"           db:"updated_at"`
+}
+
+type CampaignCreate struct {
+	Name       string       `json:"name"       validate:"required,max=255"`
+	Kind       string       `json:"kind"       validate:"required,oneof=MULTIPLIER FIXED"`
+	Multiplier *types.Money `json:"multiplier" validate:"required_if=Kind MULTIPLIER,excluded_unless=Kind MULTIPLIER"`
+	Points     *types.Money `json:"points"     validate:"required_if=Kind FIXED,excluded_unless=Kind FIXED"`
+	StartsAt   time.Time    `json:"starts_at"  validate:"required"`
+	EndsAt     *time.Time   `json:"ends_at"    validate:"omitempty,gtfield=StartsAt"`
+	MaxOrders  *int         `json:"max_orders" validate:"omitempty,min=1"`
+	Tier       *string      `json:"tier"       validate:"omitempty,max=255"`
+	Active     *bool        `json:"active"`
+}
+
+// NewCampaign builds a campaign from a validated schema,
+// campaigns are active unless said otherwise.
+func NewCampaign(schema *CampaignCreate) *Campaign {
+	now := time.Now().UTC()
+
+	campaign := &Campaign{
+		ID:         uuid.NewString(),
+		Name:       schema.Name,
+		Kind:       schema.Kind,
+		Multiplier: schema.Multiplier,
+		Points:     schema.Points,
+		StartsAt:   schema.StartsAt.UTC().Format(time.RFC3339Nano),
+		MaxOrders:  schema.MaxOrders,
+		Tier:       schema.Tier,
+		Active:     true,
+		CreatedAt:  now.Format(time.RFC3339),
+		UpdatedAt:  now.Format(time.RFC3339),
+	}
+	if schema.EndsAt != nil {
+		endsAt := schema.EndsAt.UTC().Format(time.RFC3339Nano)
+		campaign.EndsAt = &endsAt
+	}
+	if schema.Active != nil {
+		campaign.Active = *schema.Active
+	}
+
+	return campaign
+}
+
+type CampaignBonus struct {
+	ID         string      `json:"bonus_id"    db:"bonus_id"`
+	CampaignID string      `json:"campaign_id" db:"campaign_id"`
+	UserID     string      `json:"user_id"     db:"user_id"`
+	OrderID    string      `json:"order_id"    db:"order_id"`
+	Amount     types.Money `json:"amount"      db:"amount"`
+	Status     string      `json:"status"      db:"status"`
+	CreatedAt  string      `json:"created_at"  db:"created_at"`
+	ReversedAt *string     `json:"reversed_at" db:"reversed_at"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "gophermart/internal/types"
+
+// Clawback takes back Amount accrued for a revoked order. Recovered is
+// what has been debited so far, it falls short of Amount when the policy
+// spared the balance from going below zero.
+type Clawback struct {
+	ID        string      `json:"clawback_id" db:"clawback_id"`
+	OrderID   string      `json:"order_id"    db:"order_id"`
+	UserID    string      `json:"user_id"     db:"user_id"`
+	Number    string      `json:"number"      db:"number"`
+	Amount    types.Money `json:"amount"      db:"amount"`
+	Recovered types.Money `json:"recovered"   db:"recovered"`
+	Policy    string      `json:"policy"      db:"policy"`
+	Source    string      `json:"source"      db:"source"`
+	CreatedAt string      `json:"created_at"  db:"created_at"`
+	UpdatedAt string      `json:"updated_at"  db:"updated_at"`
+}
+
+// Outstanding is what is left to recover.
+func (c *Clawback) Outstanding() types.Money {
+	return c.Amount.Sub(c.Recovered)
+}
+
+type RevokeRecord struct {
+	Number string
+	Change StatusChange
+}
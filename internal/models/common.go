@@ -0,0 +1,11 @@
+package models
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 500
+)
+
+type Page struct {
+	Limit  int
+	Offset int
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type DeadLetter struct {
+	ID            string `json:"dead_letter_id"  db:"dead_letter_id"`
+	OrderID       string `json:"order_id"        db:"order_id"`
+	UserID        string `json:"user_id"         db:"user_id"`
+	Number        string `json:"number"          db:"number"`
+	LastError     string `json:"last_error"      db:"last_error"`
+	Attempts      int    `json:"attempts"        db:"attempts"`
+	FirstFailedAt string `json:"first_failed_at" db:"first_failed_at"`
+	LastFailedAt  string `json:"last_failed_at"  db:"last_failed_at"`
+}
+
+func NewDeadLetter(order *Order, attempts int, lastError string) *DeadLetter {
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	return &DeadLetter{
+		ID:            uuid.NewString(),
+		OrderID:       order.ID,
+		UserID:        order.UserID,
+		Number:        order.Number,
+		LastError:     lastError,
+		Attempts:      attempts,
+		FirstFailedAt: now,
+		LastFailedAt:  now,
+	}
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"gophermart/internal/types"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Hold struct {
+	ID           string      `json:"hold_id"       db:"hold_id"`
+	UserID       string      `json:"-"             db:"user_id"`
+	Order        string      `json:"order"         db:"order"`
+	Sum          types.Money `json:"sum"           db:"sum"`
+	Fee          types.Money `json:"fee"           db:"fee"`
+	Status       string      `json:"status"        db:"status"`
+	WithdrawalID *string     `json:"withdrawal_id" db:"withdrawal_id"`
+	ExpiresAt    string      `json:"expires_at"    db:"expires_at"`
+	CreatedAt    string      `json:"created_at"    db:"created_at"`
+	UpdatedAt    string      `json:"updated_at"    db:"updated_at"`
+}
+
+func NewHold(userID string, order string, sum types.Money, ttl time.Duration) *Hold {
+	now := time.Now().UTC()
+
+	return &Hold{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		Order:     order,
+		Sum:       sum,
+		Status:    types.HoldActive.String(),
+		ExpiresAt: now.Add(ttl).Format(time.RFC3339Nano),
+		CreatedAt: now.Format(time.RFC3339),
+		UpdatedAt: now.Format(time.RFC3339),
+	}
+}
+
+// Held is what the hold keeps out of the available balance.
+func (h *Hold) Held() types.Money {
+	return h.Sum.Add(h.Fee)
+}
+
+type HoldCreate struct {
+	Order string      `json:"order"`
+	Sum   types.Money `json:"sum"`
+}
@@ -0,0 +1,18 @@
+package models
+
+// IdempotencyKey remembers the first response to a request sent with
+// an Idempotency-Key header. StatusCode is nil while it is being handled.
+type IdempotencyKey struct {
+	UserID      string  `db:"user_id"`
+	Key         string  `db:"key"`
+	Fingerprint string  `db:"fingerprint"`
+	StatusCode  *int    `db:"status_code"`
+	ContentType *string `db:"content_type"`
+	Response    *string `db:"response"`
+	CreatedAt   string  `db:"created_at"`
+	ExpiresAt   string  `db:"expires_at"`
+}
+
+func (k *IdempotencyKey) Completed() bool {
+	return k.StatusCode != nil
+}
@@ -0,0 +1,255 @@
+package models
+
+import (
+	"gophermart/internal/types"
+)
+
+type LedgerEntry struct {
+	ID            string      `json:"entry_id"       db:"entry_id"`
+	TransactionID string      `json:"transaction_id" db:"transaction_id"`
+	UserID        *string     `json:"-"              db:"user_id"`
+	Account       string      `json:"account"        db:"account"`
+	Kind          string      `json:"kind"           db:"kind"`
+	Amount        types.Money `json:"amount"         db:"amount"`
+	Reference     *string     `json:"reference"      db:"reference"`
+	CreatedAt     string      `json:"created_at"     db:"created_at"`
+}
+
+// LedgerPosting moves Amount into Account, negative amounts move points out.
+// UserID is empty for system accounts.
+type LedgerPosting struct {
+	UserID  string
+	Account types.LedgerAccount
+	Amount  types.Money
+}
+
+// LedgerTransaction is a set of postings that sum up to zero,
+// Reference points to what caused it, e.g. an order number. Lots, if set,
+// names the point lots the transaction takes out of current: a later
+// credit with the same Lots gives them back with their accrual dates.
+type LedgerTransaction struct {
+	Kind      types.LedgerKind
+	Reference string
+	Lots      string
+	Postings  []LedgerPosting
+}
+
+func NewAccrualTransaction(userID string, orderNumber string, amount types.Money) *LedgerTransaction {
+	return &LedgerTransaction{
+		Kind:      types.LedgerAccrual,
+		Reference: orderNumber,
+		Postings: []LedgerPosting{
+			{UserID: userID, Account: types.AccountUserCurrent, Amount: amount},
+			{Account: types.AccountSystemAccruals, Amount: amount.Neg()},
+		},
+	}
+}
+
+func NewWithdrawalTransaction(userID string, orderNumber string, lots string, sum types.Money) *LedgerTransaction {
+	return &LedgerTransaction{
+		Kind:      types.LedgerWithdrawal,
+		Reference: orderNumber,
+		Lots:      lots,
+		Postings: []LedgerPosting{
+			{UserID: userID, Account: types.AccountUserCurrent, Amount: sum.Neg()},
+			{UserID: userID, Account: types.AccountUserWithdrawn, Amount: sum},
+		},
+	}
+}
+
+// NewWithdrawalFeeTransaction charges the fee of a withdrawal.
+func NewWithdrawalFeeTransaction(userID string, orderNumber string, lots string, fee types.Money) *LedgerTransaction {
+	return &LedgerTransaction{
+		Kind:      types.LedgerFee,
+		Reference: orderNumber,
+		Lots:      lots,
+		Postings: []LedgerPosting{
+			{UserID: userID, Account: types.AccountUserCurrent, Amount: fee.Neg()},
+			{Account: types.AccountSystemFees, Amount: fee},
+		},
+	}
+}
+
+// NewCaptureFeeTransaction charges the fee of a withdrawal from held points.
+func NewCaptureFeeTransaction(userID string, orderNumber string, fee types.Money) *LedgerTransaction {
+	return &LedgerTransaction{
+		Kind:      types.LedgerFee,
+		Reference: orderNumber,
+		Postings: []LedgerPosting{
+			{UserID: userID, Account: types.AccountUserHeld, Amount: fee.Neg()},
+			{Account: types.AccountSystemFees, Amount: fee},
+		},
+	}
+}
+
+// NewReversalTransaction returns withdrawn points to the user.
+func NewReversalTransaction(userID string, orderNumber string, lots string, sum types.Money) *LedgerTransaction {
+	return &LedgerTransaction{
+		Kind:      types.LedgerReversal,
+		Reference: orderNumber,
+		Lots:      lots,
+		Postings: []LedgerPosting{
+			{UserID: userID, Account: types.AccountUserWithdrawn, Amount: sum.Neg()},
+			{UserID: userID, Account: types.AccountUserCurrent, Amount: sum},
+		},
+	}
+}
+
+// NewHoldTransaction reserves points for a withdrawal that is not final yet.
+func NewHoldTransaction(userID string, orderNumber string, lots string, sum types.Money) *LedgerTransaction {
+	return &LedgerTransaction{
+		Kind:      types.LedgerHold,
+		Reference: orderNumber,
+		Lots:      lots,
+		Postings: []LedgerPosting{
+			{UserID: userID, Account: types.AccountUserCurrent, Amount: sum.Neg()},
+			{UserID: userID, Account: types.AccountUserHeld, Amount: sum},
+		},
+	}
+}
+
+// NewCaptureTransaction spends held points.
+func NewCaptureTransaction(userID string, orderNumber string, sum types.Money) *LedgerTransaction {
+	return &LedgerTransaction{
+		Kind:      types.LedgerWithdrawal,
+		Reference: orderNumber,
+		Postings: []LedgerPosting{
+			{UserID: userID, Account: types.AccountUserHeld, Amount: sum.Neg()},
+			{UserID: userID, Account: types.AccountUserWithdrawn, Amount: sum},
+		},
+	}
+}
+
+// NewReleaseTransaction returns held points to the user.
+func NewReleaseTransaction(userID string, orderNumber string, lots string, sum types.Money) *LedgerTransaction {
+	return &LedgerTransaction{
+		Kind:      types.LedgerRelease,
+		Reference: orderNumber,
+		Lots:      lots,
+		Postings: []LedgerPosting{
+			{UserID: userID, Account: types.AccountUserHeld, Amount: sum.Neg()},
+			{UserID: userID, Account: types.AccountUserCurrent, Amount: sum},
+		},
+	}
+}
+
+// NewFeeRefundTransaction returns the fee of a withdrawal to the user.
+func NewFeeRefundTransaction(userID string, orderNumber string, lots string, fee types.Money) *LedgerTransaction {
+	return &LedgerTransaction{
+		Kind:      types.LedgerFeeRefund,
+		Reference: orderNumber,
+		Lots:      lots,
+		Postings: []LedgerPosting{
+			{UserID: userID, Account: types.AccountUserCurrent, Amount: fee},
+			{Account: types.AccountSystemFees, Amount: fee.Neg()},
+		},
+	}
+}
+
+// NewTierBonusTransaction credits what the loyalty tier adds to an accrual.
+func NewTierBonusTransaction(userID string, orderNumber string, bonus types.Money) *LedgerTransaction {
+	return &LedgerTransaction{
+		Kind:      types.LedgerTierBonus,
+		Reference: orderNumber,
+		Postings: []LedgerPosting{
+			{UserID: userID, Account: types.AccountUserCurrent, Amount: bonus},
+			{Account: types.AccountSystemBonuses, Amount: bonus.Neg()},
+		},
+	}
+}
+
+// NewTransferTransaction moves points from one user to another.
+func NewTransferTransaction(senderID string, recipientID string, transferID string, sum types.Money) *LedgerTransaction {
+	return &LedgerTransaction{
+		Kind:      types.LedgerTransfer,
+		Reference: transferID,
+		Lots:      TransferLots(transferID),
+		Postings: []LedgerPosting{
+			{UserID: senderID, Account: types.AccountUserCurrent, Amount: sum.Neg()},
+			{UserID: recipientID, Account: types.AccountUserCurrent, Amount: sum},
+		},
+	}
+}
+
+// NewCampaignBonusTransaction credits what a campaign adds to an accrual.
+func NewCampaignBonusTransaction(userID string, orderNumber string, bonus types.Money) *LedgerTransaction {
+	return &LedgerTransaction{
+		Kind:      types.LedgerCampaign,
+		Reference: orderNumber,
+		Postings: []LedgerPosting{
+			{UserID: userID, Account: types.AccountUserCurrent, Amount: bonus},
+			{Account: types.AccountSystemBonuses, Amount: bonus.Neg()},
+		},
+	}
+}
+
+// NewCampaignBonusReversalTransaction takes a campaign bonus back.
+func NewCampaignBonusReversalTransaction(userID string, orderNumber string, bonus types.Money) *LedgerTransaction {
+	return &LedgerTransaction{
+		Kind:      types.LedgerCampaignReversal,
+		Reference: orderNumber,
+		Postings: []LedgerPosting{
+			{UserID: userID, Account: types.AccountUserCurrent, Amount: bonus.Neg()},
+			{Account: types.AccountSystemBonuses, Amount: bonus},
+		},
+	}
+}
+
+// NewReferralBonusTransaction credits the referral bonus to both the
+// referrer and the referee.
+func NewReferralBonusTransaction(referralID string, referrerID string, refereeID string, bonus types.Money) *LedgerTransaction {
+	return &LedgerTransaction{
+		Kind:      types.LedgerReferral,
+		Reference: referralID,
+		Postings: []LedgerPosting{
+			{UserID: referrerID, Account: types.AccountUserCurrent, Amount: bonus},
+			{UserID: refereeID, Account: types.AccountUserCurrent, Amount: bonus},
+			{Account: types.AccountSystemBonuses, Amount: bonus.Add(bonus).Neg()},
+		},
+	}
+}
+
+// NewAdjustmentTransaction credits, or debits when amount is negative,
+// the user by hand of an admin.
+func NewAdjustmentTransaction(userID string, adjustmentID string, amount types.Money) *LedgerTransaction {
+	return &LedgerTransaction{
+		Kind:      types.LedgerAdjustment,
+		Reference: adjustmentID,
+		Postings: []LedgerPosting{
+			{UserID: userID, Account: types.AccountUserCurrent, Amount: amount},
+			{Account: types.AccountSystemAdjustments, Amount: amount.Neg()},
+		},
+	}
+}
+
+// NewClawbackTransaction takes back points accrued for a revoked order.
+func NewClawbackTransaction(userID string, orderNumber string, sum types.Money) *LedgerTransaction {
+	return &LedgerTransaction{
+		Kind:      types.LedgerClawback,
+		Reference: orderNumber,
+		Postings: []LedgerPosting{
+			{UserID: userID, Account: types.AccountUserCurrent, Amount: sum.Neg()},
+			{Account: types.AccountSystemAccruals, Amount: sum},
+		},
+	}
+}
+
+// NewExpiryTransaction takes away points that outlived the expiration policy.
+func NewExpiryTransaction(userID string, sum types.Money) *LedgerTransaction {
+	return &LedgerTransaction{
+		Kind: types.LedgerExpiry,
+		Postings: []LedgerPosting{
+			{UserID: userID, Account: types.AccountUserCurrent, Amount: sum.Neg()},
+			{Account: types.AccountSystemExpired, Amount: sum},
+		},
+	}
+}
+
+func (t *LedgerTransaction) Balanced() bool {
+	sum := types.Money{}
+	for _, posting := range t.Postings {
+		sum = sum.Add(posting.Amount)
+	}
+
+	return sum.IsZero()
+}
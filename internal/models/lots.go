@@ -0,0 +1,47 @@
+package models
+
+import (
+	"gophermart/internal/types"
+)
+
+// PointLot is a portion of the current balance that was credited at once.
+// Lots are spent oldest first and expire as a whole.
+type PointLot struct {
+	ID        string      `json:"lot_id"     db:"lot_id"`
+	UserID    string      `json:"-"          db:"user_id"`
+	Amount    types.Money `json:"amount"     db:"amount"`
+	Remaining types.Money `json:"remaining"  db:"remaining"`
+	AccruedAt string      `json:"accrued_at" db:"accrued_at"`
+}
+
+// ExpiringPoints is what will expire soon, ExpiresAt is the nearest expiry.
+type ExpiringPoints struct {
+	Amount    types.Money `db:"amount"`
+	ExpiresAt *string     `db:"expires_at"`
+}
+
+// LotSpending is the part of a lot a debit took out of current for
+// SpentFor, Returned is how much of it was given back since.
+type LotSpending struct {
+	ID       string      `db:"spending_id"`
+	LotID    string      `db:"lot_id"`
+	UserID   string      `db:"user_id"`
+	SpentFor string      `db:"spent_for"`
+	Amount   types.Money `db:"amount"`
+	Returned types.Money `db:"returned"`
+}
+
+// HoldLots names the lots spent on a hold.
+func HoldLots(holdID string) string {
+	return "hold:" + holdID
+}
+
+// WithdrawalLots names the lots spent on a withdrawal and its fee.
+func WithdrawalLots(withdrawalID string) string {
+	return "withdrawal:" + withdrawalID
+}
+
+// TransferLots names the lots a transfer moves to the recipient.
+func TransferLots(transferID string) string {
+	return "transfer:" + transferID
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"encoding/json"
+	"gophermart/internal/types"
+)
+
+// UserTier is the tier stored for the user with the points the user
+// accrued within the loyalty program window.
+type UserTier struct {
+	Tier          *string     `db:"tier"`
+	TierUpdatedAt *string     `db:"tier_updated_at"`
+	Accrued       types.Money `db:"-"`
+}
+
+type TierRead struct {
+	Tier          string       `json:"tier"`
+	Multiplier    json.Number  `json:"multiplier"`
+	Accrued       types.Money  `json:"accrued"`
+	NextTier      *string      `json:"next_tier,omitempty"`
+	NextThreshold *types.Money `json:"next_threshold,omitempty"`
+	ToNextTier    *types.Money `json:"to_next_tier,omitempty"`
+}
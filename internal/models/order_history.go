@@ -0,0 +1,37 @@
+package models
+
+import (
+	"encoding/json"
+	"gophermart/internal/types"
+	"gophermart/pkg/clients/accrual"
+)
+
+type OrderStatusHistory struct {
+	ID         string     `json:"-"           db:"history_id"`
+	OrderID    string     `json:"-"           db:"order_id"`
+	FromStatus *string    `json:"from_status" db:"from_status"`
+	Status     string     `json:"status"      db:"status"`
+	Source     string     `json:"source"      db:"source"`
+	Response   types.JSON `json:"response"    db:"response"`
+	ChangedAt  string     `json:"changed_at"  db:"changed_at"`
+}
+
+// StatusChange describes where a status update came from,
+// Response is the accrual system answer that caused it, if any.
+type StatusChange struct {
+	Source   types.StatusSource
+	Response *accrual.OrderRead
+}
+
+func (c StatusChange) ResponseJSON() types.JSON {
+	if c.Response == nil {
+		return nil
+	}
+
+	raw, err := json.Marshal(c.Response)
+	if err != nil {
+		return nil
+	}
+
+	return raw
+}
@@ -8,27 +8,38 @@ import (
 )
 
 type Order struct {
-	ID         string  `json:"order_id"    db:"order_id"`
-	UserID     string  `json:"user_id"     db:"user_id"`
-	Number     string  `json:"number"      db:"number"`
-	Status     string  `json:"status"      db:"status"`
-	Accrual    float64 `json:"accrual"     db:"accrual"`
-	UploadedAt string  `json:"uploaded_at" db:"uploaded_at"`
+	ID             string      `json:"order_id"    db:"order_id"`
+	UserID         string      `json:"user_id"     db:"user_id"`
+	Number         string      `json:"number"      db:"number"`
+	Status         string      `json:"status"      db:"status"`
+	Accrual        types.Money `json:"accrual"     db:"accrual"`
+	UploadedAt     string      `json:"uploaded_at" db:"uploaded_at"`
+	CheckAttempts  int         `json:"-"           db:"check_attempts"`
+	NextCheckAt    *string     `json:"-"           db:"next_check_at"`
+	LeaseOwner     *string     `json:"-"           db:"lease_owner"`
+	LeaseExpiresAt *string     `json:"-"           db:"lease_expires_at"`
+	ReplayedAt     *string     `json:"-"           db:"replayed_at"`
 }
 
 func NewOrder(userID string, number string) *Order {
+	now := time.Now().UTC().Format(time.RFC3339)
+
 	return &Order{
-		ID:         uuid.NewString(),
-		UserID:     userID,
-		Number:     number,
-		Status:     types.OrderNew.String(),
-		Accrual:    0,
-		UploadedAt: time.Now().UTC().Format(time.RFC3339),
+		ID:            uuid.NewString(),
+		UserID:        userID,
+		Number:        number,
+		Status:        types.OrderNew.String(),
+		Accrual:       types.Money{},
+		UploadedAt:    now,
+		CheckAttempts: 0,
+		NextCheckAt:   &now,
 	}
 }
 
 type AccrueRecord struct {
-	UserID string
-	Number string
-	Amount float64
+	OrderID string
+	UserID  string
+	Number  string
+	Amount  types.Money
+	Change  StatusChange
 }
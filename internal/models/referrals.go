@@ -0,0 +1,39 @@
+package models
+
+import (
+	"gophermart/internal/types"
+	"time"
+)
+
+// ReferralTerms is what the referral program pays to each party.
+// Zero Bonus disables rewards, MaxRewards caps the rewarded referrals
+// of one referrer, zero means no cap. Against users referring their own
+// second accounts, codes of accounts younger than MinReferrerAge are
+// refused, as are codes which signed up DailySignups users within the
+// last day. Zero turns either guard off.
+type ReferralTerms struct {
+	Bonus          types.Money
+	MaxRewards     int
+	MinReferrerAge time.Duration
+	DailySignups   int
+}
+
+type Referral struct {
+	ID         string       `json:"referral_id"  db:"referral_id"`
+	ReferrerID string       `json:"-"            db:"referrer_id"`
+	RefereeID  string       `json:"-"            db:"referee_id"`
+	Login      string       `json:"login"        db:"login"`
+	Status     string       `json:"status"       db:"status"`
+	OrderID    *string      `json:"-"            db:"order_id"`
+	Bonus      *types.Money `json:"bonus"        db:"bonus"`
+	CreatedAt  string       `json:"created_at"   db:"created_at"`
+	SettledAt  *string      `json:"settled_at"   db:"settled_at"`
+}
+
+// ReferralsRead is the referral code of a user and the users who signed
+// up with it. Earned sums the bonuses the user got for them.
+type ReferralsRead struct {
+	ReferralCode string      `json:"referral_code"`
+	Earned       types.Money `json:"earned"`
+	Referrals    []Referral  `json:"referrals"`
+}
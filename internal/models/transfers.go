@@ -0,0 +1,44 @@
+package models
+
+import (
+	"gophermart/internal/types"
+
+	"github.com/google/uuid"
+)
+
+type Transfer struct {
+	ID          string      `json:"transfer_id"  db:"transfer_id"`
+	SenderID    string      `json:"sender_id"    db:"sender_id"`
+	RecipientID string      `json:"recipient_id" db:"recipient_id"`
+	Sum         types.Money `json:"sum"          db:"sum"`
+	CreatedAt   string      `json:"created_at"   db:"created_at"`
+}
+
+// NewTransfer describes a transfer to a recipient yet to be found by login.
+func NewTransfer(senderID string, sum types.Money) *Transfer {
+	return &Transfer{
+		ID:       uuid.NewString(),
+		SenderID: senderID,
+		Sum:      sum,
+	}
+}
+
+type TransferCreate struct {
+	Login string      `json:"login" validate:"required"`
+	Sum   types.Money `json:"sum"`
+}
+
+// TransferLimits cap what a user sends per UTC day, zero means no cap.
+type TransferLimits struct {
+	DailySum   types.Money
+	DailyCount int
+}
+
+// TransferRead is a transfer as one of its sides sees it.
+type TransferRead struct {
+	ID           string      `json:"transfer_id"  db:"transfer_id"`
+	Direction    string      `json:"direction"    db:"direction"`
+	Counterparty string      `json:"counterparty" db:"counterparty"`
+	Sum          types.Money `json:"sum"          db:"sum"`
+	CreatedAt    string      `json:"created_at"   db:"created_at"`
+}
@@ -1,27 +1,114 @@
 package models
 
 import (
+	"gophermart/internal/types"
+
 	"github.com/google/uuid"
 )
 
 type Withdrawal struct {
-	ID          string  `json:"withdrawal_id" db:"withdrawal_id"`
-	UserID      string  `json:"user_id"       db:"user_id"`
-	Order       string  `json:"order"         db:"order"`
-	Sum         float64 `json:"sum"           db:"sum"`
-	ProcessedAt string  `json:"processed_at"  db:"processed_at"`
+	ID           string               `json:"withdrawal_id"           db:"withdrawal_id"`
+	UserID       string               `json:"user_id"                 db:"user_id"`
+	Order        string               `json:"order"                   db:"order"`
+	Sum          types.Money          `json:"sum"                     db:"sum"`
+	Fee          types.Money          `json:"fee"                     db:"fee"`
+	Status       string               `json:"status"                  db:"status"`
+	Reversed     types.Money          `json:"reversed"                db:"reversed"`
+	ProcessedAt  string               `json:"processed_at"            db:"processed_at"`
+	ReviewedBy   *string              `json:"reviewed_by,omitempty"   db:"reviewed_by"`
+	ReviewedAt   *string              `json:"reviewed_at,omitempty"   db:"reviewed_at"`
+	ReviewReason *string              `json:"review_reason,omitempty" db:"review_reason"`
+	Reversals    []WithdrawalReversal `json:"reversals,omitempty"     db:"-"`
 }
 
-func NewWithdrawal(userID string, order string, sum float64) *Withdrawal {
+func NewWithdrawal(userID string, order string, sum types.Money) *Withdrawal {
 	return &Withdrawal{
 		ID:     uuid.NewString(),
 		UserID: userID,
 		Order:  order,
 		Sum:    sum,
+		Status: types.WithdrawalCompleted.String(),
 	}
 }
 
+// Reversible is what is left to reverse of the withdrawal.
+func (w *Withdrawal) Reversible() types.Money {
+	return w.Sum.Sub(w.Reversed)
+}
+
+// IsPendingReview tells the withdrawal holds its points until reviewed.
+func (w *Withdrawal) IsPendingReview() bool {
+	return w.Status == types.WithdrawalPendingReview.String()
+}
+
 type WithdrawalCreate struct {
-	Order string  `json:"order"`
-	Sum   float64 `json:"sum"`
+	Order string      `json:"order"`
+	Sum   types.Money `json:"sum"`
+}
+
+type WithdrawalReversal struct {
+	ID           string      `json:"reversal_id" db:"reversal_id"`
+	WithdrawalID string      `json:"-"           db:"withdrawal_id"`
+	UserID       string      `json:"-"           db:"user_id"`
+	Sum          types.Money `json:"sum"         db:"sum"`
+	Reason       *string     `json:"reason"      db:"reason"`
+	ReversedBy   string      `json:"reversed_by" db:"reversed_by"`
+	CreatedAt    string      `json:"created_at"  db:"created_at"`
+}
+
+// NewWithdrawalReversal describes a reversal requested by actorID. A zero sum
+// reverses whatever is left of the withdrawal.
+func NewWithdrawalReversal(
+	withdrawalID string,
+	actorID string,
+	sum types.Money,
+	reason string,
+) *WithdrawalReversal {
+	reversal := &WithdrawalReversal{
+		ID:           uuid.NewString(),
+		WithdrawalID: withdrawalID,
+		Sum:          sum,
+		ReversedBy:   actorID,
+	}
+	if reason != "" {
+		reversal.Reason = &reason
+	}
+
+	return reversal
+}
+
+type WithdrawalReversalCreate struct {
+	Sum    types.Money `json:"sum"`
+	Reason string      `json:"reason" validate:"max=255"`
+}
+
+// WithdrawalReview is an admin decision on a PENDING_REVIEW withdrawal:
+// COMPLETED settles it, REJECTED returns its points to the user.
+type WithdrawalReview struct {
+	WithdrawalID string
+	ReviewerID   string
+	Status       types.WithdrawalStatus
+	Reason       *string
+}
+
+func NewWithdrawalReview(
+	withdrawalID string,
+	reviewerID string,
+	status types.WithdrawalStatus,
+	reason string,
+) *WithdrawalReview {
+	review := &WithdrawalReview{
+		WithdrawalID: withdrawalID,
+		ReviewerID:   reviewerID,
+		Status:       status,
+	}
+	if reason != "" {
+		review.Reason = &reason
+	}
+
+	return review
+}
+
+type WithdrawalReviewCreate struct {
+	Reason string `json:"reason" validate:"max=255"`
 }
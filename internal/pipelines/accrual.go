@@ -3,47 +3,408 @@ package pipelines
 import (
 	"context"
 	"fmt"
+	"gophermart/internal/closer"
+	"gophermart/internal/exceptions"
 	"gophermart/internal/log"
 	"gophermart/internal/models"
 	"gophermart/internal/types"
 	"gophermart/pkg/clients/accrual"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
 )
 
 type OrdersRepo interface {
-	MarkAsProcessing(ctx context.Context, orderIDs []string) (bool, error)
-	MarkAsInvalid(ctx context.Context, orderIDs []string) (bool, error)
+	ClaimDueOrders(ctx context.Context, owner string, leaseTTL time.Duration, limit int) (*[]models.Order, error)
+	ClaimOrder(ctx context.Context, orderID string, owner string, leaseTTL time.Duration) (*models.Order, error)
+	ExtendLeases(ctx context.Context, owner string, leaseTTL time.Duration) (int64, error)
+	ReleaseLeases(ctx context.Context, owner string) (int64, error)
+	ScheduleCheck(ctx context.Context, orderID string, attempts int, nextCheckAt *time.Time) (bool, error)
+	MarkAsProcessing(ctx context.Context, orderIDs []string, change models.StatusChange) (bool, error)
+	MarkAsInvalid(ctx context.Context, orderIDs []string, change models.StatusChange) (bool, error)
 	Accrue(ctx context.Context, record models.AccrueRecord) (bool, error)
+	Revoke(ctx context.Context, record models.RevokeRecord) (*models.Clawback, error)
+}
+
+type DeadLettersRepo interface {
+	Park(ctx context.Context, model *models.DeadLetter) (*models.DeadLetter, error)
 }
 
 type AccrualClient interface {
 	GetOrder(ctx context.Context, order string) (*accrual.OrderRead, error)
 }
 
+type AccrualPipelineSettings struct {
+	BufferSize      int
+	NumberOfWorkers int
+	SweepInterval   time.Duration
+	SweepBatchSize  int
+	BackoffBase     time.Duration
+	BackoffMax      time.Duration
+	MaxAge          time.Duration
+	ReplicaID       string
+	LeaseTTL        time.Duration
+	MaxAttempts     int
+	DrainTimeout    time.Duration
+}
+
+type accrualJob struct {
+	order  models.Order
+	record models.AccrueRecord
+}
+
 type AccrualPipelineImpl struct {
 	client          AccrualClient
 	ordersRepo      OrdersRepo
+	deadLettersRepo DeadLettersRepo
 	preprocessingCh chan models.Order
-	processingCh    chan models.AccrueRecord
+	processingCh    chan accrualJob
 	numberOfWorkers int
+	sweepInterval   time.Duration
+	sweepBatchSize  int
+	backoff         backoffPolicy
+	replicaID       string
+	leaseTTL        time.Duration
+	maxAttempts     int
+	drainTimeout    time.Duration
+
+	// stopCh is closed by Close: the pipeline stops taking orders
+	// and its workers finish what they hold. cancel interrupts them
+	// once the drain timeout is over.
+	stopCh       chan struct{}
+	stopOnce     sync.Once
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
+	processingWg sync.WaitGroup
+
+	inFlightMu sync.Mutex
+	inFlight   map[string]struct{}
+
+	pauseMu     sync.RWMutex
+	pausedUntil time.Time
 }
 
 func NewAccrualPipeline(
 	ordersRepo OrdersRepo,
+	deadLettersRepo DeadLettersRepo,
 	client AccrualClient,
-	bufferSize int,
-	numberOfWorkers int,
+	settings AccrualPipelineSettings,
 ) *AccrualPipelineImpl {
 	return &AccrualPipelineImpl{
 		client:          client,
 		ordersRepo:      ordersRepo,
-		preprocessingCh: make(chan models.Order, bufferSize),
-		processingCh:    make(chan models.AccrueRecord, bufferSize),
-		numberOfWorkers: numberOfWorkers,
+		deadLettersRepo: deadLettersRepo,
+		preprocessingCh: make(chan models.Order, settings.BufferSize),
+		processingCh:    make(chan accrualJob, settings.BufferSize),
+		numberOfWorkers: settings.NumberOfWorkers,
+		sweepInterval:   settings.SweepInterval,
+		sweepBatchSize:  settings.SweepBatchSize,
+		backoff: backoffPolicy{
+			base:   settings.BackoffBase,
+			max:    settings.BackoffMax,
+			maxAge: settings.MaxAge,
+		},
+		replicaID:    settings.ReplicaID,
+		leaseTTL:     settings.LeaseTTL,
+		maxAttempts:  settings.MaxAttempts,
+		drainTimeout: settings.DrainTimeout,
+		stopCh:       make(chan struct{}),
+		inFlight:     make(map[string]struct{}),
+	}
+}
+
+// RegisterOrder leases a freshly uploaded order to this replica and queues it
+// right away. If another replica already holds the lease, the order is left to it.
+func (p *AccrualPipelineImpl) RegisterOrder(ctx context.Context, order *models.Order) {
+	if p.stopped() {
+		return
+	}
+
+	claimed, err := p.ordersRepo.ClaimOrder(ctx, order.ID, p.replicaID, p.leaseTTL)
+	if err != nil {
+		if !errors.Is(err, exceptions.ErrOrderIsLeased) {
+			log.Error(ctx, "failed to claim order", err)
+		}
+		return
+	}
+
+	p.enqueue(ctx, *claimed)
+}
+
+// ApplyStatus applies a status pushed by the accrual system, e.g. by its callback.
+// It runs the same transitions as the preprocessing worker but accrues right away.
+// Orders stay scheduled, so polling still catches anything the callback missed.
+func (p *AccrualPipelineImpl) ApplyStatus(
+	ctx context.Context,
+	order *models.Order,
+	orderRead *accrual.OrderRead,
+	source types.StatusSource,
+) error {
+	change := models.StatusChange{Source: source, Response: orderRead}
+
+	switch orderRead.Status {
+	case string(types.OrderProcessed):
+		accrueRecord := models.AccrueRecord{
+			OrderID: order.ID,
+			UserID:  order.UserID,
+			Number:  order.Number,
+			Amount:  orderRead.Accrual,
+			Change:  change,
+		}
+
+		credited, err := p.ordersRepo.Accrue(ctx, accrueRecord)
+		if err != nil {
+			return errors.Wrapf(err, "failed to accrue order")
+		}
+
+		log.Info(ctx, fmt.Sprintf("order=%s processed by callback, credited=%t", order.Number, credited))
+	case string(types.OrderProcessing):
+		if _, err := p.ordersRepo.MarkAsProcessing(ctx, []string{order.ID}, change); err != nil {
+			return errors.Wrapf(err, "failed to mark processing order")
+		}
+	case string(types.OrderInvalid):
+		if _, err := p.ordersRepo.MarkAsInvalid(ctx, []string{order.ID}, change); err != nil {
+			return errors.Wrapf(err, "failed to mark invalid order")
+		}
+	case string(types.OrderRevoked):
+		clawback, err := p.ordersRepo.Revoke(ctx, models.RevokeRecord{Number: order.Number, Change: change})
+		switch {
+		case errors.Is(err, exceptions.ErrOrderNotProcessed):
+			// Nothing was accrued yet, so there is nothing to take back.
+			if _, err := p.ordersRepo.MarkAsInvalid(ctx, []string{order.ID}, change); err != nil {
+				return errors.Wrapf(err, "failed to mark invalid order")
+			}
+		case err != nil:
+			return errors.Wrapf(err, "failed to revoke order")
+		case clawback != nil:
+			log.Info(ctx, fmt.Sprintf("order=%s revoked by callback, recovered=%s", order.Number, clawback.Recovered))
+		}
+	}
+
+	return nil
+}
+
+// acquire marks order as queued, so the sweeper and RegisterOrder
+// never put the same order into the pipeline twice.
+func (p *AccrualPipelineImpl) acquire(number string) bool {
+	p.inFlightMu.Lock()
+	defer p.inFlightMu.Unlock()
+
+	if _, ok := p.inFlight[number]; ok {
+		return false
+	}
+	p.inFlight[number] = struct{}{}
+
+	return true
+}
+
+func (p *AccrualPipelineImpl) release(number string) {
+	p.inFlightMu.Lock()
+	defer p.inFlightMu.Unlock()
+
+	delete(p.inFlight, number)
+}
+
+func (p *AccrualPipelineImpl) enqueue(ctx context.Context, order models.Order) bool {
+	if p.stopped() || !p.acquire(order.Number) {
+		return false
+	}
+
+	select {
+	case p.preprocessingCh <- order:
+		return true
+	case <-p.stopCh:
+		p.release(order.Number)
+		return false
+	case <-ctx.Done():
+		p.release(order.Number)
+		return false
+	}
+}
+
+func (p *AccrualPipelineImpl) stopped() bool {
+	select {
+	case <-p.stopCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// reschedule stores the next check time of a non-final order and releases it,
+// so the sweeper picks it up again when it is due. cause is the error of the
+// failed check, if any. Orders that run out of attempts or exceed the max age
+// are parked in the dead-letter queue instead.
+func (p *AccrualPipelineImpl) reschedule(ctx context.Context, order *models.Order, cause error) {
+	defer p.release(order.Number)
+
+	since, err := polledSince(order)
+	if err != nil {
+		log.Error(ctx, "failed to parse order polling start", err)
+		since = time.Now().UTC()
+	}
+
+	attempts := order.CheckAttempts + 1
+	nextCheckAt := p.backoff.next(time.Now().UTC(), since, attempts)
+
+	switch {
+	case nextCheckAt == nil:
+		reason := fmt.Sprintf("max age exceeded in status %s", order.Status)
+		if cause != nil {
+			reason = fmt.Sprintf("%s: %s", reason, cause)
+		}
+		p.deadLetter(ctx, order, attempts, reason)
+		return
+	case cause != nil && p.maxAttempts > 0 && attempts >= p.maxAttempts:
+		p.deadLetter(ctx, order, attempts, cause.Error())
+		return
+	}
+
+	if _, err := p.ordersRepo.ScheduleCheck(ctx, order.ID, attempts, nextCheckAt); err != nil {
+		log.Error(ctx, "failed to schedule order check", err)
+	}
+}
+
+func (p *AccrualPipelineImpl) deadLetter(
+	ctx context.Context,
+	order *models.Order,
+	attempts int,
+	reason string,
+) {
+	deadLetter, err := p.deadLettersRepo.Park(ctx, models.NewDeadLetter(order, attempts, reason))
+	if err != nil {
+		log.Error(ctx, "failed to park order in dead-letter queue", err)
+		return
+	}
+	if deadLetter == nil {
+		log.Debug(ctx, fmt.Sprintf("order=%s is settled, not parking it", order.Number))
+		return
+	}
+
+	log.Warn(
+		ctx,
+		fmt.Sprintf(
+			"order=%s parked in dead-letter queue after %d checks: %s",
+			order.Number,
+			attempts,
+			reason,
+		),
+	)
+}
+
+// requeue returns an order to the schedule at the given time
+// without counting the interrupted check as an attempt.
+func (p *AccrualPipelineImpl) requeue(ctx context.Context, order *models.Order, at time.Time) {
+	defer p.release(order.Number)
+
+	if _, err := p.ordersRepo.ScheduleCheck(ctx, order.ID, order.CheckAttempts, &at); err != nil {
+		log.Error(ctx, "failed to requeue order", err)
+	}
+}
+
+// pause stops all preprocessing workers for d, extending an active pause if needed.
+func (p *AccrualPipelineImpl) pause(d time.Duration) time.Time {
+	p.pauseMu.Lock()
+	defer p.pauseMu.Unlock()
+
+	until := time.Now().Add(d)
+	if until.After(p.pausedUntil) {
+		p.pausedUntil = until
 	}
+
+	return p.pausedUntil
 }
 
-func (p *AccrualPipelineImpl) RegisterOrder(order *models.Order) {
-	p.preprocessingCh <- *order
+// retryAfter tells whether err asks to stop calling the accrual system for a while:
+// either it answered 429 or its circuit breaker is open.
+func retryAfter(err error) (time.Duration, bool) {
+	var rateLimitErr *accrual.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return rateLimitErr.RetryAfter, true
+	}
+
+	var circuitOpenErr *accrual.CircuitOpenError
+	if errors.As(err, &circuitOpenErr) {
+		return circuitOpenErr.RetryAfter, true
+	}
+
+	return 0, false
+}
+
+func (p *AccrualPipelineImpl) waitWhilePaused(ctx context.Context) bool {
+	for {
+		p.pauseMu.RLock()
+		wait := time.Until(p.pausedUntil)
+		p.pauseMu.RUnlock()
+
+		if wait <= 0 {
+			return true
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-p.stopCh:
+			timer.Stop()
+			return false
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		}
+	}
+}
+
+func (p *AccrualPipelineImpl) sweep(ctx context.Context) {
+	orders, err := p.ordersRepo.ClaimDueOrders(
+		ctx,
+		p.replicaID,
+		p.leaseTTL,
+		p.sweepBatchSize,
+	)
+	if err != nil {
+		log.Error(ctx, "failed to claim due orders", err)
+		return
+	}
+
+	enqueued := 0
+	for _, order := range *orders {
+		if p.enqueue(ctx, order) {
+			enqueued++
+		}
+	}
+
+	if enqueued > 0 {
+		log.Info(ctx, fmt.Sprintf("sweeper: enqueued %d due orders", enqueued))
+	}
+}
+
+func (p *AccrualPipelineImpl) sweeper(ctx context.Context) {
+	log.Info(ctx, fmt.Sprintf("starting sweeper for replica=%s: recovering due orders", p.replicaID))
+	p.sweep(ctx)
+
+	ticker := time.NewTicker(p.sweepInterval)
+	defer ticker.Stop()
+
+	leaseTicker := time.NewTicker(p.leaseTTL / 3)
+	defer leaseTicker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.sweep(ctx)
+		case <-leaseTicker.C:
+			if _, err := p.ordersRepo.ExtendLeases(ctx, p.replicaID, p.leaseTTL); err != nil {
+				log.Error(ctx, "failed to extend order leases", err)
+			}
+		case <-p.stopCh:
+			log.Info(ctx, "sweeper shutdown")
+			return
+		case <-ctx.Done():
+			log.Info(ctx, "sweeper shutdown")
+			return
+		}
+	}
 }
 
 func (p *AccrualPipelineImpl) preprocessingWorker(ctx context.Context, workerID int) {
@@ -52,6 +413,12 @@ func (p *AccrualPipelineImpl) preprocessingWorker(ctx context.Context, workerID
 	for {
 		select {
 		case order := <-p.preprocessingCh:
+			if p.stopped() {
+				p.release(order.Number)
+				log.Info(ctx, fmt.Sprintf("preprocessing Worker №%d shutdown", workerID))
+				return
+			}
+
 			log.Info(
 				ctx,
 				fmt.Sprintf(
@@ -61,9 +428,33 @@ func (p *AccrualPipelineImpl) preprocessingWorker(ctx context.Context, workerID
 				),
 			)
 
+			if !p.waitWhilePaused(ctx) {
+				p.release(order.Number)
+				log.Info(ctx, fmt.Sprintf("preprocessing Worker №%d shutdown", workerID))
+				return
+			}
+
 			orderRead, err := p.client.GetOrder(ctx, order.Number)
 			if err != nil {
+				if wait, ok := retryAfter(err); ok {
+					until := p.pause(wait)
+					log.Warn(
+						ctx,
+						fmt.Sprintf(
+							"preprocessing Worker №%d: %s, paused until %s, order=%s requeued",
+							workerID,
+							err,
+							until.Format(time.RFC3339),
+							order.Number,
+						),
+					)
+					p.requeue(ctx, &order, until)
+					continue
+				}
+
 				log.Error(ctx, "failed to get order info", err)
+				p.reschedule(ctx, &order, err)
+				continue
 			}
 
 			log.Info(
@@ -76,15 +467,25 @@ func (p *AccrualPipelineImpl) preprocessingWorker(ctx context.Context, workerID
 				),
 			)
 
+			change := models.StatusChange{Source: types.SourcePoll, Response: orderRead}
+
 			switch {
 			case orderRead.Status == string(types.OrderProcessed):
 				accrueRecord := models.AccrueRecord{
-					UserID: order.UserID,
-					Number: order.Number,
-					Amount: orderRead.Accrual,
+					OrderID: order.ID,
+					UserID:  order.UserID,
+					Number:  order.Number,
+					Amount:  orderRead.Accrual,
+					Change:  change,
 				}
 
-				p.processingCh <- accrueRecord
+				select {
+				case p.processingCh <- accrualJob{order: order, record: accrueRecord}:
+				case <-ctx.Done():
+					p.release(order.Number)
+					log.Info(ctx, fmt.Sprintf("preprocessing Worker №%d shutdown", workerID))
+					return
+				}
 
 				log.Info(
 					ctx,
@@ -95,10 +496,12 @@ func (p *AccrualPipelineImpl) preprocessingWorker(ctx context.Context, workerID
 					),
 				)
 			case orderRead.Status == string(types.OrderProcessing):
-				_, err := p.ordersRepo.MarkAsProcessing(ctx, []string{order.ID})
+				_, err := p.ordersRepo.MarkAsProcessing(ctx, []string{order.ID}, change)
 				if err != nil {
 					log.Error(ctx, "failed to mark processing order", err)
 				}
+				order.Status = types.OrderProcessing.String()
+				p.reschedule(ctx, &order, err)
 
 				log.Info(
 					ctx,
@@ -109,10 +512,13 @@ func (p *AccrualPipelineImpl) preprocessingWorker(ctx context.Context, workerID
 					),
 				)
 			case orderRead.Status == string(types.OrderInvalid):
-				_, err := p.ordersRepo.MarkAsInvalid(ctx, []string{order.ID})
+				_, err := p.ordersRepo.MarkAsInvalid(ctx, []string{order.ID}, change)
 				if err != nil {
 					log.Error(ctx, "failed to mark invalid order", err)
+					p.reschedule(ctx, &order, err)
+					continue
 				}
+				p.release(order.Number)
 
 				log.Info(
 					ctx,
@@ -123,6 +529,7 @@ func (p *AccrualPipelineImpl) preprocessingWorker(ctx context.Context, workerID
 					),
 				)
 			default:
+				p.reschedule(ctx, &order, nil)
 				log.Info(
 					ctx,
 					fmt.Sprintf(
@@ -132,6 +539,9 @@ func (p *AccrualPipelineImpl) preprocessingWorker(ctx context.Context, workerID
 					),
 				)
 			}
+		case <-p.stopCh:
+			log.Info(ctx, fmt.Sprintf("preprocessing Worker №%d shutdown", workerID))
+			return
 		case <-ctx.Done():
 			log.Info(ctx, fmt.Sprintf("preprocessing Worker №%d shutdown", workerID))
 			return
@@ -144,7 +554,13 @@ func (p *AccrualPipelineImpl) processingWorker(ctx context.Context, workerID int
 
 	for {
 		select {
-		case accrueRecord := <-p.processingCh:
+		case job, ok := <-p.processingCh:
+			if !ok {
+				log.Info(ctx, fmt.Sprintf("processing Worker №%d shutdown", workerID))
+				return
+			}
+
+			accrueRecord := job.record
 			log.Info(
 				ctx,
 				fmt.Sprintf(
@@ -154,17 +570,21 @@ func (p *AccrualPipelineImpl) processingWorker(ctx context.Context, workerID int
 				),
 			)
 
-			_, err := p.ordersRepo.Accrue(ctx, accrueRecord)
+			credited, err := p.ordersRepo.Accrue(ctx, accrueRecord)
 			if err != nil {
 				log.Error(ctx, "failed to accrue order", err)
+				p.reschedule(ctx, &job.order, errors.Wrapf(err, "failed to accrue order"))
+				continue
 			}
+			p.release(accrueRecord.Number)
 
 			log.Info(
 				ctx,
 				fmt.Sprintf(
-					"processing Worker №%d: order=%s processed",
+					"processing Worker №%d: order=%s processed, credited=%t",
 					workerID,
 					accrueRecord.Number,
+					credited,
 				),
 			)
 		case <-ctx.Done():
@@ -174,10 +594,87 @@ func (p *AccrualPipelineImpl) processingWorker(ctx context.Context, workerID int
 	}
 }
 
+// Start runs the workers on a context detached from ctx: cancelling the
+// application must not interrupt an accrual halfway, Close stops them instead.
 func (p *AccrualPipelineImpl) Start(ctx context.Context) {
+	ctx, p.cancel = context.WithCancel(context.WithoutCancel(ctx))
+
 	log.Info(ctx, fmt.Sprintf("Starting %d workers", p.numberOfWorkers))
 	for i := 1; i <= p.numberOfWorkers; i++ {
-		go p.preprocessingWorker(ctx, i)
-		go p.processingWorker(ctx, i)
+		p.wg.Add(1)
+		go func(workerID int) {
+			defer p.wg.Done()
+			p.preprocessingWorker(ctx, workerID)
+		}(i)
+
+		p.processingWg.Add(1)
+		go func(workerID int) {
+			defer p.processingWg.Done()
+			p.processingWorker(ctx, workerID)
+		}(i)
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.sweeper(ctx)
+	}()
+
+	closer.Add(p.Close)
+}
+
+// Close stops taking orders and lets the workers finish the orders they hold,
+// accruals that are already known included. Whatever is not done within the
+// drain timeout is interrupted. Queued orders are not checked: their leases
+// are released, so this or another replica sweeps them right away.
+func (p *AccrualPipelineImpl) Close() error {
+	ctx := context.Background()
+
+	p.stopOnce.Do(func() { close(p.stopCh) })
+	log.Info(ctx, fmt.Sprintf("draining accrual pipeline, timeout %s", p.drainTimeout))
+
+	deadline := time.Now().Add(p.drainTimeout)
+
+	// Preprocessing workers are the only senders to processingCh,
+	// it is closed once they are gone and processing workers empty it.
+	if !waitUntil(&p.wg, deadline) {
+		log.Warn(ctx, "accrual pipeline drain timeout exceeded, interrupting workers")
+		p.cancel()
+		p.wg.Wait()
+	}
+	close(p.processingCh)
+
+	if !waitUntil(&p.processingWg, deadline) {
+		log.Warn(ctx, "accrual pipeline drain timeout exceeded, interrupting workers")
+		p.cancel()
+		p.processingWg.Wait()
+	}
+	p.cancel()
+
+	released, err := p.ordersRepo.ReleaseLeases(ctx, p.replicaID)
+	if err != nil {
+		return errors.Wrapf(err, "failed to release order leases")
+	}
+
+	log.Info(ctx, fmt.Sprintf("accrual pipeline drained, released %d leases", released))
+
+	return nil
+}
+
+func waitUntil(wg *sync.WaitGroup, deadline time.Time) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
 	}
 }
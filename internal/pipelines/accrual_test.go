@@ -0,0 +1,157 @@
+package pipelines
+
+import (
+	"context"
+	"gophermart/internal/models"
+	"gophermart/internal/types"
+	"gophermart/pkg/clients/accrual"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeOrdersRepo struct {
+	mu       sync.Mutex
+	accrued  []string
+	released int
+}
+
+func (r *fakeOrdersRepo) ClaimDueOrders(context.Context, string, time.Duration, int) (*[]models.Order, error) {
+	return &[]models.Order{}, nil
+}
+
+func (r *fakeOrdersRepo) ClaimOrder(_ context.Context, orderID string, _ string, _ time.Duration) (*models.Order, error) {
+	return &models.Order{ID: orderID, Number: orderID, Status: types.OrderNew.String()}, nil
+}
+
+func (r *fakeOrdersRepo) ExtendLeases(context.Context, string, time.Duration) (int64, error) {
+	return 0, nil
+}
+
+func (r *fakeOrdersRepo) ReleaseLeases(context.Context, string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.released++
+	return 0, nil
+}
+
+func (r *fakeOrdersRepo) ScheduleCheck(context.Context, string, int, *time.Time) (bool, error) {
+	return true, nil
+}
+
+func (r *fakeOrdersRepo) MarkAsProcessing(context.Context, []string, models.StatusChange) (bool, error) {
+	return true, nil
+}
+
+func (r *fakeOrdersRepo) MarkAsInvalid(context.Context, []string, models.StatusChange) (bool, error) {
+	return true, nil
+}
+
+func (r *fakeOrdersRepo) Accrue(ctx context.Context, record models.AccrueRecord) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.accrued = append(r.accrued, record.Number)
+	return true, nil
+}
+
+func (r *fakeOrdersRepo) Revoke(context.Context, models.RevokeRecord) (*models.Clawback, error) {
+	return nil, nil
+}
+
+type fakeDeadLettersRepo struct{}
+
+func (r *fakeDeadLettersRepo) Park(_ context.Context, model *models.DeadLetter) (*models.DeadLetter, error) {
+	return model, nil
+}
+
+type slowAccrualClient struct {
+	delay   time.Duration
+	started chan struct{}
+}
+
+func (c *slowAccrualClient) GetOrder(ctx context.Context, order string) (*accrual.OrderRead, error) {
+	c.started <- struct{}{}
+
+	select {
+	case <-time.After(c.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	return &accrual.OrderRead{Order: order, Status: types.OrderProcessed.String(), Accrual: types.MoneyFromInt(100)}, nil
+}
+
+func newTestPipeline(repo *fakeOrdersRepo, client AccrualClient, drainTimeout time.Duration) *AccrualPipelineImpl {
+	return NewAccrualPipeline(repo, &fakeDeadLettersRepo{}, client, AccrualPipelineSettings{
+		BufferSize:      10,
+		NumberOfWorkers: 1,
+		SweepInterval:   time.Hour,
+		SweepBatchSize:  10,
+		BackoffBase:     time.Second,
+		BackoffMax:      time.Minute,
+		MaxAge:          time.Hour,
+		ReplicaID:       "test",
+		LeaseTTL:        time.Minute,
+		DrainTimeout:    drainTimeout,
+	})
+}
+
+func TestAccrualPipelineImpl_Close_DrainsInFlightOrder(t *testing.T) {
+	repo := &fakeOrdersRepo{}
+	client := &slowAccrualClient{delay: 100 * time.Millisecond, started: make(chan struct{}, 1)}
+	p := newTestPipeline(repo, client, 5*time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.Start(ctx)
+
+	p.RegisterOrder(ctx, &models.Order{ID: "1"})
+	<-client.started
+
+	// Cancelling the application must not interrupt the order being checked.
+	cancel()
+	if err := p.Close(); err != nil {
+		t.Fatalf("failed to close pipeline: %s", err)
+	}
+
+	if len(repo.accrued) != 1 {
+		t.Errorf("accrued orders are different: got=%v want=[1]", repo.accrued)
+	}
+	if repo.released != 1 {
+		t.Errorf("leases released %d times, want once", repo.released)
+	}
+
+	p.RegisterOrder(context.Background(), &models.Order{ID: "2"})
+	if len(p.preprocessingCh) != 0 {
+		t.Errorf("closed pipeline accepted an order")
+	}
+}
+
+func TestAccrualPipelineImpl_Close_InterruptsAfterTimeout(t *testing.T) {
+	repo := &fakeOrdersRepo{}
+	client := &slowAccrualClient{delay: time.Hour, started: make(chan struct{}, 1)}
+	p := newTestPipeline(repo, client, 50*time.Millisecond)
+
+	p.Start(context.Background())
+
+	p.RegisterOrder(context.Background(), &models.Order{ID: "1"})
+	<-client.started
+
+	start := time.Now()
+	if err := p.Close(); err != nil {
+		t.Fatalf("failed to close pipeline: %s", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("close took %s, drain timeout is not respected", elapsed)
+	}
+	if len(repo.accrued) != 0 {
+		t.Errorf("interrupted order was accrued: %v", repo.accrued)
+	}
+	if repo.released != 1 {
+		t.Errorf("leases released %d times, want once", repo.released)
+	}
+}
@@ -1,34 +1,52 @@
 package pipelines
 
 import (
-	"context"
+	"fmt"
+	"gophermart/internal/config"
 	"gophermart/internal/repository"
-	"gophermart/pkg/clients/accrual"
-	"time"
+	"os"
+
+	"github.com/google/uuid"
 )
 
-var AccrualPipeline AccrualPipelineImpl
+var AccrualPipeline *AccrualPipelineImpl
 
 func InitAccrualPipeline(
-	ctx context.Context,
-	accrualBaseURL string,
-	accrualRetryCount int,
-	accrualRetryWaitTime time.Duration,
-	accrualRetryMaxWaitTime time.Duration,
+	cfg *config.Config,
 	repos *repository.Repos,
-	bufferSize int,
-	numberOfWorkers int,
+	client AccrualClient,
 ) {
-	AccrualPipeline = *NewAccrualPipeline(
+	AccrualPipeline = NewAccrualPipeline(
 		repository.NewOrdersRepoImpl(repos),
-		accrual.NewAccrualClient(
-			ctx,
-			accrualBaseURL,
-			accrualRetryCount,
-			accrualRetryWaitTime,
-			accrualRetryMaxWaitTime,
-		),
-		bufferSize,
-		numberOfWorkers,
+		repository.NewDeadLettersRepo(repos),
+		client,
+		AccrualPipelineSettings{
+			BufferSize:      cfg.AccrualPipelineBufferSize,
+			NumberOfWorkers: cfg.AccrualPipelineNumberOfWorkers,
+			SweepInterval:   cfg.AccrualPipelineSweepInterval,
+			SweepBatchSize:  cfg.AccrualPipelineSweepBatchSize,
+			BackoffBase:     cfg.AccrualPipelineBackoffBase,
+			BackoffMax:      cfg.AccrualPipelineBackoffMax,
+			MaxAge:          cfg.AccrualPipelineMaxAge,
+			ReplicaID:       replicaID(cfg.AccrualPipelineReplicaID),
+			LeaseTTL:        cfg.AccrualPipelineLeaseTTL,
+			MaxAttempts:     cfg.AccrualPipelineMaxAttempts,
+			DrainTimeout:    cfg.AccrualPipelineDrainTimeout,
+		},
 	)
 }
+
+// replicaID identifies the lease owner. Unless configured explicitly,
+// every process start gets a new one, so leases of a crashed process expire.
+func replicaID(configured string) string {
+	if configured != "" {
+		return configured
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "gophermart"
+	}
+
+	return fmt.Sprintf("%s-%s", hostname, uuid.NewString())
+}
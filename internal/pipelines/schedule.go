@@ -0,0 +1,45 @@
+package pipelines
+
+import (
+	"gophermart/internal/models"
+	"math"
+	"time"
+)
+
+type backoffPolicy struct {
+	base   time.Duration
+	max    time.Duration
+	maxAge time.Duration
+}
+
+// next returns the time of the following accrual check for an order that
+// has been polled since polledSince and already checked attempts times.
+// A nil result means the order is polled for longer than maxAge and should
+// not be polled again.
+func (b backoffPolicy) next(now time.Time, polledSince time.Time, attempts int) *time.Time {
+	delay := time.Duration(float64(b.base) * math.Pow(2, float64(attempts)))
+	if delay <= 0 || delay > b.max {
+		delay = b.max
+	}
+
+	nextCheckAt := now.Add(delay)
+	if b.maxAge > 0 && nextCheckAt.After(polledSince.Add(b.maxAge)) {
+		return nil
+	}
+
+	return &nextCheckAt
+}
+
+// polledSince is when polling of the order started: its upload or, once
+// replayed from the dead-letter queue, the last replay.
+func polledSince(order *models.Order) (time.Time, error) {
+	if order.ReplayedAt != nil {
+		return parseTimestamp(*order.ReplayedAt)
+	}
+
+	return parseTimestamp(order.UploadedAt)
+}
+
+func parseTimestamp(raw string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, raw)
+}
@@ -0,0 +1,35 @@
+package pipelines
+
+import (
+	"gophermart/internal/models"
+	"testing"
+	"time"
+)
+
+func TestBackoffPolicy_next_Replayed(t *testing.T) {
+	b := backoffPolicy{base: time.Second, max: time.Minute, maxAge: time.Hour}
+	now := time.Now().UTC()
+
+	uploadedAt := now.Add(-2 * time.Hour).Format(time.RFC3339Nano)
+	replayedAt := now.Add(-time.Minute).Format(time.RFC3339Nano)
+
+	tests := []struct {
+		name   string
+		order  *models.Order
+		wantOK bool
+	}{
+		{"Test #1 Past max age", &models.Order{UploadedAt: uploadedAt}, false},
+		{"Test #2 Replayed", &models.Order{UploadedAt: uploadedAt, ReplayedAt: &replayedAt}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			since, err := polledSince(tt.order)
+			if err != nil {
+				t.Fatalf("failed to get polling start: %s", err)
+			}
+			if got := b.next(now, since, 1) != nil; got != tt.wantOK {
+				t.Errorf("next check is different: got=%v want=%v", got, tt.wantOK)
+			}
+		})
+	}
+}
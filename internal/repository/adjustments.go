@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"gophermart/internal/exceptions"
+	"gophermart/internal/models"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/pkg/errors"
+)
+
+type AdjustmentsRepoImpl struct {
+	repos *Repos
+}
+
+func NewAdjustmentsRepo(repos *Repos) *AdjustmentsRepoImpl {
+	return &AdjustmentsRepoImpl{repos: repos}
+}
+
+// Create posts the adjustment to the ledger and records it for audit in
+// one transaction. A debit may not take the user below zero, the same as
+// a withdrawal.
+func (r *AdjustmentsRepoImpl) Create(
+	ctx context.Context,
+	model *models.Adjustment,
+) (*models.Adjustment, error) {
+	tx, err := r.repos.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to begin transaction")
+	}
+	defer rollback(tx)
+
+	qu, _, err := goqu.
+		Select("user_id").
+		From(usersTName).
+		Where(
+			goqu.C("user_id").Eq(model.UserID),
+			goqu.C("deleted_at").IsNull(),
+		).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	var userID string
+	if err := tx.GetContext(ctx, &userID, qu); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, exceptions.ErrUserNotFound
+		}
+		return nil, errors.Wrapf(err, "failed to get user")
+	}
+
+	balances, err := ledgerPost(
+		ctx,
+		tx,
+		models.NewAdjustmentTransaction(model.UserID, model.ID, model.Amount),
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to post adjustment")
+	}
+
+	if model.Amount.IsNegative() && balances[model.UserID].Current.IsNegative() {
+		return nil, exceptions.ErrBalanceIsNegative
+	}
+
+	qu, _, err = goqu.
+		Insert(adjustmentsTName).
+		Rows(model).
+		Returning(&models.Adjustment{}).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	var adjustment models.Adjustment
+	err = tx.QueryRowxContext(ctx, qu).StructScan(&adjustment)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to insert adjustment")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrapf(err, "failed to commit")
+	}
+
+	return &adjustment, nil
+}
+
+// List returns adjustments matching filter, newest first.
+func (r *AdjustmentsRepoImpl) List(
+	ctx context.Context,
+	filter models.AdjustmentsFilter,
+	limit int,
+	offset int,
+) (*[]models.Adjustment, error) {
+	where := []exp.Expression{}
+	if filter.UserID != "" {
+		where = append(where, goqu.C("user_id").Eq(filter.UserID))
+	}
+	if filter.AdminID != "" {
+		where = append(where, goqu.C("admin_id").Eq(filter.AdminID))
+	}
+
+	qu, _, err := goqu.
+		Select(&models.Adjustment{}).
+		From(adjustmentsTName).
+		Where(where...).
+		Order(goqu.I("created_at").Desc(), goqu.I("adjustment_id").Asc()).
+		Limit(uint(limit)).
+		Offset(uint(offset)).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	adjustments := []models.Adjustment{}
+	if err := r.repos.DB.SelectContext(ctx, &adjustments, qu); err != nil {
+		return nil, errors.Wrapf(err, "failed to select adjustments")
+	}
+
+	return &adjustments, nil
+}
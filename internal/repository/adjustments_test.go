@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"gophermart/internal/exceptions"
+	"gophermart/internal/models"
+	"gophermart/internal/types"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestAdjustmentsRepoImpl_Create(t *testing.T) {
+	repos := setupRepos(t)
+	ctx := context.Background()
+
+	admin := createTestUser(t, repos, "adjustments-")
+	user := createTestUser(t, repos, "adjustments-")
+
+	adjust := func(amount int64) (*models.Adjustment, error) {
+		return repos.AdjustmentsRepo.Create(ctx, models.NewAdjustment(admin.ID, user.ID, &models.AdjustmentCreate{
+			Amount:  types.MoneyFromInt(amount),
+			Reason:  types.AdjustmentCorrection.String(),
+			Comment: "test",
+		}))
+	}
+
+	if _, err := adjust(100); err != nil {
+		t.Fatalf("failed to credit: %s", err)
+	}
+	if _, err := adjust(-150); !errors.Is(err, exceptions.ErrBalanceIsNegative) {
+		t.Errorf("error is different: got=%v want=%v", err, exceptions.ErrBalanceIsNegative)
+	}
+	if _, err := adjust(-40); err != nil {
+		t.Fatalf("failed to debit: %s", err)
+	}
+
+	balance, err := repos.BalanceRepo.GetOrCreateForUser(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("failed to get balance: %s", err)
+	}
+	if !balance.Current.Equal(types.MoneyFromInt(60)) {
+		t.Errorf("current is different: got=%s want=60", balance.Current)
+	}
+
+	adjustments, err := repos.AdjustmentsRepo.List(ctx, models.AdjustmentsFilter{UserID: user.ID}, 10, 0)
+	if err != nil {
+		t.Fatalf("failed to list adjustments: %s", err)
+	}
+	if len(*adjustments) != 2 {
+		t.Fatalf("adjustments count is different: got=%d want=2", len(*adjustments))
+	}
+	for _, adjustment := range *adjustments {
+		if adjustment.AdminID != admin.ID {
+			t.Errorf("admin is different: got=%s want=%s", adjustment.AdminID, admin.ID)
+		}
+	}
+}
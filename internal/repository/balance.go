@@ -4,7 +4,6 @@ import (
 	"context"
 	"database/sql"
 	"gophermart/internal/exceptions"
-	"gophermart/internal/log"
 	"gophermart/internal/models"
 
 	"github.com/doug-martin/goqu/v9"
@@ -38,11 +37,15 @@ func (r *BalanceRepoImpl) GetOrCreateForUser(
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			balance, err := r.Create(ctx, models.NewBalance(userID))
-			if err != nil {
+			if err == nil {
+				return balance, nil
+			}
+			if !errors.Is(err, exceptions.ErrBalanceAlreadyExists) {
 				return nil, errors.Wrapf(err, "failed to create balance")
 			}
 
-			return balance, nil
+			// Created concurrently by another request
+			return r.GetOrCreateForUser(ctx, userID)
 		} else {
 			return nil, errors.Wrapf(err, "failed to execute query")
 		}
@@ -80,6 +83,7 @@ func (r *BalanceRepoImpl) Create(
 	qu, _, err := goqu.
 		Insert(balanceTName).
 		Rows(model).
+		OnConflict(goqu.DoNothing()).
 		Returning(
 			"balance_id",
 			"user_id",
@@ -96,47 +100,11 @@ func (r *BalanceRepoImpl) Create(
 	var balance models.Balance
 	err = r.repos.DB.QueryRowxContext(ctx, qu).StructScan(&balance)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, exceptions.ErrBalanceAlreadyExists
+		}
 		return nil, errors.Wrapf(err, "failed to insert")
 	}
 
 	return &balance, nil
 }
-
-func (r *BalanceRepoImpl) Update(
-	ctx context.Context,
-	model *models.Balance,
-) (*models.Balance, error) {
-	qu, _, err := goqu.
-		Update(balanceTName).
-		Set(model).
-		Where(goqu.C("balance_id").Eq(model.ID)).
-		ToSQL()
-	if err != nil {
-		return nil, errors.Wrapf(err, "failed to build query")
-	}
-
-	tx, err := r.repos.DB.BeginTxx(ctx, nil)
-	if err != nil {
-		return nil, errors.Wrapf(err, "failed to begin transaction")
-	}
-	defer func() {
-		if err := tx.Rollback(); err != nil {
-			log.Error(context.Background(), "failed to rollback", err)
-		}
-	}()
-
-	if _, err := tx.ExecContext(ctx, qu); err != nil {
-		return nil, errors.Wrapf(err, "update balance error during execute query")
-	}
-
-	balance, err := r.Get(ctx, model.ID)
-	if err != nil {
-		return nil, errors.Wrapf(err, "refresh balance error")
-	}
-
-	if err := tx.Commit(); err != nil {
-		return nil, errors.Wrapf(err, "failed to commit")
-	}
-
-	return balance, nil
-}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"gophermart/internal/exceptions"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/pkg/errors"
+)
+
+type CallbacksRepoImpl struct {
+	repos *Repos
+}
+
+func NewCallbacksRepo(repos *Repos) *CallbacksRepoImpl {
+	return &CallbacksRepoImpl{repos: repos}
+}
+
+// Register remembers a delivered callback by its signature and returns
+// exceptions.ErrCallbackReplayed if it has been seen already. Entries older
+// than ttl are dropped: their timestamps are rejected before reaching here.
+func (r *CallbacksRepoImpl) Register(
+	ctx context.Context,
+	signature string,
+	number string,
+	ttl time.Duration,
+) error {
+	tx, err := r.repos.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrapf(err, "failed to begin transaction")
+	}
+	defer rollback(tx)
+
+	qu, _, err := goqu.
+		Delete(callbacksTName).
+		Where(goqu.C("received_at").Lt(nowUTCPlus(-ttl))).
+		ToSQL()
+	if err != nil {
+		return errors.Wrapf(err, "failed to build query")
+	}
+
+	if _, err := tx.ExecContext(ctx, qu); err != nil {
+		return errors.Wrapf(err, "failed to delete expired callbacks")
+	}
+
+	qu, _, err = goqu.
+		Insert(callbacksTName).
+		Rows(
+			goqu.Record{
+				"signature":   signature,
+				"number":      number,
+				"received_at": nowUTC,
+			},
+		).
+		OnConflict(goqu.DoNothing()).
+		Returning("signature").
+		ToSQL()
+	if err != nil {
+		return errors.Wrapf(err, "failed to build query")
+	}
+
+	var inserted string
+	err = tx.QueryRowxContext(ctx, qu).Scan(&inserted)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return exceptions.ErrCallbackReplayed
+		}
+		return errors.Wrapf(err, "failed to insert callback")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrapf(err, "failed to commit")
+	}
+
+	return nil
+}
+
+// Forget drops a registered callback, so a retry of a delivery that failed
+// to apply is not taken for a replay.
+func (r *CallbacksRepoImpl) Forget(ctx context.Context, signature string) error {
+	qu, _, err := goqu.
+		Delete(callbacksTName).
+		Where(goqu.C("signature").Eq(signature)).
+		ToSQL()
+	if err != nil {
+		return errors.Wrapf(err, "failed to build query")
+	}
+
+	if _, err := r.repos.DB.ExecContext(ctx, qu); err != nil {
+		return errors.Wrapf(err, "failed to delete callback")
+	}
+
+	return nil
+}
@@ -0,0 +1,389 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"gophermart/internal/campaigns"
+	"gophermart/internal/exceptions"
+	"gophermart/internal/models"
+	"gophermart/internal/types"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+type CampaignsRepoImpl struct {
+	repos *Repos
+}
+
+func NewCampaignsRepo(repos *Repos) *CampaignsRepoImpl {
+	return &CampaignsRepoImpl{repos: repos}
+}
+
+func (r *CampaignsRepoImpl) Create(
+	ctx context.Context,
+	model *models.Campaign,
+) (*models.Campaign, error) {
+	qu, _, err := goqu.
+		Insert(campaignsTName).
+		Rows(model).
+		Returning(&models.Campaign{}).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	var campaign models.Campaign
+	err = r.repos.DB.QueryRowxContext(ctx, qu).StructScan(&campaign)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to insert campaign")
+	}
+
+	return &campaign, nil
+}
+
+func (r *CampaignsRepoImpl) List(
+	ctx context.Context,
+	limit int,
+	offset int,
+) (*[]models.Campaign, error) {
+	qu, _, err := goqu.
+		Select(&models.Campaign{}).
+		From(campaignsTName).
+		Order(goqu.I("starts_at").Desc(), goqu.I("campaign_id").Asc()).
+		Limit(uint(limit)).
+		Offset(uint(offset)).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	campaigns := []models.Campaign{}
+	if err := r.repos.DB.SelectContext(ctx, &campaigns, qu); err != nil {
+		return nil, errors.Wrapf(err, "failed to select campaigns")
+	}
+
+	return &campaigns, nil
+}
+
+func (r *CampaignsRepoImpl) Get(
+	ctx context.Context,
+	campaignID string,
+) (*models.Campaign, error) {
+	qu, _, err := goqu.
+		Select(&models.Campaign{}).
+		From(campaignsTName).
+		Where(goqu.C("campaign_id").Eq(campaignID)).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	var campaign models.Campaign
+	err = r.repos.DB.QueryRowxContext(ctx, qu).StructScan(&campaign)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, exceptions.ErrCampaignNotFound
+		}
+		return nil, errors.Wrapf(err, "failed to get campaign")
+	}
+
+	return &campaign, nil
+}
+
+// Update replaces the campaign terms. Bonuses already applied stay as they are.
+func (r *CampaignsRepoImpl) Update(
+	ctx context.Context,
+	model *models.Campaign,
+) (*models.Campaign, error) {
+	qu, _, err := goqu.
+		Update(campaignsTName).
+		Set(
+			goqu.Record{
+				"name":       model.Name,
+				"kind":       model.Kind,
+				"multiplier": model.Multiplier,
+				"points":     model.Points,
+				"starts_at":  model.StartsAt,
+				"ends_at":    model.EndsAt,
+				"max_orders": model.MaxOrders,
+				"tier":       model.Tier,
+				"active":     model.Active,
+				"updated_at": nowUTC,
+			},
+		).
+		Where(goqu.C("campaign_id").Eq(model.ID)).
+		Returning(&models.Campaign{}).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	var campaign models.Campaign
+	err = r.repos.DB.QueryRowxContext(ctx, qu).StructScan(&campaign)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, exceptions.ErrCampaignNotFound
+		}
+		return nil, errors.Wrapf(err, "failed to update campaign")
+	}
+
+	return &campaign, nil
+}
+
+// Delete removes a campaign that never applied, others are to be deactivated.
+func (r *CampaignsRepoImpl) Delete(
+	ctx context.Context,
+	campaignID string,
+) error {
+	applied := goqu.
+		From(campaignBonusesTName).
+		Where(goqu.C("campaign_id").Eq(goqu.I(campaignsTName + ".campaign_id")))
+
+	qu, _, err := goqu.
+		Delete(campaignsTName).
+		Where(
+			goqu.C("campaign_id").Eq(campaignID),
+			goqu.Func("NOT EXISTS", applied),
+		).
+		ToSQL()
+	if err != nil {
+		return errors.Wrapf(err, "failed to build query")
+	}
+
+	res, err := r.repos.DB.ExecContext(ctx, qu)
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete campaign")
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrapf(err, "failed to get affected rows")
+	}
+	if deleted > 0 {
+		return nil
+	}
+
+	if _, err := r.Get(ctx, campaignID); err != nil {
+		return err
+	}
+
+	return exceptions.ErrCampaignInUse
+}
+
+func (r *CampaignsRepoImpl) Bonuses(
+	ctx context.Context,
+	campaignID string,
+	limit int,
+	offset int,
+) (*[]models.CampaignBonus, error) {
+	qu, _, err := goqu.
+		Select(&models.CampaignBonus{}).
+		From(campaignBonusesTName).
+		Where(goqu.C("campaign_id").Eq(campaignID)).
+		Order(goqu.I("created_at").Desc(), goqu.I("bonus_id").Asc()).
+		Limit(uint(limit)).
+		Offset(uint(offset)).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	bonuses := []models.CampaignBonus{}
+	if err := r.repos.DB.SelectContext(ctx, &bonuses, qu); err != nil {
+		return nil, errors.Wrapf(err, "failed to select campaign bonuses")
+	}
+
+	return &bonuses, nil
+}
+
+// ReverseBonus takes an applied bonus back from the user. It fails with
+// ErrBalanceIsNegative when the user has already spent it.
+func (r *CampaignsRepoImpl) ReverseBonus(
+	ctx context.Context,
+	bonusID string,
+) (*models.CampaignBonus, error) {
+	tx, err := r.repos.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to begin transaction")
+	}
+	defer rollback(tx)
+
+	qu, _, err := goqu.
+		Select(
+			goqu.I("b.bonus_id"),
+			goqu.I("b.user_id"),
+			goqu.I("b.amount"),
+			goqu.I("b.status"),
+			goqu.I("o.number"),
+		).
+		From(goqu.T(campaignBonusesTName).As("b")).
+		Join(goqu.T(ordersTName).As("o"), goqu.On(goqu.I("o.order_id").Eq(goqu.I("b.order_id")))).
+		Where(goqu.I("b.bonus_id").Eq(bonusID)).
+		ForUpdate(exp.Wait, goqu.T("b")).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	var bonus struct {
+		ID     string      `db:"bonus_id"`
+		UserID string      `db:"user_id"`
+		Amount types.Money `db:"amount"`
+		Status string      `db:"status"`
+		Number string      `db:"number"`
+	}
+	if err := tx.GetContext(ctx, &bonus, qu); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, exceptions.ErrCampaignBonusNotFound
+		}
+		return nil, errors.Wrapf(err, "failed to get campaign bonus")
+	}
+
+	if bonus.Status == types.CampaignBonusReversed.String() {
+		return nil, exceptions.ErrCampaignBonusAlreadyReversed
+	}
+
+	balances, err := ledgerPost(
+		ctx,
+		tx,
+		models.NewCampaignBonusReversalTransaction(bonus.UserID, bonus.Number, bonus.Amount),
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to post campaign bonus reversal")
+	}
+
+	if balances[bonus.UserID].Current.IsNegative() {
+		return nil, exceptions.ErrBalanceIsNegative
+	}
+
+	qu, _, err = goqu.
+		Update(campaignBonusesTName).
+		Set(
+			goqu.Record{
+				"status":      types.CampaignBonusReversed.String(),
+				"reversed_at": nowUTC,
+			},
+		).
+		Where(goqu.C("bonus_id").Eq(bonus.ID)).
+		Returning(&models.CampaignBonus{}).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	var reversed models.CampaignBonus
+	err = tx.QueryRowxContext(ctx, qu).StructScan(&reversed)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to update campaign bonus")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrapf(err, "failed to commit")
+	}
+
+	return &reversed, nil
+}
+
+// applyCampaigns credits the bonuses of campaigns matching an accrual of
+// amount for order, already posted within tx, and records each of them.
+func applyCampaigns(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	order *models.Order,
+	amount types.Money,
+	tier string,
+) error {
+	qu, _, err := goqu.
+		Select(&models.Campaign{}).
+		From(campaignsTName).
+		Where(
+			goqu.C("active").IsTrue(),
+			goqu.C("starts_at").Lte(nowUTC),
+			goqu.Or(
+				goqu.C("ends_at").IsNull(),
+				goqu.C("ends_at").Gt(nowUTC),
+			),
+		).
+		ToSQL()
+	if err != nil {
+		return errors.Wrapf(err, "failed to build query")
+	}
+
+	active := []models.Campaign{}
+	if err := tx.SelectContext(ctx, &active, qu); err != nil {
+		return errors.Wrapf(err, "failed to select active campaigns")
+	}
+	if len(active) == 0 {
+		return nil
+	}
+
+	qu, _, err = goqu.
+		Select(goqu.COUNT("*")).
+		From(ordersTName).
+		Where(
+			goqu.C("user_id").Eq(order.UserID),
+			// Revoked orders were processed once, so a revocation doesn't
+			// make the next order the first again.
+			goqu.C("status").In(
+				types.OrderProcessed.String(),
+				types.OrderRevoked.String(),
+			),
+		).
+		ToSQL()
+	if err != nil {
+		return errors.Wrapf(err, "failed to build query")
+	}
+
+	var ordersCount int
+	if err := tx.GetContext(ctx, &ordersCount, qu); err != nil {
+		return errors.Wrapf(err, "failed to count processed orders")
+	}
+
+	bonuses, err := campaigns.Evaluate(active, campaigns.Facts{
+		Now:         time.Now().UTC(),
+		Amount:      amount,
+		OrdersCount: ordersCount,
+		Tier:        tier,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to evaluate campaigns")
+	}
+
+	for _, bonus := range bonuses {
+		_, err := ledgerPost(
+			ctx,
+			tx,
+			models.NewCampaignBonusTransaction(order.UserID, order.Number, bonus.Amount),
+		)
+		if err != nil {
+			return errors.Wrapf(err, "failed to post campaign bonus")
+		}
+
+		qu, _, err := goqu.
+			Insert(campaignBonusesTName).
+			Rows(
+				goqu.Record{
+					"campaign_id": bonus.CampaignID,
+					"user_id":     order.UserID,
+					"order_id":    order.ID,
+					"amount":      bonus.Amount,
+					"status":      types.CampaignBonusApplied.String(),
+					"created_at":  nowUTC,
+				},
+			).
+			ToSQL()
+		if err != nil {
+			return errors.Wrapf(err, "failed to build query")
+		}
+
+		if _, err := tx.ExecContext(ctx, qu); err != nil {
+			return errors.Wrapf(err, "failed to insert campaign bonus")
+		}
+	}
+
+	return nil
+}
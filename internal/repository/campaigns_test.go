@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"gophermart/internal/exceptions"
+	"gophermart/internal/models"
+	"gophermart/internal/types"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+func TestCampaignsRepoImpl_BonusAndReversal(t *testing.T) {
+	repos := setupRepos(t)
+	ctx := context.Background()
+
+	points := types.MoneyFromInt(50)
+	endsAt := time.Now().Add(time.Hour)
+	campaign, err := repos.CampaignsRepo.Create(ctx, models.NewCampaign(&models.CampaignCreate{
+		Name:     "test-" + uuid.NewString(),
+		Kind:     types.CampaignFixed.String(),
+		Points:   &points,
+		StartsAt: time.Now().Add(-time.Minute),
+		EndsAt:   &endsAt,
+	}))
+	if err != nil {
+		t.Fatalf("failed to create campaign: %s", err)
+	}
+	// Campaigns apply to every user, keep it from leaking into other tests.
+	t.Cleanup(func() {
+		campaign.Active = false
+		if _, err := repos.CampaignsRepo.Update(ctx, campaign); err != nil {
+			t.Errorf("failed to deactivate campaign: %s", err)
+		}
+	})
+
+	user := createTestUser(t, repos, "campaigns-")
+	accrueTestOrder(t, repos, user.ID, types.MoneyFromInt(100))
+
+	balance, err := repos.BalanceRepo.GetOrCreateForUser(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("failed to get balance: %s", err)
+	}
+	if !balance.Current.Equal(types.MoneyFromInt(150)) {
+		t.Errorf("current is different: got=%s want=150", balance.Current)
+	}
+
+	bonuses, err := repos.CampaignsRepo.Bonuses(ctx, campaign.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("failed to get bonuses: %s", err)
+	}
+	if len(*bonuses) != 1 {
+		t.Fatalf("bonuses count is different: got=%d want=1", len(*bonuses))
+	}
+
+	if err := repos.CampaignsRepo.Delete(ctx, campaign.ID); !errors.Is(err, exceptions.ErrCampaignInUse) {
+		t.Errorf("delete error is different: got=%v want=%v", err, exceptions.ErrCampaignInUse)
+	}
+
+	bonus, err := repos.CampaignsRepo.ReverseBonus(ctx, (*bonuses)[0].ID)
+	if err != nil {
+		t.Fatalf("failed to reverse bonus: %s", err)
+	}
+	if bonus.Status != types.CampaignBonusReversed.String() {
+		t.Errorf("status is different: got=%s want=%s", bonus.Status, types.CampaignBonusReversed)
+	}
+
+	_, err = repos.CampaignsRepo.ReverseBonus(ctx, bonus.ID)
+	if !errors.Is(err, exceptions.ErrCampaignBonusAlreadyReversed) {
+		t.Errorf("reverse error is different: got=%v want=%v", err, exceptions.ErrCampaignBonusAlreadyReversed)
+	}
+
+	balance, err = repos.BalanceRepo.GetOrCreateForUser(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("failed to get balance: %s", err)
+	}
+	if !balance.Current.Equal(types.MoneyFromInt(100)) {
+		t.Errorf("current is different: got=%s want=100", balance.Current)
+	}
+}
+
+func TestCampaignsRepoImpl_FirstOrderAfterRevoke(t *testing.T) {
+	repos := setupRepos(t)
+	ctx := context.Background()
+
+	points := types.MoneyFromInt(50)
+	maxOrders := 1
+	campaign, err := repos.CampaignsRepo.Create(ctx, models.NewCampaign(&models.CampaignCreate{
+		Name:      "test-" + uuid.NewString(),
+		Kind:      types.CampaignFixed.String(),
+		Points:    &points,
+		StartsAt:  time.Now().Add(-time.Minute),
+		MaxOrders: &maxOrders,
+	}))
+	if err != nil {
+		t.Fatalf("failed to create campaign: %s", err)
+	}
+	t.Cleanup(func() {
+		campaign.Active = false
+		if _, err := repos.CampaignsRepo.Update(ctx, campaign); err != nil {
+			t.Errorf("failed to deactivate campaign: %s", err)
+		}
+	})
+
+	user := createTestUser(t, repos, "campaigns-")
+
+	order := accrueTestOrder(t, repos, user.ID, types.MoneyFromInt(100))
+	_, err = repos.OrdersRepo.Revoke(ctx, models.RevokeRecord{
+		Number: order.Number,
+		Change: models.StatusChange{Source: types.SourceAdmin},
+	})
+	if err != nil {
+		t.Fatalf("failed to revoke: %s", err)
+	}
+
+	// The second order is not the first one, whatever became of the first.
+	accrueTestOrder(t, repos, user.ID, types.MoneyFromInt(100))
+
+	bonuses, err := repos.CampaignsRepo.Bonuses(ctx, campaign.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("failed to get bonuses: %s", err)
+	}
+	if len(*bonuses) != 1 {
+		t.Errorf("bonuses count is different: got=%d want=1", len(*bonuses))
+	}
+}
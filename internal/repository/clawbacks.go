@@ -0,0 +1,334 @@
+package repository
+
+import (
+	"context"
+	"gophermart/internal/exceptions"
+	"gophermart/internal/models"
+	"gophermart/internal/types"
+	"sort"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// applyClawback takes back everything credited for the revoked order: the
+// accrual with its tier and campaign bonuses, and the referral bonus of
+// both parties when the order settled a referral. Campaign bonuses and the
+// referral are marked REVERSED. Each user gets a clawback of their own,
+// recovered as policy allows. It returns the clawback of the order owner.
+func applyClawback(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	policy types.ClawbackPolicy,
+	order *models.Order,
+	source types.StatusSource,
+) (*models.Clawback, error) {
+	owed, err := orderCredits(ctx, tx, order)
+	if err != nil {
+		return nil, err
+	}
+
+	// Balances are locked in the same order as ledgerPost does.
+	userIDs := make([]string, 0, len(owed))
+	for userID := range owed {
+		userIDs = append(userIDs, userID)
+	}
+	sort.Strings(userIDs)
+
+	var result *models.Clawback
+	for _, userID := range userIDs {
+		clawback, err := clawBack(ctx, tx, policy, order, userID, owed[userID], source)
+		if err != nil {
+			return nil, err
+		}
+		if userID == order.UserID {
+			result = clawback
+		}
+	}
+
+	return result, nil
+}
+
+// orderCredits sums what each user was credited for the order and reverses
+// the campaign bonuses and the referral it paid.
+func orderCredits(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	order *models.Order,
+) (map[string]types.Money, error) {
+	owed := map[string]types.Money{order.UserID: order.Accrual}
+
+	qu, _, err := goqu.
+		Select(goqu.L("COALESCE(SUM(amount), 0)")).
+		From(ledgerTName).
+		Where(
+			goqu.C("user_id").Eq(order.UserID),
+			goqu.C("account").Eq(types.AccountUserCurrent.String()),
+			goqu.C("kind").Eq(types.LedgerTierBonus.String()),
+			goqu.C("reference").Eq(order.Number),
+		).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	var tierBonus types.Money
+	if err := tx.GetContext(ctx, &tierBonus, qu); err != nil {
+		return nil, errors.Wrapf(err, "failed to sum tier bonuses")
+	}
+	owed[order.UserID] = owed[order.UserID].Add(tierBonus)
+
+	qu, _, err = goqu.
+		Update(campaignBonusesTName).
+		Set(
+			goqu.Record{
+				"status":      types.CampaignBonusReversed.String(),
+				"reversed_at": nowUTC,
+			},
+		).
+		Where(
+			goqu.C("order_id").Eq(order.ID),
+			goqu.C("status").Eq(types.CampaignBonusApplied.String()),
+		).
+		Returning("amount").
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	campaignBonuses := []types.Money{}
+	if err := tx.SelectContext(ctx, &campaignBonuses, qu); err != nil {
+		return nil, errors.Wrapf(err, "failed to reverse campaign bonuses")
+	}
+	for _, bonus := range campaignBonuses {
+		owed[order.UserID] = owed[order.UserID].Add(bonus)
+	}
+
+	qu, _, err = goqu.
+		Update(referralsTName).
+		Set(
+			goqu.Record{
+				"status":      types.ReferralReversed.String(),
+				"reversed_at": nowUTC,
+			},
+		).
+		Where(
+			goqu.C("order_id").Eq(order.ID),
+			goqu.C("status").Eq(types.ReferralRewarded.String()),
+		).
+		Returning("referrer_id", "referee_id", "bonus").
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	referrals := []models.Referral{}
+	if err := tx.SelectContext(ctx, &referrals, qu); err != nil {
+		return nil, errors.Wrapf(err, "failed to reverse referrals")
+	}
+	for _, referral := range referrals {
+		if referral.Bonus == nil {
+			continue
+		}
+		owed[referral.ReferrerID] = owed[referral.ReferrerID].Add(*referral.Bonus)
+		owed[referral.RefereeID] = owed[referral.RefereeID].Add(*referral.Bonus)
+	}
+
+	return owed, nil
+}
+
+// clawBack takes amount credited for order back from the user as policy
+// allows and records the clawback.
+func clawBack(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	policy types.ClawbackPolicy,
+	order *models.Order,
+	userID string,
+	amount types.Money,
+	source types.StatusSource,
+) (*models.Clawback, error) {
+	current, err := lockCurrent(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	recovered := amount
+	if policy != types.ClawbackAllowDebt {
+		recovered = recoverable(amount, current)
+	}
+
+	if recovered.IsPositive() {
+		_, err = ledgerPost(ctx, tx, models.NewClawbackTransaction(userID, order.Number, recovered))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to post clawback")
+		}
+	}
+
+	qu, _, err := goqu.
+		Insert(clawbacksTName).
+		Rows(goqu.Record{
+			"order_id":   order.ID,
+			"user_id":    userID,
+			"number":     order.Number,
+			"amount":     amount,
+			"recovered":  recovered,
+			"policy":     policy.String(),
+			"source":     source.String(),
+			"created_at": nowUTC,
+			"updated_at": nowUTC,
+		}).
+		Returning(&models.Clawback{}).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	var result models.Clawback
+	if err := tx.QueryRowxContext(ctx, qu).StructScan(&result); err != nil {
+		return nil, errors.Wrapf(err, "failed to insert clawback")
+	}
+
+	return &result, nil
+}
+
+// recoverClawbacks takes the outstanding part of BLOCK_WITHDRAWALS
+// clawbacks of the user out of their balance, oldest first, as far as
+// the balance goes.
+func recoverClawbacks(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	userID string,
+) error {
+	qu, _, err := goqu.
+		Select(&models.Clawback{}).
+		From(clawbacksTName).
+		Where(outstandingClawbacks(userID)).
+		Order(goqu.I("created_at").Asc(), goqu.I("clawback_id").Asc()).
+		ForUpdate(exp.Wait).
+		ToSQL()
+	if err != nil {
+		return errors.Wrapf(err, "failed to build query")
+	}
+
+	clawbacks := []models.Clawback{}
+	if err := tx.SelectContext(ctx, &clawbacks, qu); err != nil {
+		return errors.Wrapf(err, "failed to lock clawbacks")
+	}
+	if len(clawbacks) == 0 {
+		return nil
+	}
+
+	current, err := lockCurrent(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range clawbacks {
+		recovered := recoverable(c.Outstanding(), current)
+		if !recovered.IsPositive() {
+			return nil
+		}
+
+		balances, err := ledgerPost(ctx, tx, models.NewClawbackTransaction(userID, c.Number, recovered))
+		if err != nil {
+			return errors.Wrapf(err, "failed to post clawback recovery")
+		}
+		current = balances[userID].Current
+
+		qu, _, err := goqu.
+			Update(clawbacksTName).
+			Set(goqu.Record{
+				"recovered":  c.Recovered.Add(recovered),
+				"updated_at": nowUTC,
+			}).
+			Where(goqu.C("clawback_id").Eq(c.ID)).
+			ToSQL()
+		if err != nil {
+			return errors.Wrapf(err, "failed to build query")
+		}
+
+		if _, err := tx.ExecContext(ctx, qu); err != nil {
+			return errors.Wrapf(err, "failed to update clawback")
+		}
+	}
+
+	return nil
+}
+
+// checkWithdrawalsAllowed fails with ErrWithdrawalsBlocked while the user
+// has BLOCK_WITHDRAWALS clawbacks left to recover.
+func checkWithdrawalsAllowed(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	userID string,
+) error {
+	qu, _, err := goqu.
+		Select(goqu.L("EXISTS ?", goqu.
+			Select(goqu.L("1")).
+			From(clawbacksTName).
+			Where(outstandingClawbacks(userID)),
+		)).
+		ToSQL()
+	if err != nil {
+		return errors.Wrapf(err, "failed to build query")
+	}
+
+	var blocked bool
+	if err := tx.GetContext(ctx, &blocked, qu); err != nil {
+		return errors.Wrapf(err, "failed to check clawbacks")
+	}
+
+	if blocked {
+		return exceptions.ErrWithdrawalsBlocked
+	}
+
+	return nil
+}
+
+func outstandingClawbacks(userID string) exp.Expression {
+	return goqu.And(
+		goqu.C("user_id").Eq(userID),
+		goqu.C("policy").Eq(types.ClawbackBlockWithdrawals.String()),
+		goqu.C("recovered").Lt(goqu.C("amount")),
+	)
+}
+
+// lockCurrent locks the balance of the user and returns its current points.
+func lockCurrent(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	userID string,
+) (types.Money, error) {
+	qu, _, err := goqu.
+		Select("current").
+		From(balanceTName).
+		Where(goqu.C("user_id").Eq(userID)).
+		ForUpdate(exp.Wait).
+		ToSQL()
+	if err != nil {
+		return types.Money{}, errors.Wrapf(err, "failed to build query")
+	}
+
+	var current types.Money
+	if err := tx.GetContext(ctx, &current, qu); err != nil {
+		return types.Money{}, errors.Wrapf(err, "failed to lock balance")
+	}
+
+	return current, nil
+}
+
+// recoverable is the part of sum a current balance can pay without
+// going below zero.
+func recoverable(sum types.Money, current types.Money) types.Money {
+	switch {
+	case !current.IsPositive():
+		return types.Money{}
+	case sum.GreaterThan(current):
+		return current
+	default:
+		return sum
+	}
+}
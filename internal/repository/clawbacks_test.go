@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"gophermart/internal/exceptions"
+	"gophermart/internal/loyalty"
+	"gophermart/internal/models"
+	"gophermart/internal/types"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+func TestOrdersRepoImpl_Revoke(t *testing.T) {
+	repos := setupRepos(t)
+	ctx := context.Background()
+
+	user := createTestUser(t, repos, "clawbacks-")
+
+	accrue := func(amount int64) *models.Order {
+		return accrueTestOrder(t, repos, user.ID, types.MoneyFromInt(amount))
+	}
+	withdraw := func(sum int64) error {
+		number := strconv.FormatInt(time.Now().UnixNano(), 10)
+		_, err := repos.WithdrawalsRepo.Create(ctx, models.NewWithdrawal(user.ID, number, types.MoneyFromInt(sum)))
+		return err
+	}
+	revoke := func(order *models.Order) (*models.Clawback, error) {
+		return repos.OrdersRepo.Revoke(ctx, models.RevokeRecord{
+			Number: order.Number,
+			Change: models.StatusChange{Source: types.SourceAdmin},
+		})
+	}
+	assertCurrent := func(want int64) {
+		t.Helper()
+		balance, err := repos.BalanceRepo.GetOrCreateForUser(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("failed to get balance: %s", err)
+		}
+		if !balance.Current.Equal(types.MoneyFromInt(want)) {
+			t.Errorf("current is different: got=%s want=%d", balance.Current, want)
+		}
+	}
+
+	// ALLOW_DEBT takes it all.
+	repos.ClawbackPolicy = types.ClawbackAllowDebt
+	order := accrue(100)
+	if err := withdraw(80); err != nil {
+		t.Fatalf("failed to withdraw: %s", err)
+	}
+	if _, err := revoke(order); err != nil {
+		t.Fatalf("failed to revoke: %s", err)
+	}
+	assertCurrent(-80)
+
+	if clawback, err := revoke(order); err != nil || clawback != nil {
+		t.Errorf("repeated revoke is different: got=%v, %v want=nil, nil", clawback, err)
+	}
+
+	// BLOCK_WITHDRAWALS takes what is left and the rest from later accruals.
+	repos.ClawbackPolicy = types.ClawbackBlockWithdrawals
+	accrue(100)
+	order = accrue(100)
+	assertCurrent(120)
+	if err := withdraw(100); err != nil {
+		t.Fatalf("failed to withdraw: %s", err)
+	}
+	clawback, err := revoke(order)
+	if err != nil {
+		t.Fatalf("failed to revoke: %s", err)
+	}
+	if !clawback.Recovered.Equal(types.MoneyFromInt(20)) {
+		t.Errorf("recovered is different: got=%s want=20", clawback.Recovered)
+	}
+	assertCurrent(0)
+
+	admin := createTestUser(t, repos, "clawbacks-admin-")
+	_, err = repos.AdjustmentsRepo.Create(ctx, models.NewAdjustment(admin.ID, user.ID, &models.AdjustmentCreate{
+		Amount:  types.MoneyFromInt(50),
+		Reason:  types.AdjustmentGoodwill.String(),
+		Comment: "test",
+	}))
+	if err != nil {
+		t.Fatalf("failed to adjust: %s", err)
+	}
+	if err := withdraw(10); !errors.Is(err, exceptions.ErrWithdrawalsBlocked) {
+		t.Errorf("error is different: got=%v want=%v", err, exceptions.ErrWithdrawalsBlocked)
+	}
+
+	accrue(100)
+	assertCurrent(70)
+	if err := withdraw(10); err != nil {
+		t.Errorf("failed to withdraw after recovery: %s", err)
+	}
+
+	// Bonuses paid for the order go back with it, the referrer's too.
+	repos.ClawbackPolicy = types.ClawbackAllowDebt
+	tiers, err := loyalty.ParseTiers("BRONZE:0:1.5")
+	if err != nil {
+		t.Fatalf("failed to parse tiers: %s", err)
+	}
+	repos.Loyalty = loyalty.NewProgram(tiers, time.Hour)
+	repos.Referrals = models.ReferralTerms{Bonus: types.MoneyFromInt(10)}
+
+	points := types.MoneyFromInt(5)
+	campaign, err := repos.CampaignsRepo.Create(ctx, models.NewCampaign(&models.CampaignCreate{
+		Name:     "test-" + uuid.NewString(),
+		Kind:     types.CampaignFixed.String(),
+		Points:   &points,
+		StartsAt: time.Now().Add(-time.Minute),
+	}))
+	if err != nil {
+		t.Fatalf("failed to create campaign: %s", err)
+	}
+	t.Cleanup(func() {
+		campaign.Active = false
+		if _, err := repos.CampaignsRepo.Update(ctx, campaign); err != nil {
+			t.Errorf("failed to deactivate campaign: %s", err)
+		}
+	})
+
+	referrer := user
+	referrerBalance, err := repos.BalanceRepo.GetOrCreateForUser(ctx, referrer.ID)
+	if err != nil {
+		t.Fatalf("failed to get balance: %s", err)
+	}
+
+	authUser := newTestUser(t, "clawbacks-referee-")
+	authUser.ReferredWith = referrer.ReferralCode
+	if user, err = repos.UsersRepo.Create(ctx, authUser); err != nil {
+		t.Fatalf("failed to create referee: %s", err)
+	}
+
+	order = accrue(100)
+	assertCurrent(165)
+
+	clawback, err = revoke(order)
+	if err != nil {
+		t.Fatalf("failed to revoke: %s", err)
+	}
+	if !clawback.Amount.Equal(types.MoneyFromInt(165)) {
+		t.Errorf("clawback amount is different: got=%s want=165", clawback.Amount)
+	}
+	assertCurrent(0)
+
+	balance, err := repos.BalanceRepo.GetOrCreateForUser(ctx, referrer.ID)
+	if err != nil {
+		t.Fatalf("failed to get balance: %s", err)
+	}
+	if !balance.Current.Equal(referrerBalance.Current) {
+		t.Errorf("referrer current is different: got=%s want=%s", balance.Current, referrerBalance.Current)
+	}
+
+	read, err := repos.ReferralsRepo.UserReferrals(ctx, referrer.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("failed to get referrals: %s", err)
+	}
+	if len(read.Referrals) != 1 || read.Referrals[0].Status != types.ReferralReversed.String() {
+		t.Errorf("referrals are different: got=%+v want one %s", read.Referrals, types.ReferralReversed)
+	}
+
+	bonuses, err := repos.CampaignsRepo.Bonuses(ctx, campaign.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("failed to get bonuses: %s", err)
+	}
+	if len(*bonuses) != 1 || (*bonuses)[0].Status != types.CampaignBonusReversed.String() {
+		t.Errorf("bonuses are different: got=%+v want one %s", *bonuses, types.CampaignBonusReversed)
+	}
+}
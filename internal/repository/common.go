@@ -1,8 +1,53 @@
 package repository
 
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"gophermart/internal/log"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
 const (
-	balanceTName     = "bll_balance"
-	ordersTName      = "bll_orders"
-	usersTName       = "usr_users"
-	withdrawalsTName = "wdr_withdrawals"
+	adjustmentsTName     = "bll_adjustments"
+	balanceTName         = "bll_balance"
+	callbacksTName       = "bll_accrual_callbacks"
+	campaignBonusesTName = "bll_campaign_bonuses"
+	campaignsTName       = "bll_campaigns"
+	clawbacksTName       = "bll_clawbacks"
+	deadLettersTName     = "bll_dead_letters"
+	holdsTName           = "wdr_holds"
+	idempotencyTName     = "usr_idempotency_keys"
+	ledgerTName          = "bll_ledger"
+	lotSpendingsTName    = "bll_lot_spendings"
+	orderHistoryTName    = "bll_order_status_history"
+	ordersTName          = "bll_orders"
+	pointLotsTName       = "bll_point_lots"
+	referralsTName       = "usr_referrals"
+	reversalsTName       = "wdr_reversals"
+	transfersTName       = "bll_transfers"
+	usersTName           = "usr_users"
+	withdrawalsTName     = "wdr_withdrawals"
 )
+
+// Timestamps are stored without time zone in UTC.
+var nowUTC = goqu.L("(now() AT TIME ZONE 'UTC')")
+
+func nowUTCPlus(d time.Duration) interface{} {
+	return goqu.L(
+		"(now() AT TIME ZONE 'UTC') + ?::interval",
+		fmt.Sprintf("%d milliseconds", d.Milliseconds()),
+	)
+}
+
+// rollback is deferred right after a transaction begins,
+// it is a no-op once the transaction has been committed.
+func rollback(tx *sqlx.Tx) {
+	if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		log.Error(context.Background(), "failed to rollback", err)
+	}
+}
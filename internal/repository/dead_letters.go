@@ -0,0 +1,292 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"gophermart/internal/exceptions"
+	"gophermart/internal/log"
+	"gophermart/internal/models"
+	"gophermart/internal/types"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+type DeadLettersRepoImpl struct {
+	repos *Repos
+}
+
+func NewDeadLettersRepo(repos *Repos) *DeadLettersRepoImpl {
+	return &DeadLettersRepoImpl{repos: repos}
+}
+
+// Park stops polling of the order and records it in the dead-letter queue
+// in one transaction. Parking an already parked order refreshes its entry.
+// An order which got a final status meanwhile is left alone and Park
+// reports nil.
+func (r *DeadLettersRepoImpl) Park(
+	ctx context.Context,
+	model *models.DeadLetter,
+) (*models.DeadLetter, error) {
+	tx, err := r.repos.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to begin transaction")
+	}
+	defer rollback(tx)
+
+	qu, _, err := goqu.
+		Update(ordersTName).
+		Set(
+			map[string]interface{}{
+				"check_attempts":   model.Attempts,
+				"next_check_at":    nil,
+				"lease_owner":      nil,
+				"lease_expires_at": nil,
+			},
+		).
+		Where(
+			goqu.C("order_id").Eq(model.OrderID),
+			goqu.C("status").In(
+				types.OrderNew.String(),
+				types.OrderProcessing.String(),
+			),
+		).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	res, err := tx.ExecContext(ctx, qu)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to park order")
+	}
+
+	parked, err := res.RowsAffected()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get affected rows")
+	}
+	if parked == 0 {
+		return nil, nil
+	}
+
+	qu, _, err = goqu.
+		Insert(deadLettersTName).
+		Rows(model).
+		OnConflict(
+			goqu.DoUpdate(
+				"order_id",
+				goqu.Record{
+					"last_error":     goqu.I("excluded.last_error"),
+					"attempts":       goqu.I("excluded.attempts"),
+					"last_failed_at": goqu.I("excluded.last_failed_at"),
+				},
+			),
+		).
+		Returning(&models.DeadLetter{}).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	var deadLetter models.DeadLetter
+	err = tx.QueryRowxContext(ctx, qu).StructScan(&deadLetter)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to insert dead letter")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrapf(err, "failed to commit")
+	}
+
+	return &deadLetter, nil
+}
+
+func (r *DeadLettersRepoImpl) List(
+	ctx context.Context,
+	limit int,
+	offset int,
+) (*[]models.DeadLetter, error) {
+	qu, _, err := goqu.
+		Select(&models.DeadLetter{}).
+		From(deadLettersTName).
+		Order(goqu.I("last_failed_at").Desc()).
+		Limit(uint(limit)).
+		Offset(uint(offset)).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	rows, err := r.repos.DB.QueryxContext(ctx, qu)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read dead letters error during querying")
+	}
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			log.Error(ctx, "failed to close rows", err)
+		}
+	}()
+
+	deadLetters := []models.DeadLetter{}
+	for rows.Next() {
+		deadLetter := models.DeadLetter{}
+		err := rows.StructScan(&deadLetter)
+		if err != nil {
+			return nil, errors.Wrapf(err, "read dead letters error during scan rows")
+		}
+		deadLetters = append(deadLetters, deadLetter)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read dead letters error during querying: %w", err)
+	}
+
+	return &deadLetters, nil
+}
+
+// Replay removes the entry and schedules its order for an immediate check
+// with a fresh attempt counter. The max age of the order counts again from
+// the replay.
+func (r *DeadLettersRepoImpl) Replay(
+	ctx context.Context,
+	deadLetterID string,
+) (*models.Order, error) {
+	tx, err := r.repos.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to begin transaction")
+	}
+	defer rollback(tx)
+
+	deadLetter, err := r.delete(ctx, tx, deadLetterID)
+	if err != nil {
+		return nil, err
+	}
+
+	qu, _, err := goqu.
+		Update(ordersTName).
+		Set(
+			map[string]interface{}{
+				"check_attempts":   0,
+				"next_check_at":    nowUTC,
+				"replayed_at":      nowUTC,
+				"lease_owner":      nil,
+				"lease_expires_at": nil,
+			},
+		).
+		Where(
+			goqu.C("order_id").Eq(deadLetter.OrderID),
+			goqu.C("status").In(
+				types.OrderNew.String(),
+				types.OrderProcessing.String(),
+			),
+		).
+		Returning(&models.Order{}).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	var order models.Order
+	err = tx.QueryRowxContext(ctx, qu).StructScan(&order)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, exceptions.ErrOrderIsFinal
+		}
+		return nil, errors.Wrapf(err, "failed to reschedule order")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrapf(err, "failed to commit")
+	}
+
+	return &order, nil
+}
+
+// Discard removes the entry and gives up on its order by marking it INVALID.
+func (r *DeadLettersRepoImpl) Discard(
+	ctx context.Context,
+	deadLetterID string,
+) (*models.DeadLetter, error) {
+	tx, err := r.repos.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to begin transaction")
+	}
+	defer rollback(tx)
+
+	deadLetter, err := r.delete(ctx, tx, deadLetterID)
+	if err != nil {
+		return nil, err
+	}
+
+	where := goqu.And(
+		goqu.C("order_id").Eq(deadLetter.OrderID),
+		goqu.C("status").In(
+			types.OrderNew.String(),
+			types.OrderProcessing.String(),
+		),
+	)
+
+	from, err := lockStatuses(ctx, tx, where)
+	if err != nil {
+		return nil, err
+	}
+
+	qu, _, err := goqu.
+		Update(ordersTName).
+		Set(map[string]interface{}{"status": types.OrderInvalid.String()}).
+		Where(where).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	if _, err := tx.ExecContext(ctx, qu); err != nil {
+		return nil, errors.Wrapf(err, "failed to invalidate order")
+	}
+
+	err = recordStatusChanges(
+		ctx,
+		tx,
+		from,
+		types.OrderInvalid,
+		models.StatusChange{Source: types.SourceAdmin},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrapf(err, "failed to commit")
+	}
+
+	return deadLetter, nil
+}
+
+func (r *DeadLettersRepoImpl) delete(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	deadLetterID string,
+) (*models.DeadLetter, error) {
+	qu, _, err := goqu.
+		Delete(deadLettersTName).
+		Where(goqu.C("dead_letter_id").Eq(deadLetterID)).
+		Returning(&models.DeadLetter{}).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	var deadLetter models.DeadLetter
+	err = tx.QueryRowxContext(ctx, qu).StructScan(&deadLetter)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, exceptions.ErrDeadLetterNotFound
+		}
+		return nil, errors.Wrapf(err, "failed to delete dead letter")
+	}
+
+	return &deadLetter, nil
+}
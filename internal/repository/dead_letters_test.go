@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"gophermart/internal/models"
+	"gophermart/internal/types"
+	"testing"
+)
+
+func TestDeadLettersRepoImpl_Park(t *testing.T) {
+	repos := setupRepos(t)
+	ctx := context.Background()
+
+	user := createTestUser(t, repos, "dead-letters-")
+
+	pending := createTestOrder(t, repos, user.ID)
+	parked, err := repos.DeadLettersRepo.Park(ctx, models.NewDeadLetter(pending, 3, "timeout"))
+	if err != nil {
+		t.Fatalf("failed to park: %s", err)
+	}
+	if parked == nil || parked.OrderID != pending.ID {
+		t.Errorf("dead letter is different: got=%+v want order=%s", parked, pending.ID)
+	}
+
+	// The order was settled, e.g. by a callback, before the poll gave up.
+	settled := accrueTestOrder(t, repos, user.ID, types.MoneyFromInt(10))
+	parked, err = repos.DeadLettersRepo.Park(ctx, models.NewDeadLetter(settled, 3, "timeout"))
+	if err != nil {
+		t.Fatalf("failed to park: %s", err)
+	}
+	if parked != nil {
+		t.Errorf("settled order is parked: %+v", parked)
+	}
+
+	deadLetters, err := repos.DeadLettersRepo.List(ctx, models.MaxPageLimit, 0)
+	if err != nil {
+		t.Fatalf("failed to list dead letters: %s", err)
+	}
+	for _, deadLetter := range *deadLetters {
+		if deadLetter.OrderID == settled.ID {
+			t.Errorf("settled order is in the dead-letter queue")
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"gophermart/internal/exceptions"
+	"gophermart/internal/models"
+	"gophermart/internal/types"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+type HoldsRepoImpl struct {
+	repos *Repos
+}
+
+func NewHoldsRepo(repos *Repos) *HoldsRepoImpl {
+	return &HoldsRepoImpl{repos: repos}
+}
+
+// Create moves the hold sum and the withdrawal fee from current to held
+// points. It fails with ErrBalanceIsNegative when the available balance is
+// not enough, and with a *withdrawals.Violation when the withdrawal would
+// exceed the caps of the withdrawal policy.
+func (r *HoldsRepoImpl) Create(
+	ctx context.Context,
+	model *models.Hold,
+) (*models.Hold, error) {
+	tx, err := r.repos.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to begin transaction")
+	}
+	defer rollback(tx)
+
+	policy := &r.repos.WithdrawalPolicy
+	model.Fee = policy.Fee(model.Sum)
+
+	balances, err := ledgerPost(
+		ctx,
+		tx,
+		models.NewHoldTransaction(model.UserID, model.Order, models.HoldLots(model.ID), model.Held()),
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to post hold")
+	}
+
+	if balances[model.UserID].Current.IsNegative() {
+		return nil, exceptions.ErrBalanceIsNegative
+	}
+
+	if err := checkWithdrawalsAllowed(ctx, tx, model.UserID); err != nil {
+		return nil, err
+	}
+
+	if err := checkWithdrawalUsage(ctx, tx, policy, model.UserID, model.Sum); err != nil {
+		return nil, err
+	}
+
+	qu, _, err := goqu.
+		Insert(holdsTName).
+		Rows(model).
+		Returning(&models.Hold{}).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	var hold models.Hold
+	err = tx.QueryRowxContext(ctx, qu).StructScan(&hold)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to insert hold")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrapf(err, "failed to commit")
+	}
+
+	return &hold, nil
+}
+
+// Capture records the hold as a withdrawal the way WithdrawalsRepo.Create
+// would: the caps are checked again and the held sum and fee are spent, or
+// stay held in a PENDING_REVIEW withdrawal if the policy wants it reviewed.
+func (r *HoldsRepoImpl) Capture(
+	ctx context.Context,
+	holdID string,
+	userID string,
+) (*models.Hold, error) {
+	tx, err := r.repos.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to begin transaction")
+	}
+	defer rollback(tx)
+
+	hold, err := lockActiveHold(ctx, tx, holdID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339Nano, hold.ExpiresAt)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse expires_at")
+	}
+	if !time.Now().Before(expiresAt) {
+		return nil, exceptions.ErrHoldExpired
+	}
+
+	// The caps are checked under the balance lock, as for withdrawals.
+	if _, err := lockCurrent(ctx, tx, hold.UserID); err != nil {
+		return nil, err
+	}
+
+	if err := checkWithdrawalsAllowed(ctx, tx, hold.UserID); err != nil {
+		return nil, err
+	}
+
+	policy := &r.repos.WithdrawalPolicy
+	if err := checkWithdrawalUsage(ctx, tx, policy, hold.UserID, hold.Sum); err != nil {
+		return nil, err
+	}
+
+	model := models.NewWithdrawal(hold.UserID, hold.Order, hold.Sum)
+	model.Fee = hold.Fee
+	if policy.NeedsReview(hold.Sum) {
+		model.Status = types.WithdrawalPendingReview.String()
+	} else {
+		txns := []*models.LedgerTransaction{
+			models.NewCaptureTransaction(hold.UserID, hold.Order, hold.Sum),
+		}
+		if hold.Fee.IsPositive() {
+			txns = append(txns, models.NewCaptureFeeTransaction(hold.UserID, hold.Order, hold.Fee))
+		}
+		for _, txn := range txns {
+			if _, err := ledgerPost(ctx, tx, txn); err != nil {
+				return nil, errors.Wrapf(err, "failed to post %s", txn.Kind)
+			}
+		}
+	}
+
+	withdrawal, err := insertWithdrawal(ctx, tx, model)
+	if err != nil {
+		return nil, err
+	}
+
+	// A reversal or rejection of the withdrawal gives back what the hold spent.
+	err = moveLotSpendings(ctx, tx, models.HoldLots(hold.ID), models.WithdrawalLots(withdrawal.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	hold, err = finishHold(ctx, tx, hold.ID, types.HoldCaptured, withdrawal.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrapf(err, "failed to commit")
+	}
+
+	return hold, nil
+}
+
+// Release returns the held points to the user.
+func (r *HoldsRepoImpl) Release(
+	ctx context.Context,
+	holdID string,
+	userID string,
+) (*models.Hold, error) {
+	tx, err := r.repos.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to begin transaction")
+	}
+	defer rollback(tx)
+
+	hold, err := lockActiveHold(ctx, tx, holdID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	hold, err = releaseHold(ctx, tx, hold, types.HoldReleased)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrapf(err, "failed to commit")
+	}
+
+	return hold, nil
+}
+
+// ReleaseExpired releases up to limit active holds past their expiry,
+// skipping the ones being captured or released right now.
+func (r *HoldsRepoImpl) ReleaseExpired(ctx context.Context, limit int) (int64, error) {
+	tx, err := r.repos.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to begin transaction")
+	}
+	defer rollback(tx)
+
+	qu, _, err := goqu.
+		Select(&models.Hold{}).
+		From(holdsTName).
+		Where(
+			goqu.C("status").Eq(types.HoldActive.String()),
+			goqu.C("expires_at").Lte(nowUTC),
+		).
+		Order(goqu.I("expires_at").Asc()).
+		Limit(uint(limit)).
+		ForUpdate(exp.SkipLocked).
+		ToSQL()
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to build query")
+	}
+
+	holds := []models.Hold{}
+	if err := tx.SelectContext(ctx, &holds, qu); err != nil {
+		return 0, errors.Wrapf(err, "failed to select expired holds")
+	}
+
+	for i := range holds {
+		if _, err := releaseHold(ctx, tx, &holds[i], types.HoldExpired); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, errors.Wrapf(err, "failed to commit")
+	}
+
+	return int64(len(holds)), nil
+}
+
+func lockActiveHold(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	holdID string,
+	userID string,
+) (*models.Hold, error) {
+	qu, _, err := goqu.
+		Select(&models.Hold{}).
+		From(holdsTName).
+		Where(
+			goqu.C("hold_id").Eq(holdID),
+			goqu.C("user_id").Eq(userID),
+		).
+		ForUpdate(exp.Wait).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	var hold models.Hold
+	err = tx.QueryRowxContext(ctx, qu).StructScan(&hold)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, exceptions.ErrHoldNotFound
+		}
+		return nil, errors.Wrapf(err, "failed to get hold")
+	}
+
+	if hold.Status != types.HoldActive.String() {
+		return nil, exceptions.ErrHoldIsNotActive
+	}
+
+	return &hold, nil
+}
+
+func releaseHold(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	hold *models.Hold,
+	status types.HoldStatus,
+) (*models.Hold, error) {
+	_, err := ledgerPost(
+		ctx,
+		tx,
+		models.NewReleaseTransaction(hold.UserID, hold.Order, models.HoldLots(hold.ID), hold.Held()),
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to post release")
+	}
+
+	return finishHold(ctx, tx, hold.ID, status, "")
+}
+
+func finishHold(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	holdID string,
+	status types.HoldStatus,
+	withdrawalID string,
+) (*models.Hold, error) {
+	record := goqu.Record{
+		"status":     status.String(),
+		"updated_at": nowUTC,
+	}
+	if withdrawalID != "" {
+		record["withdrawal_id"] = withdrawalID
+	}
+
+	qu, _, err := goqu.
+		Update(holdsTName).
+		Set(record).
+		Where(goqu.C("hold_id").Eq(holdID)).
+		Returning(&models.Hold{}).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	var hold models.Hold
+	err = tx.QueryRowxContext(ctx, qu).StructScan(&hold)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to update hold")
+	}
+
+	return &hold, nil
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"gophermart/internal/exceptions"
+	"gophermart/internal/models"
+	"gophermart/internal/types"
+	"gophermart/internal/withdrawals"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestHoldsRepoImpl_Lifecycle(t *testing.T) {
+	repos := setupRepos(t)
+	ctx := context.Background()
+
+	user := createTestUser(t, repos, "holds-")
+
+	accrueTestOrder(t, repos, user.ID, types.MoneyFromInt(100))
+
+	assertBalance := func(t *testing.T, current, withdrawn, held int64) {
+		t.Helper()
+
+		balance, err := repos.BalanceRepo.GetOrCreateForUser(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("failed to get balance: %s", err)
+		}
+		if !balance.Current.Equal(types.MoneyFromInt(current)) ||
+			!balance.Withdrawn.Equal(types.MoneyFromInt(withdrawn)) ||
+			!balance.Held.Equal(types.MoneyFromInt(held)) {
+			t.Errorf(
+				"balance is different: got=%s/%s/%s want=%d/%d/%d",
+				balance.Current, balance.Withdrawn, balance.Held, current, withdrawn, held,
+			)
+		}
+	}
+
+	captured, err := repos.HoldsRepo.Create(
+		ctx,
+		models.NewHold(user.ID, "2377225624", types.MoneyFromInt(30), time.Hour),
+	)
+	if err != nil {
+		t.Fatalf("failed to hold: %s", err)
+	}
+	released, err := repos.HoldsRepo.Create(
+		ctx,
+		models.NewHold(user.ID, "2377225624", types.MoneyFromInt(20), time.Hour),
+	)
+	if err != nil {
+		t.Fatalf("failed to hold: %s", err)
+	}
+	assertBalance(t, 50, 0, 50)
+
+	_, err = repos.HoldsRepo.Create(
+		ctx,
+		models.NewHold(user.ID, "2377225624", types.MoneyFromInt(60), time.Hour),
+	)
+	if !errors.Is(err, exceptions.ErrBalanceIsNegative) {
+		t.Fatalf("overdraft error is different: got=%v want=%s", err, exceptions.ErrBalanceIsNegative)
+	}
+
+	hold, err := repos.HoldsRepo.Capture(ctx, captured.ID, user.ID)
+	if err != nil {
+		t.Fatalf("failed to capture: %s", err)
+	}
+	if hold.Status != types.HoldCaptured.String() || hold.WithdrawalID == nil {
+		t.Errorf("hold is not captured: %+v", hold)
+	}
+	if _, err := repos.HoldsRepo.Release(ctx, released.ID, user.ID); err != nil {
+		t.Fatalf("failed to release: %s", err)
+	}
+	assertBalance(t, 70, 30, 0)
+
+	_, err = repos.HoldsRepo.Release(ctx, captured.ID, user.ID)
+	if !errors.Is(err, exceptions.ErrHoldIsNotActive) {
+		t.Errorf("release error is different: got=%v want=%s", err, exceptions.ErrHoldIsNotActive)
+	}
+
+	expired, err := repos.HoldsRepo.Create(
+		ctx,
+		models.NewHold(user.ID, "2377225624", types.MoneyFromInt(10), -time.Minute),
+	)
+	if err != nil {
+		t.Fatalf("failed to hold: %s", err)
+	}
+
+	_, err = repos.HoldsRepo.Capture(ctx, expired.ID, user.ID)
+	if !errors.Is(err, exceptions.ErrHoldExpired) {
+		t.Errorf("capture error is different: got=%v want=%s", err, exceptions.ErrHoldExpired)
+	}
+
+	for {
+		n, err := repos.HoldsRepo.ReleaseExpired(ctx, 100)
+		if err != nil {
+			t.Fatalf("failed to release expired: %s", err)
+		}
+		if n == 0 {
+			break
+		}
+	}
+	assertBalance(t, 70, 30, 0)
+}
+
+func TestHoldsRepoImpl_Capture_Policy(t *testing.T) {
+	repos := setupRepos(t)
+	ctx := context.Background()
+	repos.WithdrawalPolicy = withdrawals.Policy{
+		DailySum:        types.MoneyFromInt(50),
+		FeeFixed:        types.MoneyFromInt(1),
+		ReviewThreshold: types.MoneyFromInt(30),
+	}
+
+	user := createTestUser(t, repos, "holds-policy-")
+
+	accrueTestOrder(t, repos, user.ID, types.MoneyFromInt(100))
+
+	assertBalance := func(t *testing.T, current, withdrawn, held int64) {
+		t.Helper()
+
+		balance, err := repos.BalanceRepo.GetOrCreateForUser(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("failed to get balance: %s", err)
+		}
+		if !balance.Current.Equal(types.MoneyFromInt(current)) ||
+			!balance.Withdrawn.Equal(types.MoneyFromInt(withdrawn)) ||
+			!balance.Held.Equal(types.MoneyFromInt(held)) {
+			t.Errorf(
+				"balance is different: got=%s/%s/%s want=%d/%d/%d",
+				balance.Current, balance.Withdrawn, balance.Held, current, withdrawn, held,
+			)
+		}
+	}
+
+	large, err := repos.HoldsRepo.Create(ctx, models.NewHold(user.ID, "2377225624", types.MoneyFromInt(40), time.Hour))
+	if err != nil {
+		t.Fatalf("failed to hold: %s", err)
+	}
+	small, err := repos.HoldsRepo.Create(ctx, models.NewHold(user.ID, "2377225624", types.MoneyFromInt(20), time.Hour))
+	if err != nil {
+		t.Fatalf("failed to hold: %s", err)
+	}
+	// Holds keep the fee along with the sum.
+	assertBalance(t, 38, 0, 62)
+
+	// A large capture waits for review with its points still held.
+	captured, err := repos.HoldsRepo.Capture(ctx, large.ID, user.ID)
+	if err != nil {
+		t.Fatalf("failed to capture: %s", err)
+	}
+	assertBalance(t, 38, 0, 62)
+
+	// The pending withdrawal counts against the daily cap.
+	_, err = repos.HoldsRepo.Capture(ctx, small.ID, user.ID)
+	var violation *withdrawals.Violation
+	if !errors.As(err, &violation) || violation.Code != withdrawals.CodeDailyLimitExceeded {
+		t.Fatalf("capture error is different: got=%v want=%s", err, withdrawals.CodeDailyLimitExceeded)
+	}
+	if _, err := repos.HoldsRepo.Release(ctx, small.ID, user.ID); err != nil {
+		t.Fatalf("failed to release: %s", err)
+	}
+	assertBalance(t, 59, 0, 41)
+
+	review := models.NewWithdrawalReview(*captured.WithdrawalID, user.ID, types.WithdrawalCompleted, "")
+	withdrawal, err := repos.WithdrawalsRepo.Review(ctx, review)
+	if err != nil {
+		t.Fatalf("failed to approve: %s", err)
+	}
+	if !withdrawal.Fee.Equal(types.MoneyFromInt(1)) {
+		t.Errorf("fee is different: got=%s want=1", withdrawal.Fee)
+	}
+	assertBalance(t, 59, 40, 0)
+}
@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"gophermart/internal/models"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/pkg/errors"
+)
+
+type IdempotencyRepoImpl struct {
+	repos *Repos
+}
+
+func NewIdempotencyRepo(repos *Repos) *IdempotencyRepoImpl {
+	return &IdempotencyRepoImpl{repos: repos}
+}
+
+// Acquire stores a new key for the request and reports true, or returns
+// the key already stored by an earlier request and false. Expired keys
+// are taken over as if they did not exist.
+func (r *IdempotencyRepoImpl) Acquire(
+	ctx context.Context,
+	userID string,
+	key string,
+	fingerprint string,
+	ttl time.Duration,
+) (*models.IdempotencyKey, bool, error) {
+	qu, _, err := goqu.
+		Insert(idempotencyTName).
+		Rows(
+			goqu.Record{
+				"user_id":     userID,
+				"key":         key,
+				"fingerprint": fingerprint,
+				"created_at":  nowUTC,
+				"expires_at":  nowUTCPlus(ttl),
+			},
+		).
+		OnConflict(
+			goqu.DoUpdate(
+				"user_id, key",
+				goqu.Record{
+					"fingerprint":  goqu.I("excluded.fingerprint"),
+					"status_code":  nil,
+					"content_type": nil,
+					"response":     nil,
+					"created_at":   goqu.I("excluded.created_at"),
+					"expires_at":   goqu.I("excluded.expires_at"),
+				},
+			).Where(goqu.I("usr_idempotency_keys.expires_at").Lt(nowUTC)),
+		).
+		Returning(&models.IdempotencyKey{}).
+		ToSQL()
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "failed to build query")
+	}
+
+	var stored models.IdempotencyKey
+	err = r.repos.DB.QueryRowxContext(ctx, qu).StructScan(&stored)
+	if err == nil {
+		return &stored, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, false, errors.Wrapf(err, "failed to insert idempotency key")
+	}
+
+	qu, _, err = goqu.
+		Select(&models.IdempotencyKey{}).
+		From(idempotencyTName).
+		Where(
+			goqu.C("user_id").Eq(userID),
+			goqu.C("key").Eq(key),
+		).
+		ToSQL()
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "failed to build query")
+	}
+
+	err = r.repos.DB.QueryRowxContext(ctx, qu).StructScan(&stored)
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "failed to get idempotency key")
+	}
+
+	return &stored, false, nil
+}
+
+func (r *IdempotencyRepoImpl) Complete(
+	ctx context.Context,
+	userID string,
+	key string,
+	statusCode int,
+	contentType string,
+	response []byte,
+) error {
+	qu, _, err := goqu.
+		Update(idempotencyTName).
+		Set(
+			map[string]interface{}{
+				"status_code":  statusCode,
+				"content_type": contentType,
+				"response":     string(response),
+			},
+		).
+		Where(
+			goqu.C("user_id").Eq(userID),
+			goqu.C("key").Eq(key),
+		).
+		ToSQL()
+	if err != nil {
+		return errors.Wrapf(err, "failed to build query")
+	}
+
+	if _, err := r.repos.DB.ExecContext(ctx, qu); err != nil {
+		return errors.Wrapf(err, "failed to complete idempotency key")
+	}
+
+	return nil
+}
+
+// Release forgets a key whose request failed, so the client may retry it.
+func (r *IdempotencyRepoImpl) Release(
+	ctx context.Context,
+	userID string,
+	key string,
+) error {
+	qu, _, err := goqu.
+		Delete(idempotencyTName).
+		Where(
+			goqu.C("user_id").Eq(userID),
+			goqu.C("key").Eq(key),
+		).
+		ToSQL()
+	if err != nil {
+		return errors.Wrapf(err, "failed to build query")
+	}
+
+	if _, err := r.repos.DB.ExecContext(ctx, qu); err != nil {
+		return errors.Wrapf(err, "failed to release idempotency key")
+	}
+
+	return nil
+}
+
+func (r *IdempotencyRepoImpl) DeleteExpired(ctx context.Context) (int64, error) {
+	qu, _, err := goqu.
+		Delete(idempotencyTName).
+		Where(goqu.C("expires_at").Lt(nowUTC)).
+		ToSQL()
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to build query")
+	}
+
+	res, err := r.repos.DB.ExecContext(ctx, qu)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to delete expired idempotency keys")
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to get affected rows")
+	}
+
+	return deleted, nil
+}
@@ -3,7 +3,11 @@ package repository
 import (
 	"context"
 	"errors"
+	"gophermart/internal/loyalty"
 	"gophermart/internal/models"
+	"gophermart/internal/types"
+	"gophermart/internal/withdrawals"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -11,12 +15,33 @@ import (
 type Repos struct {
 	DB *sqlx.DB
 
-	HealthRepo      HealthRepo
-	AuthRepo        AuthRepo
-	UsersRepo       UsersRepo
-	BalanceRepo     BalanceRepo
-	WithdrawalsRepo WithdrawalsRepo
-	OrdersRepo      OrdersRepo
+	// Loyalty is the tier program applied to accruals, nil disables it.
+	Loyalty *loyalty.Program
+	// Referrals are the referral program terms applied to accruals.
+	Referrals models.ReferralTerms
+	// ClawbackPolicy applies to revoked orders the balance can't pay back.
+	ClawbackPolicy types.ClawbackPolicy
+	// WithdrawalPolicy caps withdrawals and sets their fee.
+	WithdrawalPolicy withdrawals.Policy
+
+	HealthRepo       HealthRepo
+	AuthRepo         AuthRepo
+	UsersRepo        UsersRepo
+	BalanceRepo      BalanceRepo
+	WithdrawalsRepo  WithdrawalsRepo
+	HoldsRepo        HoldsRepo
+	OrdersRepo       OrdersRepo
+	DeadLettersRepo  DeadLettersRepo
+	CallbacksRepo    CallbacksRepo
+	OrderHistoryRepo OrderHistoryRepo
+	LedgerRepo       LedgerRepo
+	PointLotsRepo    PointLotsRepo
+	LoyaltyRepo      LoyaltyRepo
+	TransfersRepo    TransfersRepo
+	CampaignsRepo    CampaignsRepo
+	ReferralsRepo    ReferralsRepo
+	AdjustmentsRepo  AdjustmentsRepo
+	IdempotencyRepo  IdempotencyRepo
 }
 
 type HealthRepo interface {
@@ -30,30 +55,112 @@ type AuthRepo interface {
 
 type UsersRepo interface {
 	Create(ctx context.Context, model *models.AuthUser) (*models.User, error)
+	IsAdmin(ctx context.Context, userID string) (bool, error)
 }
 
 type BalanceRepo interface {
 	GetOrCreateForUser(ctx context.Context, userID string) (*models.Balance, error)
 	Get(ctx context.Context, balanceID string) (*models.Balance, error)
 	Create(ctx context.Context, model *models.Balance) (*models.Balance, error)
-	Update(ctx context.Context, model *models.Balance) (*models.Balance, error)
 }
 
 type WithdrawalsRepo interface {
 	Create(ctx context.Context, model *models.Withdrawal) (*models.Withdrawal, error)
 	UserWithdrawals(ctx context.Context, userID string) (*[]models.Withdrawal, error)
+	Reverse(
+		ctx context.Context,
+		reversal *models.WithdrawalReversal,
+		ownerID string,
+		window time.Duration,
+	) (*models.Withdrawal, error)
+	PendingReviews(ctx context.Context, limit int, offset int) (*[]models.Withdrawal, error)
+	Review(ctx context.Context, review *models.WithdrawalReview) (*models.Withdrawal, error)
+}
+
+type HoldsRepo interface {
+	Create(ctx context.Context, model *models.Hold) (*models.Hold, error)
+	Capture(ctx context.Context, holdID string, userID string) (*models.Hold, error)
+	Release(ctx context.Context, holdID string, userID string) (*models.Hold, error)
+	ReleaseExpired(ctx context.Context, limit int) (int64, error)
 }
 
 type OrdersRepo interface {
 	Create(ctx context.Context, model *models.Order) (*models.Order, error)
-	MarkAsProcessing(ctx context.Context, orderIDs []string) (bool, error)
-	MarkAsInvalid(ctx context.Context, orderIDs []string) (bool, error)
+	ClaimDueOrders(ctx context.Context, owner string, leaseTTL time.Duration, limit int) (*[]models.Order, error)
+	ClaimOrder(ctx context.Context, orderID string, owner string, leaseTTL time.Duration) (*models.Order, error)
+	ExtendLeases(ctx context.Context, owner string, leaseTTL time.Duration) (int64, error)
+	ReleaseLeases(ctx context.Context, owner string) (int64, error)
+	ScheduleCheck(ctx context.Context, orderID string, attempts int, nextCheckAt *time.Time) (bool, error)
+	MarkAsProcessing(ctx context.Context, orderIDs []string, change models.StatusChange) (bool, error)
+	MarkAsInvalid(ctx context.Context, orderIDs []string, change models.StatusChange) (bool, error)
 	Accrue(ctx context.Context, record models.AccrueRecord) (bool, error)
+	Revoke(ctx context.Context, record models.RevokeRecord) (*models.Clawback, error)
 	UserOrders(ctx context.Context, userID string) (*[]models.Order, error)
 	GetByNumber(ctx context.Context, orderNumber string) (*models.Order, error)
 	GetUserOrderByNumber(ctx context.Context, userID string, orderNumber uint64) (*models.Order, error)
 }
 
+type OrderHistoryRepo interface {
+	List(ctx context.Context, orderID string) (*[]models.OrderStatusHistory, error)
+}
+
+type LedgerRepo interface {
+	UserEntries(ctx context.Context, userID string, limit int, offset int) (*[]models.LedgerEntry, error)
+}
+
+type PointLotsRepo interface {
+	DueUsers(ctx context.Context, months int, limit int) ([]string, error)
+	Expire(ctx context.Context, userID string, months int) (types.Money, error)
+	Expiring(ctx context.Context, userID string, months int, until time.Time) (*models.ExpiringPoints, error)
+}
+
+type LoyaltyRepo interface {
+	UserTier(ctx context.Context, userID string, window time.Duration) (*models.UserTier, error)
+}
+
+type TransfersRepo interface {
+	Create(ctx context.Context, model *models.Transfer, recipientLogin string, limits models.TransferLimits) (*models.Transfer, error)
+	UserTransfers(ctx context.Context, userID string, limit int, offset int) (*[]models.TransferRead, error)
+}
+
+type CampaignsRepo interface {
+	Create(ctx context.Context, model *models.Campaign) (*models.Campaign, error)
+	List(ctx context.Context, limit int, offset int) (*[]models.Campaign, error)
+	Get(ctx context.Context, campaignID string) (*models.Campaign, error)
+	Update(ctx context.Context, model *models.Campaign) (*models.Campaign, error)
+	Delete(ctx context.Context, campaignID string) error
+	Bonuses(ctx context.Context, campaignID string, limit int, offset int) (*[]models.CampaignBonus, error)
+	ReverseBonus(ctx context.Context, bonusID string) (*models.CampaignBonus, error)
+}
+
+type ReferralsRepo interface {
+	UserReferrals(ctx context.Context, userID string, limit int, offset int) (*models.ReferralsRead, error)
+}
+
+type AdjustmentsRepo interface {
+	Create(ctx context.Context, model *models.Adjustment) (*models.Adjustment, error)
+	List(ctx context.Context, filter models.AdjustmentsFilter, limit int, offset int) (*[]models.Adjustment, error)
+}
+
+type IdempotencyRepo interface {
+	Acquire(ctx context.Context, userID string, key string, fingerprint string, ttl time.Duration) (*models.IdempotencyKey, bool, error)
+	Complete(ctx context.Context, userID string, key string, statusCode int, contentType string, response []byte) error
+	Release(ctx context.Context, userID string, key string) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+type DeadLettersRepo interface {
+	Park(ctx context.Context, model *models.DeadLetter) (*models.DeadLetter, error)
+	List(ctx context.Context, limit int, offset int) (*[]models.DeadLetter, error)
+	Replay(ctx context.Context, deadLetterID string) (*models.Order, error)
+	Discard(ctx context.Context, deadLetterID string) (*models.DeadLetter, error)
+}
+
+type CallbacksRepo interface {
+	Register(ctx context.Context, signature string, number string, ttl time.Duration) error
+	Forget(ctx context.Context, signature string) error
+}
+
 func NewRepos(ctx context.Context, db *sqlx.DB) (*Repos, error) {
 	if db != nil {
 		repos := &Repos{DB: db}
@@ -62,7 +169,19 @@ func NewRepos(ctx context.Context, db *sqlx.DB) (*Repos, error) {
 		repos.UsersRepo = NewUsersRepo(repos)
 		repos.BalanceRepo = NewBalanceRepo(repos)
 		repos.WithdrawalsRepo = NewWithdrawalsRepo(repos)
+		repos.HoldsRepo = NewHoldsRepo(repos)
 		repos.OrdersRepo = NewOrdersRepoImpl(repos)
+		repos.DeadLettersRepo = NewDeadLettersRepo(repos)
+		repos.CallbacksRepo = NewCallbacksRepo(repos)
+		repos.OrderHistoryRepo = NewOrderHistoryRepo(repos)
+		repos.LedgerRepo = NewLedgerRepo(repos)
+		repos.PointLotsRepo = NewPointLotsRepo(repos)
+		repos.LoyaltyRepo = NewLoyaltyRepo(repos)
+		repos.TransfersRepo = NewTransfersRepo(repos)
+		repos.CampaignsRepo = NewCampaignsRepo(repos)
+		repos.ReferralsRepo = NewReferralsRepo(repos)
+		repos.AdjustmentsRepo = NewAdjustmentsRepo(repos)
+		repos.IdempotencyRepo = NewIdempotencyRepo(repos)
 		return repos, nil
 	} else {
 		return nil, errors.New("database is not provided")
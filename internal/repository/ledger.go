@@ -0,0 +1,218 @@
+package repository
+
+import (
+	"context"
+	"gophermart/internal/log"
+	"gophermart/internal/models"
+	"gophermart/internal/types"
+	"sort"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+type LedgerRepoImpl struct {
+	repos *Repos
+}
+
+func NewLedgerRepo(repos *Repos) *LedgerRepoImpl {
+	return &LedgerRepoImpl{repos: repos}
+}
+
+// UserEntries returns the entries of the user accounts, newest first.
+func (r *LedgerRepoImpl) UserEntries(
+	ctx context.Context,
+	userID string,
+	limit int,
+	offset int,
+) (*[]models.LedgerEntry, error) {
+	qu, _, err := goqu.
+		Select(&models.LedgerEntry{}).
+		From(ledgerTName).
+		Where(goqu.C("user_id").Eq(userID)).
+		Order(goqu.I("created_at").Desc(), goqu.I("entry_id").Asc()).
+		Limit(uint(limit)).
+		Offset(uint(offset)).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	rows, err := r.repos.DB.QueryxContext(ctx, qu)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read ledger error during querying")
+	}
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			log.Error(ctx, "failed to close rows", err)
+		}
+	}()
+
+	entries := []models.LedgerEntry{}
+	for rows.Next() {
+		entry := models.LedgerEntry{}
+		err := rows.StructScan(&entry)
+		if err != nil {
+			return nil, errors.Wrapf(err, "read ledger error during scan rows")
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrapf(err, "read ledger error during querying")
+	}
+
+	return &entries, nil
+}
+
+type balanceDelta struct {
+	current   types.Money
+	withdrawn types.Money
+	held      types.Money
+}
+
+// ledgerPost is the only way points move. It appends the transaction to the
+// ledger and applies it to the bll_balance projection and the point lots
+// within tx, creating missing balances. Balances are locked in user_id order to avoid deadlocks
+// between transactions touching several users. It returns the updated
+// balances by user id, callers check them before committing.
+func ledgerPost(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	txn *models.LedgerTransaction,
+) (map[string]*models.Balance, error) {
+	if len(txn.Postings) == 0 || !txn.Balanced() {
+		return nil, errors.Errorf("ledger transaction %s %s is not balanced", txn.Kind, txn.Reference)
+	}
+
+	transactionID := uuid.NewString()
+
+	var reference interface{}
+	if txn.Reference != "" {
+		reference = txn.Reference
+	}
+
+	entries := make([]interface{}, 0, len(txn.Postings))
+	deltas := map[string]*balanceDelta{}
+	for _, posting := range txn.Postings {
+		var userID interface{}
+		if posting.Account.IsUser() {
+			if posting.UserID == "" {
+				return nil, errors.Errorf("posting to %s without user", posting.Account)
+			}
+			userID = posting.UserID
+
+			delta, ok := deltas[posting.UserID]
+			if !ok {
+				delta = &balanceDelta{}
+				deltas[posting.UserID] = delta
+			}
+			switch posting.Account {
+			case types.AccountUserCurrent:
+				delta.current = delta.current.Add(posting.Amount)
+			case types.AccountUserWithdrawn:
+				delta.withdrawn = delta.withdrawn.Add(posting.Amount)
+			case types.AccountUserHeld:
+				delta.held = delta.held.Add(posting.Amount)
+			}
+		}
+
+		entries = append(entries, goqu.Record{
+			"transaction_id": transactionID,
+			"user_id":        userID,
+			"account":        posting.Account.String(),
+			"kind":           txn.Kind.String(),
+			"amount":         posting.Amount,
+			"reference":      reference,
+			"created_at":     nowUTC,
+		})
+	}
+
+	qu, _, err := goqu.
+		Insert(ledgerTName).
+		Rows(entries...).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	if _, err := tx.ExecContext(ctx, qu); err != nil {
+		return nil, errors.Wrapf(err, "failed to insert ledger entries")
+	}
+
+	userIDs := make([]string, 0, len(deltas))
+	for userID := range deltas {
+		userIDs = append(userIDs, userID)
+	}
+	sort.Strings(userIDs)
+
+	balances := make(map[string]*models.Balance, len(userIDs))
+	for _, userID := range userIDs {
+		balance, err := applyBalanceDelta(ctx, tx, userID, deltas[userID])
+		if err != nil {
+			return nil, err
+		}
+		balances[userID] = balance
+	}
+
+	// Debits go first, so credits of the same transaction, e.g. of a
+	// transfer, find the lots they give back.
+	for _, credit := range []bool{false, true} {
+		for _, userID := range userIDs {
+			delta := deltas[userID].current
+			if delta.IsZero() || delta.IsPositive() != credit {
+				continue
+			}
+			if err := applyLots(ctx, tx, txn, userID, delta, balances[userID].Current); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return balances, nil
+}
+
+func applyBalanceDelta(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	userID string,
+	delta *balanceDelta,
+) (*models.Balance, error) {
+	qu, _, err := goqu.
+		Insert(balanceTName).
+		Rows(
+			goqu.Record{
+				"user_id":   userID,
+				"current":   delta.current,
+				"withdrawn": delta.withdrawn,
+				"held":      delta.held,
+			},
+		).
+		OnConflict(
+			goqu.DoUpdate(
+				"user_id",
+				goqu.Record{
+					"current":    goqu.L("bll_balance.current + excluded.current"),
+					"withdrawn":  goqu.L("bll_balance.withdrawn + excluded.withdrawn"),
+					"held":       goqu.L("bll_balance.held + excluded.held"),
+					"updated_at": nowUTC,
+				},
+			),
+		).
+		Returning(&models.Balance{}).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	var balance models.Balance
+	err = tx.QueryRowxContext(ctx, qu).StructScan(&balance)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to update balance")
+	}
+
+	return &balance, nil
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"gophermart/internal/exceptions"
+	"gophermart/internal/models"
+	"gophermart/internal/types"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestLedger_BalanceIsProjection(t *testing.T) {
+	repos := setupRepos(t)
+	ctx := context.Background()
+
+	user := createTestUser(t, repos, "ledger-")
+
+	accrueTestOrder(t, repos, user.ID, types.NewMoney(10010, -2))
+
+	withdrawal := models.NewWithdrawal(user.ID, "2377225624", types.NewMoney(4005, -2))
+	if _, err := repos.WithdrawalsRepo.Create(ctx, withdrawal); err != nil {
+		t.Fatalf("failed to withdraw: %s", err)
+	}
+
+	overdraft := models.NewWithdrawal(user.ID, "2377225624", types.MoneyFromInt(1000))
+	_, err := repos.WithdrawalsRepo.Create(ctx, overdraft)
+	if !errors.Is(err, exceptions.ErrBalanceIsNegative) {
+		t.Fatalf("overdraft error is different: got=%v want=%s", err, exceptions.ErrBalanceIsNegative)
+	}
+
+	entries, err := repos.LedgerRepo.UserEntries(ctx, user.ID, models.MaxPageLimit, 0)
+	if err != nil {
+		t.Fatalf("failed to get ledger: %s", err)
+	}
+
+	current, withdrawn := types.Money{}, types.Money{}
+	for _, entry := range *entries {
+		switch types.LedgerAccount(entry.Account) {
+		case types.AccountUserCurrent:
+			current = current.Add(entry.Amount)
+		case types.AccountUserWithdrawn:
+			withdrawn = withdrawn.Add(entry.Amount)
+		}
+	}
+
+	balance, err := repos.BalanceRepo.GetOrCreateForUser(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("failed to get balance: %s", err)
+	}
+	if !balance.Current.Equal(current) || !balance.Withdrawn.Equal(withdrawn) {
+		t.Errorf(
+			"balance is different from ledger: got=%s/%s want=%s/%s",
+			balance.Current, balance.Withdrawn, current, withdrawn,
+		)
+	}
+	if !balance.Current.Equal(types.NewMoney(6005, -2)) {
+		t.Errorf("current is different: got=%s want=60.05", balance.Current)
+	}
+}
@@ -0,0 +1,438 @@
+package repository
+
+import (
+	"context"
+	"gophermart/internal/models"
+	"gophermart/internal/types"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+type PointLotsRepoImpl struct {
+	repos *Repos
+}
+
+func NewPointLotsRepo(repos *Repos) *PointLotsRepoImpl {
+	return &PointLotsRepoImpl{repos: repos}
+}
+
+// DueUsers returns up to limit users having lots older than months.
+func (r *PointLotsRepoImpl) DueUsers(
+	ctx context.Context,
+	months int,
+	limit int,
+) ([]string, error) {
+	qu, _, err := goqu.
+		Select("user_id").
+		Distinct().
+		From(pointLotsTName).
+		Where(
+			goqu.C("remaining").Gt(0),
+			lotExpiresAt(months).Lte(nowUTC),
+		).
+		Limit(uint(limit)).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	userIDs := []string{}
+	if err := r.repos.DB.SelectContext(ctx, &userIDs, qu); err != nil {
+		return nil, errors.Wrapf(err, "failed to select users with expired lots")
+	}
+
+	return userIDs, nil
+}
+
+// Expire takes away the remaining points of the user lots older than months
+// and returns how many points expired.
+func (r *PointLotsRepoImpl) Expire(
+	ctx context.Context,
+	userID string,
+	months int,
+) (types.Money, error) {
+	tx, err := r.repos.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return types.Money{}, errors.Wrapf(err, "failed to begin transaction")
+	}
+	defer rollback(tx)
+
+	// Lots are changed only under the balance lock, see ledgerPost.
+	qu, _, err := goqu.
+		Select("current").
+		From(balanceTName).
+		Where(goqu.C("user_id").Eq(userID)).
+		ForUpdate(exp.Wait).
+		ToSQL()
+	if err != nil {
+		return types.Money{}, errors.Wrapf(err, "failed to build query")
+	}
+
+	var current types.Money
+	if err := tx.GetContext(ctx, &current, qu); err != nil {
+		return types.Money{}, errors.Wrapf(err, "failed to lock balance")
+	}
+
+	qu, _, err = goqu.
+		Select(goqu.L("COALESCE(SUM(remaining), 0)")).
+		From(pointLotsTName).
+		Where(
+			goqu.C("user_id").Eq(userID),
+			goqu.C("remaining").Gt(0),
+			lotExpiresAt(months).Lte(nowUTC),
+		).
+		ToSQL()
+	if err != nil {
+		return types.Money{}, errors.Wrapf(err, "failed to build query")
+	}
+
+	var expired types.Money
+	if err := tx.GetContext(ctx, &expired, qu); err != nil {
+		return types.Money{}, errors.Wrapf(err, "failed to sum expired lots")
+	}
+
+	if expired.GreaterThan(current) {
+		expired = current
+	}
+
+	// Expired lots are the oldest ones, so spending them in order
+	// consumes exactly those lots.
+	if expired.IsPositive() {
+		_, err = ledgerPost(ctx, tx, models.NewExpiryTransaction(userID, expired))
+		if err != nil {
+			return types.Money{}, errors.Wrapf(err, "failed to post expiry")
+		}
+	} else {
+		expired = types.Money{}
+	}
+
+	// What is left of them is not backed by the balance any more.
+	qu, _, err = goqu.
+		Update(pointLotsTName).
+		Set(goqu.Record{"remaining": 0}).
+		Where(
+			goqu.C("user_id").Eq(userID),
+			goqu.C("remaining").Gt(0),
+			lotExpiresAt(months).Lte(nowUTC),
+		).
+		ToSQL()
+	if err != nil {
+		return types.Money{}, errors.Wrapf(err, "failed to build query")
+	}
+
+	if _, err := tx.ExecContext(ctx, qu); err != nil {
+		return types.Money{}, errors.Wrapf(err, "failed to close expired lots")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return types.Money{}, errors.Wrapf(err, "failed to commit")
+	}
+
+	return expired, nil
+}
+
+// Expiring sums the user points which expire before until.
+func (r *PointLotsRepoImpl) Expiring(
+	ctx context.Context,
+	userID string,
+	months int,
+	until time.Time,
+) (*models.ExpiringPoints, error) {
+	qu, _, err := goqu.
+		Select(
+			goqu.L("COALESCE(SUM(remaining), 0)").As("amount"),
+			goqu.MIN(lotExpiresAt(months)).As("expires_at"),
+		).
+		From(pointLotsTName).
+		Where(
+			goqu.C("user_id").Eq(userID),
+			goqu.C("remaining").Gt(0),
+			lotExpiresAt(months).Lte(until.UTC().Format(time.RFC3339Nano)),
+		).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	var expiring models.ExpiringPoints
+	if err := r.repos.DB.GetContext(ctx, &expiring, qu); err != nil {
+		return nil, errors.Wrapf(err, "failed to sum expiring lots")
+	}
+
+	return &expiring, nil
+}
+
+func lotExpiresAt(months int) exp.LiteralExpression {
+	return goqu.L("accrued_at + make_interval(months => ?)", months)
+}
+
+// applyLots keeps lots in step with a change of the user current balance
+// made by txn. Debits spend the oldest lots first and, when txn names its
+// Lots, remember what they spent. Credits naming Lots give those spendings
+// back with their accrual dates, so returned points don't start a fresh
+// life; only credits of new points open a new lot. Debits beyond the lots
+// leave nothing to spend and credits first pay such a debt back, so lots
+// never hold more than current. The caller must hold the user balance lock.
+func applyLots(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	txn *models.LedgerTransaction,
+	userID string,
+	delta types.Money,
+	current types.Money,
+) error {
+	if delta.IsPositive() {
+		if current.LessThan(delta) {
+			delta = current
+		}
+		if !delta.IsPositive() {
+			return nil
+		}
+
+		if txn.Lots == "" {
+			if !txn.Kind.OpensLots() {
+				return errors.Errorf("%s credit doesn't name the lots it gives back", txn.Kind)
+			}
+			return openLot(ctx, tx, userID, delta)
+		}
+
+		restored, err := restoreLots(ctx, tx, userID, txn.Lots, delta)
+		if err != nil {
+			return err
+		}
+
+		// Only points spent before spendings were tracked are left.
+		if rest := delta.Sub(restored); rest.IsPositive() {
+			return openLot(ctx, tx, userID, rest)
+		}
+
+		return nil
+	}
+
+	qu, _, err := goqu.
+		Select(&models.PointLot{}).
+		From(pointLotsTName).
+		Where(
+			goqu.C("user_id").Eq(userID),
+			goqu.C("remaining").Gt(0),
+		).
+		Order(goqu.I("accrued_at").Asc(), goqu.I("lot_id").Asc()).
+		ToSQL()
+	if err != nil {
+		return errors.Wrapf(err, "failed to build query")
+	}
+
+	lots := []models.PointLot{}
+	if err := tx.SelectContext(ctx, &lots, qu); err != nil {
+		return errors.Wrapf(err, "failed to select lots")
+	}
+
+	spendings := []interface{}{}
+	debit := delta.Neg()
+	for _, lot := range lots {
+		if !debit.IsPositive() {
+			break
+		}
+
+		spent := lot.Remaining
+		if debit.LessThan(spent) {
+			spent = debit
+		}
+		debit = debit.Sub(spent)
+
+		qu, _, err := goqu.
+			Update(pointLotsTName).
+			Set(goqu.Record{"remaining": lot.Remaining.Sub(spent)}).
+			Where(goqu.C("lot_id").Eq(lot.ID)).
+			ToSQL()
+		if err != nil {
+			return errors.Wrapf(err, "failed to build query")
+		}
+
+		if _, err := tx.ExecContext(ctx, qu); err != nil {
+			return errors.Wrapf(err, "failed to spend lot")
+		}
+
+		if txn.Lots != "" {
+			spendings = append(spendings, goqu.Record{
+				"lot_id":     lot.ID,
+				"spent_for":  txn.Lots,
+				"amount":     spent,
+				"created_at": nowUTC,
+			})
+		}
+	}
+
+	if len(spendings) == 0 {
+		return nil
+	}
+
+	qu, _, err = goqu.
+		Insert(lotSpendingsTName).
+		Rows(spendings...).
+		ToSQL()
+	if err != nil {
+		return errors.Wrapf(err, "failed to build query")
+	}
+
+	if _, err := tx.ExecContext(ctx, qu); err != nil {
+		return errors.Wrapf(err, "failed to insert lot spendings")
+	}
+
+	return nil
+}
+
+func openLot(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	userID string,
+	amount types.Money,
+) error {
+	qu, _, err := goqu.
+		Insert(pointLotsTName).
+		Rows(
+			goqu.Record{
+				"user_id":    userID,
+				"amount":     amount,
+				"remaining":  amount,
+				"accrued_at": nowUTC,
+			},
+		).
+		ToSQL()
+	if err != nil {
+		return errors.Wrapf(err, "failed to build query")
+	}
+
+	if _, err := tx.ExecContext(ctx, qu); err != nil {
+		return errors.Wrapf(err, "failed to insert lot")
+	}
+
+	return nil
+}
+
+// restoreLots gives back up to amount of the lots spent for spentFor to
+// the user, oldest first, and returns how much it gave back. Lots of the
+// user are refilled, lots of another user, e.g. the sender of a transfer,
+// are copied to the user with their accrual date.
+func restoreLots(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	userID string,
+	spentFor string,
+	amount types.Money,
+) (types.Money, error) {
+	qu, _, err := goqu.
+		Select(
+			goqu.I("s.spending_id"),
+			goqu.I("s.lot_id"),
+			goqu.I("l.user_id"),
+			goqu.I("s.spent_for"),
+			goqu.I("s.amount"),
+			goqu.I("s.returned"),
+		).
+		From(goqu.T(lotSpendingsTName).As("s")).
+		Join(goqu.T(pointLotsTName).As("l"), goqu.On(goqu.I("l.lot_id").Eq(goqu.I("s.lot_id")))).
+		Where(
+			goqu.I("s.spent_for").Eq(spentFor),
+			goqu.I("s.returned").Lt(goqu.I("s.amount")),
+		).
+		Order(goqu.I("l.accrued_at").Asc(), goqu.I("s.lot_id").Asc()).
+		ForUpdate(exp.Wait, goqu.T("s")).
+		ToSQL()
+	if err != nil {
+		return types.Money{}, errors.Wrapf(err, "failed to build query")
+	}
+
+	spendings := []models.LotSpending{}
+	if err := tx.SelectContext(ctx, &spendings, qu); err != nil {
+		return types.Money{}, errors.Wrapf(err, "failed to select lot spendings")
+	}
+
+	restored := types.Money{}
+	for _, spending := range spendings {
+		left := amount.Sub(restored)
+		if !left.IsPositive() {
+			break
+		}
+
+		back := spending.Amount.Sub(spending.Returned)
+		if left.LessThan(back) {
+			back = left
+		}
+		restored = restored.Add(back)
+
+		if spending.UserID == userID {
+			qu, _, err = goqu.
+				Update(pointLotsTName).
+				Set(goqu.Record{"remaining": goqu.L("remaining + ?", back)}).
+				Where(goqu.C("lot_id").Eq(spending.LotID)).
+				ToSQL()
+		} else {
+			qu, _, err = goqu.
+				Insert(pointLotsTName).
+				FromQuery(
+					goqu.
+						Select(
+							goqu.V(userID).As("user_id"),
+							goqu.V(back).As("amount"),
+							goqu.V(back).As("remaining"),
+							goqu.C("accrued_at"),
+						).
+						From(pointLotsTName).
+						Where(goqu.C("lot_id").Eq(spending.LotID)),
+				).
+				Cols("user_id", "amount", "remaining", "accrued_at").
+				ToSQL()
+		}
+		if err != nil {
+			return types.Money{}, errors.Wrapf(err, "failed to build query")
+		}
+
+		if _, err := tx.ExecContext(ctx, qu); err != nil {
+			return types.Money{}, errors.Wrapf(err, "failed to restore lot")
+		}
+
+		qu, _, err = goqu.
+			Update(lotSpendingsTName).
+			Set(goqu.Record{"returned": goqu.L("returned + ?", back)}).
+			Where(goqu.C("spending_id").Eq(spending.ID)).
+			ToSQL()
+		if err != nil {
+			return types.Money{}, errors.Wrapf(err, "failed to build query")
+		}
+
+		if _, err := tx.ExecContext(ctx, qu); err != nil {
+			return types.Money{}, errors.Wrapf(err, "failed to return lot spending")
+		}
+	}
+
+	return restored, nil
+}
+
+// moveLotSpendings hands the lots spent for from over to to, e.g. when a
+// captured hold becomes a withdrawal.
+func moveLotSpendings(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	from string,
+	to string,
+) error {
+	qu, _, err := goqu.
+		Update(lotSpendingsTName).
+		Set(goqu.Record{"spent_for": to}).
+		Where(goqu.C("spent_for").Eq(from)).
+		ToSQL()
+	if err != nil {
+		return errors.Wrapf(err, "failed to build query")
+	}
+
+	if _, err := tx.ExecContext(ctx, qu); err != nil {
+		return errors.Wrapf(err, "failed to move lot spendings")
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"gophermart/internal/models"
+	"gophermart/internal/types"
+	"testing"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+)
+
+func TestPointLotsRepoImpl_Expire(t *testing.T) {
+	repos := setupRepos(t)
+	ctx := context.Background()
+
+	user := createTestUser(t, repos, "lots-")
+
+	for _, amount := range []int64{100, 50} {
+		accrueTestOrder(t, repos, user.ID, types.MoneyFromInt(amount))
+	}
+
+	// The first lot was accrued long ago, the second one recently.
+	qu, _, err := goqu.
+		Update(pointLotsTName).
+		Set(goqu.Record{"accrued_at": nowUTCPlus(-13 * 30 * 24 * time.Hour)}).
+		Where(goqu.C("user_id").Eq(user.ID), goqu.C("amount").Eq(types.MoneyFromInt(100))).
+		ToSQL()
+	if err != nil {
+		t.Fatalf("failed to build query: %s", err)
+	}
+	if _, err := repos.DB.ExecContext(ctx, qu); err != nil {
+		t.Fatalf("failed to age lot: %s", err)
+	}
+
+	withdrawal := models.NewWithdrawal(user.ID, "2377225624", types.MoneyFromInt(60))
+	if _, err := repos.WithdrawalsRepo.Create(ctx, withdrawal); err != nil {
+		t.Fatalf("failed to withdraw: %s", err)
+	}
+
+	expiring, err := repos.PointLotsRepo.Expiring(ctx, user.ID, 12, time.Now())
+	if err != nil {
+		t.Fatalf("failed to get expiring points: %s", err)
+	}
+	if !expiring.Amount.Equal(types.MoneyFromInt(40)) || expiring.ExpiresAt == nil {
+		t.Errorf("expiring points are different: got=%s want=40", expiring.Amount)
+	}
+
+	userIDs, err := repos.PointLotsRepo.DueUsers(ctx, 12, 1000)
+	if err != nil {
+		t.Fatalf("failed to get due users: %s", err)
+	}
+	found := false
+	for _, userID := range userIDs {
+		found = found || userID == user.ID
+	}
+	if !found {
+		t.Errorf("user with expired points is not due")
+	}
+
+	expired, err := repos.PointLotsRepo.Expire(ctx, user.ID, 12)
+	if err != nil {
+		t.Fatalf("failed to expire: %s", err)
+	}
+	if !expired.Equal(types.MoneyFromInt(40)) {
+		t.Errorf("expired points are different: got=%s want=40", expired)
+	}
+
+	balance, err := repos.BalanceRepo.GetOrCreateForUser(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("failed to get balance: %s", err)
+	}
+	if !balance.Current.Equal(types.MoneyFromInt(50)) {
+		t.Errorf("current is different: got=%s want=50", balance.Current)
+	}
+
+	expired, err = repos.PointLotsRepo.Expire(ctx, user.ID, 12)
+	if err != nil {
+		t.Fatalf("failed to expire: %s", err)
+	}
+	if !expired.IsZero() {
+		t.Errorf("points expired twice: %s", expired)
+	}
+}
+
+func TestPointLotsRepoImpl_Expire_Released(t *testing.T) {
+	repos := setupRepos(t)
+	ctx := context.Background()
+
+	user := createTestUser(t, repos, "lots-")
+
+	accrueTestOrder(t, repos, user.ID, types.MoneyFromInt(100))
+
+	qu, _, err := goqu.
+		Update(pointLotsTName).
+		Set(goqu.Record{"accrued_at": nowUTCPlus(-13 * 30 * 24 * time.Hour)}).
+		Where(goqu.C("user_id").Eq(user.ID)).
+		ToSQL()
+	if err != nil {
+		t.Fatalf("failed to build query: %s", err)
+	}
+	if _, err := repos.DB.ExecContext(ctx, qu); err != nil {
+		t.Fatalf("failed to age lot: %s", err)
+	}
+
+	// Released points come back into the old lot instead of a fresh one.
+	hold, err := repos.HoldsRepo.Create(ctx, models.NewHold(user.ID, "2377225624", types.MoneyFromInt(60), time.Hour))
+	if err != nil {
+		t.Fatalf("failed to hold: %s", err)
+	}
+	if _, err := repos.HoldsRepo.Release(ctx, hold.ID, user.ID); err != nil {
+		t.Fatalf("failed to release: %s", err)
+	}
+
+	expired, err := repos.PointLotsRepo.Expire(ctx, user.ID, 12)
+	if err != nil {
+		t.Fatalf("failed to expire: %s", err)
+	}
+	if !expired.Equal(types.MoneyFromInt(100)) {
+		t.Errorf("expired points are different: got=%s want=100", expired)
+	}
+
+	balance, err := repos.BalanceRepo.GetOrCreateForUser(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("failed to get balance: %s", err)
+	}
+	if !balance.Current.IsZero() {
+		t.Errorf("current is different: got=%s want=0", balance.Current)
+	}
+}
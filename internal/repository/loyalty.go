@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"gophermart/internal/exceptions"
+	"gophermart/internal/loyalty"
+	"gophermart/internal/models"
+	"gophermart/internal/types"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+type LoyaltyRepoImpl struct {
+	repos *Repos
+}
+
+func NewLoyaltyRepo(repos *Repos) *LoyaltyRepoImpl {
+	return &LoyaltyRepoImpl{repos: repos}
+}
+
+// UserTier returns the stored tier of the user and the points accrued
+// within window.
+func (r *LoyaltyRepoImpl) UserTier(
+	ctx context.Context,
+	userID string,
+	window time.Duration,
+) (*models.UserTier, error) {
+	qu, _, err := goqu.
+		Select("tier", "tier_updated_at").
+		From(usersTName).
+		Where(goqu.C("user_id").Eq(userID)).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	var tier models.UserTier
+	if err := r.repos.DB.GetContext(ctx, &tier, qu); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, exceptions.ErrUserNotFound
+		}
+		return nil, errors.Wrapf(err, "failed to get user tier")
+	}
+
+	tier.Accrued, err = accruedWithin(ctx, r.repos.DB, userID, window)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tier, nil
+}
+
+// accruedWithin sums the points credited to the user by orders within
+// window. Bonuses do not count.
+func accruedWithin(
+	ctx context.Context,
+	q sqlx.QueryerContext,
+	userID string,
+	window time.Duration,
+) (types.Money, error) {
+	qu, _, err := goqu.
+		Select(goqu.L("COALESCE(SUM(amount), 0)")).
+		From(ledgerTName).
+		Where(
+			goqu.C("user_id").Eq(userID),
+			goqu.C("account").Eq(types.AccountUserCurrent.String()),
+			goqu.C("kind").Eq(types.LedgerAccrual.String()),
+			goqu.C("created_at").Gte(nowUTCPlus(-window)),
+		).
+		ToSQL()
+	if err != nil {
+		return types.Money{}, errors.Wrapf(err, "failed to build query")
+	}
+
+	var accrued types.Money
+	if err := sqlx.GetContext(ctx, q, &accrued, qu); err != nil {
+		return types.Money{}, errors.Wrapf(err, "failed to sum accruals")
+	}
+
+	return accrued, nil
+}
+
+// applyTier credits the bonus of the tier the user holds before an
+// accrual of amount already posted within tx, then moves the user to the
+// tier the accruals within the program window reach with it. Both tiers
+// are classified over the window, so a tier reached long ago earns no
+// bonus. The user row lock serializes tier changes of one user. It
+// returns the tier the accrual was credited in.
+func applyTier(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	program *loyalty.Program,
+	userID string,
+	orderNumber string,
+	amount types.Money,
+) (string, error) {
+	qu, _, err := goqu.
+		Select("tier").
+		From(usersTName).
+		Where(goqu.C("user_id").Eq(userID)).
+		ForUpdate(exp.Wait).
+		ToSQL()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to build query")
+	}
+
+	var stored *string
+	if err := tx.GetContext(ctx, &stored, qu); err != nil {
+		return "", errors.Wrapf(err, "failed to lock user tier")
+	}
+
+	accrued, err := accruedWithin(ctx, tx, userID, program.Window())
+	if err != nil {
+		return "", err
+	}
+
+	// The accrual itself only counts towards the next one.
+	current := program.Classify(accrued.Sub(amount))
+
+	bonus := program.Bonus(current.Name, amount)
+	if bonus.IsPositive() {
+		_, err := ledgerPost(
+			ctx,
+			tx,
+			models.NewTierBonusTransaction(userID, orderNumber, bonus),
+		)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to post tier bonus")
+		}
+	}
+
+	reached := program.Classify(accrued)
+	if stored != nil && *stored == reached.Name {
+		return current.Name, nil
+	}
+
+	qu, _, err = goqu.
+		Update(usersTName).
+		Set(
+			goqu.Record{
+				"tier":            reached.Name,
+				"tier_updated_at": nowUTC,
+			},
+		).
+		Where(goqu.C("user_id").Eq(userID)).
+		ToSQL()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to build query")
+	}
+
+	if _, err := tx.ExecContext(ctx, qu); err != nil {
+		return "", errors.Wrapf(err, "failed to update user tier")
+	}
+
+	return current.Name, nil
+}
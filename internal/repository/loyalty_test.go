@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"gophermart/internal/loyalty"
+	"gophermart/internal/types"
+	"testing"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+)
+
+func TestOrdersRepoImpl_Accrue_Tiers(t *testing.T) {
+	repos := setupRepos(t)
+	ctx := context.Background()
+
+	tiers, err := loyalty.ParseTiers("BRONZE:0:1,SILVER:100:1.5")
+	if err != nil {
+		t.Fatalf("failed to parse tiers: %s", err)
+	}
+	repos.Loyalty = loyalty.NewProgram(tiers, time.Hour)
+
+	user := createTestUser(t, repos, "tiers-")
+
+	// The first accrual lifts the user to SILVER, the second one earns its bonus.
+	for i := 0; i < 2; i++ {
+		accrueTestOrder(t, repos, user.ID, types.MoneyFromInt(100))
+	}
+
+	userTier, err := repos.LoyaltyRepo.UserTier(ctx, user.ID, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to get tier: %s", err)
+	}
+	if userTier.Tier == nil || *userTier.Tier != "SILVER" {
+		t.Errorf("tier is different: got=%v want=SILVER", userTier.Tier)
+	}
+	if !userTier.Accrued.Equal(types.MoneyFromInt(200)) {
+		t.Errorf("accrued is different: got=%s want=200", userTier.Accrued)
+	}
+
+	balance, err := repos.BalanceRepo.GetOrCreateForUser(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("failed to get balance: %s", err)
+	}
+	if !balance.Current.Equal(types.MoneyFromInt(250)) {
+		t.Errorf("current is different: got=%s want=250", balance.Current)
+	}
+}
+
+func TestOrdersRepoImpl_Accrue_StaleTier(t *testing.T) {
+	repos := setupRepos(t)
+	ctx := context.Background()
+
+	tiers, err := loyalty.ParseTiers("BRONZE:0:1,SILVER:100:1.5")
+	if err != nil {
+		t.Fatalf("failed to parse tiers: %s", err)
+	}
+	repos.Loyalty = loyalty.NewProgram(tiers, time.Hour)
+
+	user := createTestUser(t, repos, "tiers-")
+
+	// The user reached SILVER with accruals that left the window since.
+	qu, _, err := goqu.
+		Update(usersTName).
+		Set(goqu.Record{"tier": "SILVER", "tier_updated_at": nowUTCPlus(-2 * time.Hour)}).
+		Where(goqu.C("user_id").Eq(user.ID)).
+		ToSQL()
+	if err != nil {
+		t.Fatalf("failed to build query: %s", err)
+	}
+	if _, err := repos.DB.ExecContext(ctx, qu); err != nil {
+		t.Fatalf("failed to set tier: %s", err)
+	}
+
+	accrueTestOrder(t, repos, user.ID, types.MoneyFromInt(50))
+
+	userTier, err := repos.LoyaltyRepo.UserTier(ctx, user.ID, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to get tier: %s", err)
+	}
+	if userTier.Tier == nil || *userTier.Tier != "BRONZE" {
+		t.Errorf("tier is different: got=%v want=BRONZE", userTier.Tier)
+	}
+
+	balance, err := repos.BalanceRepo.GetOrCreateForUser(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("failed to get balance: %s", err)
+	}
+	if !balance.Current.Equal(types.MoneyFromInt(50)) {
+		t.Errorf("current is different: got=%s want=50", balance.Current)
+	}
+}
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"gophermart/internal/log"
+	"gophermart/internal/models"
+	"gophermart/internal/types"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+type OrderHistoryRepoImpl struct {
+	repos *Repos
+}
+
+func NewOrderHistoryRepo(repos *Repos) *OrderHistoryRepoImpl {
+	return &OrderHistoryRepoImpl{repos: repos}
+}
+
+func (r *OrderHistoryRepoImpl) List(
+	ctx context.Context,
+	orderID string,
+) (*[]models.OrderStatusHistory, error) {
+	qu, _, err := goqu.
+		Select(&models.OrderStatusHistory{}).
+		From(orderHistoryTName).
+		Where(goqu.C("order_id").Eq(orderID)).
+		Order(goqu.I("changed_at").Asc()).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	rows, err := r.repos.DB.QueryxContext(ctx, qu)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read order history error during querying")
+	}
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			log.Error(ctx, "failed to close rows", err)
+		}
+	}()
+
+	history := []models.OrderStatusHistory{}
+	for rows.Next() {
+		entry := models.OrderStatusHistory{}
+		err := rows.StructScan(&entry)
+		if err != nil {
+			return nil, errors.Wrapf(err, "read order history error during scan rows")
+		}
+		history = append(history, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrapf(err, "read order history error during querying")
+	}
+
+	return &history, nil
+}
+
+// lockStatuses locks the matching orders for the rest of tx
+// and returns their current statuses by order id.
+func lockStatuses(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	where exp.Expression,
+) (map[string]string, error) {
+	qu, _, err := goqu.
+		Select("order_id", "status").
+		From(ordersTName).
+		Where(where).
+		ForUpdate(exp.Wait).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	rows, err := tx.QueryxContext(ctx, qu)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to lock orders")
+	}
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			log.Error(ctx, "failed to close rows", err)
+		}
+	}()
+
+	statuses := map[string]string{}
+	for rows.Next() {
+		var orderID, status string
+		if err := rows.Scan(&orderID, &status); err != nil {
+			return nil, errors.Wrapf(err, "failed to scan order status")
+		}
+		statuses[orderID] = status
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrapf(err, "failed to lock orders")
+	}
+
+	return statuses, nil
+}
+
+// recordStatusChanges appends a history entry for every order
+// whose status in from differs from the new one.
+func recordStatusChanges(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	from map[string]string,
+	status types.OrderStatus,
+	change models.StatusChange,
+) error {
+	entries := []interface{}{}
+	for orderID, fromStatus := range from {
+		if fromStatus == status.String() {
+			continue
+		}
+
+		entry := goqu.Record{
+			"order_id":    orderID,
+			"from_status": nil,
+			"status":      status.String(),
+			"source":      change.Source.String(),
+			"response":    change.ResponseJSON(),
+			"changed_at":  nowUTC,
+		}
+		if fromStatus != "" {
+			entry["from_status"] = fromStatus
+		}
+		entries = append(entries, entry)
+	}
+
+	if len(entries) == 0 {
+		return nil
+	}
+
+	qu, _, err := goqu.
+		Insert(orderHistoryTName).
+		Rows(entries...).
+		ToSQL()
+	if err != nil {
+		return errors.Wrapf(err, "failed to build query")
+	}
+
+	if _, err := tx.ExecContext(ctx, qu); err != nil {
+		return errors.Wrapf(err, "failed to insert order history")
+	}
+
+	return nil
+}
@@ -8,8 +8,10 @@ import (
 	"gophermart/internal/log"
 	"gophermart/internal/models"
 	"gophermart/internal/types"
+	"time"
 
 	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
 	"github.com/pkg/errors"
 )
 
@@ -25,6 +27,12 @@ func (r *OrdersRepoImpl) Create(
 	ctx context.Context,
 	model *models.Order,
 ) (*models.Order, error) {
+	tx, err := r.repos.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to begin transaction")
+	}
+	defer rollback(tx)
+
 	qu, _, err := goqu.
 		Insert(ordersTName).
 		Rows(model).
@@ -42,11 +50,26 @@ func (r *OrdersRepoImpl) Create(
 	}
 
 	var order models.Order
-	err = r.repos.DB.QueryRowxContext(ctx, qu).StructScan(&order)
+	err = tx.QueryRowxContext(ctx, qu).StructScan(&order)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to insert")
 	}
 
+	err = recordStatusChanges(
+		ctx,
+		tx,
+		map[string]string{order.ID: ""},
+		types.OrderStatus(order.Status),
+		models.StatusChange{Source: types.SourceUser},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrapf(err, "failed to commit")
+	}
+
 	return &order, nil
 }
 
@@ -108,35 +131,248 @@ func (r *OrdersRepoImpl) GetUserOrderByNumber(
 	return &order, nil
 }
 
+func (r *OrdersRepoImpl) ClaimDueOrders(
+	ctx context.Context,
+	owner string,
+	leaseTTL time.Duration,
+	limit int,
+) (*[]models.Order, error) {
+	due := goqu.
+		Select(goqu.C("order_id")).
+		From(ordersTName).
+		Where(
+			goqu.C("status").In(
+				types.OrderNew.String(),
+				types.OrderProcessing.String(),
+			),
+			goqu.C("next_check_at").Lte(nowUTC),
+			goqu.Or(
+				goqu.C("lease_expires_at").IsNull(),
+				goqu.C("lease_expires_at").Lt(nowUTC),
+			),
+		).
+		Order(goqu.I("next_check_at").Asc()).
+		Limit(uint(limit)).
+		ForUpdate(exp.SkipLocked)
+
+	qu, _, err := goqu.
+		Update(ordersTName).
+		Set(
+			map[string]interface{}{
+				"lease_owner":      owner,
+				"lease_expires_at": nowUTCPlus(leaseTTL),
+			},
+		).
+		Where(goqu.C("order_id").In(due)).
+		Returning(&models.Order{}).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	return r.selectOrders(ctx, qu)
+}
+
+func (r *OrdersRepoImpl) ClaimOrder(
+	ctx context.Context,
+	orderID string,
+	owner string,
+	leaseTTL time.Duration,
+) (*models.Order, error) {
+	qu, _, err := goqu.
+		Update(ordersTName).
+		Set(
+			map[string]interface{}{
+				"lease_owner":      owner,
+				"lease_expires_at": nowUTCPlus(leaseTTL),
+			},
+		).
+		Where(
+			goqu.C("order_id").Eq(orderID),
+			goqu.Or(
+				goqu.C("lease_expires_at").IsNull(),
+				goqu.C("lease_expires_at").Lt(nowUTC),
+			),
+		).
+		Returning(&models.Order{}).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	var order models.Order
+	err = r.repos.DB.QueryRowxContext(ctx, qu).StructScan(&order)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, exceptions.ErrOrderIsLeased
+		}
+		return nil, errors.Wrapf(err, "failed to claim order")
+	}
+
+	return &order, nil
+}
+
+func (r *OrdersRepoImpl) ExtendLeases(
+	ctx context.Context,
+	owner string,
+	leaseTTL time.Duration,
+) (int64, error) {
+	qu, _, err := goqu.
+		Update(ordersTName).
+		Set(map[string]interface{}{"lease_expires_at": nowUTCPlus(leaseTTL)}).
+		Where(goqu.C("lease_owner").Eq(owner)).
+		ToSQL()
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to build query")
+	}
+
+	res, err := r.repos.DB.ExecContext(ctx, qu)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to update")
+	}
+
+	extended, err := res.RowsAffected()
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to get affected rows")
+	}
+
+	return extended, nil
+}
+
+// ReleaseLeases drops all leases held by owner without touching the schedule,
+// so due orders are picked up by the next sweep of any replica.
+func (r *OrdersRepoImpl) ReleaseLeases(
+	ctx context.Context,
+	owner string,
+) (int64, error) {
+	qu, _, err := goqu.
+		Update(ordersTName).
+		Set(
+			map[string]interface{}{
+				"lease_owner":      nil,
+				"lease_expires_at": nil,
+			},
+		).
+		Where(goqu.C("lease_owner").Eq(owner)).
+		ToSQL()
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to build query")
+	}
+
+	res, err := r.repos.DB.ExecContext(ctx, qu)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to update")
+	}
+
+	released, err := res.RowsAffected()
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to get affected rows")
+	}
+
+	return released, nil
+}
+
+func (r *OrdersRepoImpl) ScheduleCheck(
+	ctx context.Context,
+	orderID string,
+	attempts int,
+	nextCheckAt *time.Time,
+) (bool, error) {
+	var next interface{}
+	if nextCheckAt != nil {
+		next = nextCheckAt.UTC().Format(time.RFC3339Nano)
+	}
+
+	qu, _, err := goqu.
+		Update(ordersTName).
+		Set(
+			map[string]interface{}{
+				"check_attempts":   attempts,
+				"next_check_at":    next,
+				"lease_owner":      nil,
+				"lease_expires_at": nil,
+			},
+		).
+		Where(goqu.C("order_id").Eq(orderID)).
+		ToSQL()
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to build query")
+	}
+
+	_, err = r.repos.DB.ExecContext(ctx, qu)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to update")
+	}
+
+	return true, nil
+}
+
+// changeStatus only moves orders that are not final yet, so a late poll
+// result or callback never overwrites PROCESSED or INVALID.
+// Every actual transition is recorded in the order history.
 func (r *OrdersRepoImpl) changeStatus(
 	ctx context.Context,
 	orderIDs []string,
 	status types.OrderStatus,
+	change models.StatusChange,
 ) (bool, error) {
+	values := map[string]interface{}{"status": status.String()}
+	if status.IsFinal() {
+		values["next_check_at"] = nil
+		values["lease_owner"] = nil
+		values["lease_expires_at"] = nil
+	}
+
+	tx, err := r.repos.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to begin transaction")
+	}
+	defer rollback(tx)
+
+	where := goqu.And(
+		goqu.C("order_id").In(orderIDs),
+		goqu.C("status").In(
+			types.OrderNew.String(),
+			types.OrderProcessing.String(),
+		),
+	)
+
+	from, err := lockStatuses(ctx, tx, where)
+	if err != nil {
+		return false, err
+	}
+
 	qu, _, err := goqu.
 		Update(ordersTName).
-		Set(map[string]interface{}{"status": status.String()}).
-		Where(
-			goqu.C("order_id").In(orderIDs),
-		).
+		Set(values).
+		Where(where).
 		ToSQL()
 	if err != nil {
 		return false, errors.Wrapf(err, "failed to build query")
 	}
 
-	_, err = r.repos.DB.ExecContext(ctx, qu)
+	_, err = tx.ExecContext(ctx, qu)
 	if err != nil {
 		return false, errors.Wrapf(err, "failed to update")
 	}
 
+	if err := recordStatusChanges(ctx, tx, from, status, change); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, errors.Wrapf(err, "failed to commit")
+	}
+
 	return true, nil
 }
 
 func (r *OrdersRepoImpl) MarkAsProcessing(
 	ctx context.Context,
 	orderIDs []string,
+	change models.StatusChange,
 ) (bool, error) {
-	status, err := r.changeStatus(ctx, orderIDs, types.OrderProcessing)
+	status, err := r.changeStatus(ctx, orderIDs, types.OrderProcessing, change)
 	if err != nil {
 		return false, errors.Wrapf(err, "failed to change status")
 	}
@@ -147,8 +383,9 @@ func (r *OrdersRepoImpl) MarkAsProcessing(
 func (r *OrdersRepoImpl) MarkAsInvalid(
 	ctx context.Context,
 	orderIDs []string,
+	change models.StatusChange,
 ) (bool, error) {
-	status, err := r.changeStatus(ctx, orderIDs, types.OrderInvalid)
+	status, err := r.changeStatus(ctx, orderIDs, types.OrderInvalid, change)
 	if err != nil {
 		return false, errors.Wrapf(err, "failed to change status")
 	}
@@ -156,36 +393,48 @@ func (r *OrdersRepoImpl) MarkAsInvalid(
 	return status, nil
 }
 
+// Accrue marks the order as PROCESSED and posts the accrual to the ledger in one transaction.
+// The credit happens only on the transition from a non-final state,
+// so replaying the same record reports false and leaves the balance untouched.
 func (r *OrdersRepoImpl) Accrue(
 	ctx context.Context,
 	record models.AccrueRecord,
 ) (bool, error) {
-	_, err := r.repos.BalanceRepo.GetOrCreateForUser(ctx, record.UserID)
-	if err != nil {
-		return false, errors.Wrapf(err, "failed to get user balance")
-	}
-
 	// Setup transaction
 	tx, err := r.repos.DB.BeginTxx(ctx, nil)
 	if err != nil {
 		return false, errors.Wrapf(err, "failed to begin transaction")
 	}
 	defer func() {
-		if err := tx.Rollback(); err != nil {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
 			log.Error(context.Background(), "failed to rollback", err)
 		}
 	}()
 
+	from, err := lockStatuses(ctx, tx, goqu.C("number").Eq(record.Number))
+	if err != nil {
+		return false, err
+	}
+
 	// Update order
 	qu, _, err := goqu.
 		Update(ordersTName).
 		Set(
 			map[string]interface{}{
-				"accrual": record.Amount,
-				"status":  types.OrderProcessed.String(),
+				"accrual":          record.Amount,
+				"status":           types.OrderProcessed.String(),
+				"next_check_at":    nil,
+				"lease_owner":      nil,
+				"lease_expires_at": nil,
 			},
 		).
-		Where(goqu.C("number").Eq(record.Number)).
+		Where(
+			goqu.C("number").Eq(record.Number),
+			goqu.C("status").In(
+				types.OrderNew.String(),
+				types.OrderProcessing.String(),
+			),
+		).
 		Returning(
 			"order_id",
 			"user_id",
@@ -202,39 +451,121 @@ func (r *OrdersRepoImpl) Accrue(
 	var order models.Order
 	err = tx.QueryRowxContext(ctx, qu).StructScan(&order)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
 		return false, errors.Wrapf(err, "failed to update order")
 	}
 
 	// Making balance update
-	qu, _, err = goqu.
-		Update(balanceTName).
-		Set(
-			map[string]interface{}{
-				"current": goqu.L(fmt.Sprintf("current + %f", record.Amount)),
-			},
-		).
-		Where(goqu.C("user_id").Eq(record.UserID)).
+	_, err = ledgerPost(
+		ctx,
+		tx,
+		models.NewAccrualTransaction(order.UserID, order.Number, record.Amount),
+	)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to post accrual")
+	}
+
+	tier := ""
+	if r.repos.Loyalty != nil {
+		tier, err = applyTier(ctx, tx, r.repos.Loyalty, order.UserID, order.Number, record.Amount)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if err := applyCampaigns(ctx, tx, &order, record.Amount, tier); err != nil {
+		return false, err
+	}
+
+	if err := applyReferral(ctx, tx, r.repos.Referrals, &order, record.Amount); err != nil {
+		return false, err
+	}
+
+	if err := recoverClawbacks(ctx, tx, order.UserID); err != nil {
+		return false, err
+	}
+
+	err = recordStatusChanges(ctx, tx, from, types.OrderProcessed, record.Change)
+	if err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, errors.Wrapf(err, "failed to commit on accrue")
+	}
+
+	return true, nil
+}
+
+// Revoke marks a PROCESSED order as REVOKED and claws its accrual back
+// per the clawback policy in one transaction. Revoking a REVOKED order
+// again reports nil and changes nothing.
+func (r *OrdersRepoImpl) Revoke(
+	ctx context.Context,
+	record models.RevokeRecord,
+) (*models.Clawback, error) {
+	tx, err := r.repos.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to begin transaction")
+	}
+	defer rollback(tx)
+
+	from, err := lockStatuses(ctx, tx, goqu.C("number").Eq(record.Number))
+	if err != nil {
+		return nil, err
+	}
+	if len(from) == 0 {
+		return nil, exceptions.ErrOrderNotFound
+	}
+	for _, status := range from {
+		switch status {
+		case types.OrderProcessed.String():
+		case types.OrderRevoked.String():
+			return nil, nil
+		default:
+			return nil, exceptions.ErrOrderNotProcessed
+		}
+	}
+
+	qu, _, err := goqu.
+		Update(ordersTName).
+		Set(goqu.Record{"status": types.OrderRevoked.String()}).
+		Where(goqu.C("number").Eq(record.Number)).
 		Returning(
-			"balance_id",
+			"order_id",
 			"user_id",
-			"current",
-			"withdrawn",
-			"created_at",
-			"updated_at",
+			"number",
+			"status",
+			"accrual",
+			"uploaded_at",
 		).
 		ToSQL()
 	if err != nil {
-		return false, errors.Wrapf(err, "failed to build query")
+		return nil, errors.Wrapf(err, "failed to build query")
 	}
-	if _, err := tx.ExecContext(ctx, qu); err != nil {
-		return false, errors.Wrapf(err, "update balance error during execute query")
+
+	var order models.Order
+	if err := tx.QueryRowxContext(ctx, qu).StructScan(&order); err != nil {
+		return nil, errors.Wrapf(err, "failed to update order")
+	}
+
+	clawback, err := applyClawback(ctx, tx, r.repos.ClawbackPolicy, &order, record.Change.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	err = recordStatusChanges(ctx, tx, from, types.OrderRevoked, record.Change)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := tx.Commit(); err != nil {
-		return false, errors.Wrapf(err, "failed to commti on accrue")
+		return nil, errors.Wrapf(err, "failed to commit on revoke")
 	}
 
-	return true, nil
+	return clawback, nil
 }
 
 func (r *OrdersRepoImpl) UserOrders(
@@ -251,6 +582,13 @@ func (r *OrdersRepoImpl) UserOrders(
 		return nil, errors.Wrapf(err, "failed to build query")
 	}
 
+	return r.selectOrders(ctx, qu)
+}
+
+func (r *OrdersRepoImpl) selectOrders(
+	ctx context.Context,
+	qu string,
+) (*[]models.Order, error) {
 	rows, err := r.repos.DB.QueryxContext(ctx, qu)
 	if err != nil {
 		return nil, errors.Wrapf(err, "read orders error during querying")
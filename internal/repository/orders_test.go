@@ -0,0 +1,216 @@
+package repository
+
+import (
+	"context"
+	"gophermart/internal/bootstrap"
+	"gophermart/internal/config"
+	"gophermart/internal/models"
+	"gophermart/internal/types"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// setupRepos connects to the database from TEST_DATABASE_URI and applies migrations.
+// Tests that need a real database are skipped when it is not set.
+func setupRepos(t *testing.T) *Repos {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URI")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URI is not set")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	db, err := bootstrap.InitDB(ctx, &config.Postgres{
+		DSN:             dsn,
+		MaxOpenConn:     20,
+		IdleConn:        20,
+		PingInterval:    time.Minute,
+		MigrationFolder: "../../migrations",
+	})
+	if err != nil {
+		t.Fatalf("failed to init db: %s", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	repos, err := NewRepos(ctx, db)
+	if err != nil {
+		t.Fatalf("failed to init repos: %s", err)
+	}
+
+	return repos
+}
+
+var testOrderSeq atomic.Int64
+
+// newTestUser builds a user with a unique login starting with prefix.
+func newTestUser(t *testing.T, prefix string) *models.AuthUser {
+	t.Helper()
+
+	authUser, err := models.NewAuthUser(prefix+uuid.NewString(), "Password123")
+	if err != nil {
+		t.Fatalf("failed to build user: %s", err)
+	}
+
+	return authUser
+}
+
+// createTestUser creates a user with a unique login starting with prefix.
+func createTestUser(t *testing.T, repos *Repos, prefix string) *models.User {
+	t.Helper()
+
+	user, err := repos.UsersRepo.Create(context.Background(), newTestUser(t, prefix))
+	if err != nil {
+		t.Fatalf("failed to create user: %s", err)
+	}
+
+	return user
+}
+
+// createTestOrder uploads an order with a unique number for the user.
+func createTestOrder(t *testing.T, repos *Repos, userID string) *models.Order {
+	t.Helper()
+
+	number := strconv.FormatInt(time.Now().UnixNano()+testOrderSeq.Add(1), 10)
+	order, err := repos.OrdersRepo.Create(context.Background(), models.NewOrder(userID, number))
+	if err != nil {
+		t.Fatalf("failed to create order: %s", err)
+	}
+
+	return order
+}
+
+// accrueTestOrder uploads an order for the user and accrues amount for it.
+func accrueTestOrder(t *testing.T, repos *Repos, userID string, amount types.Money) *models.Order {
+	t.Helper()
+
+	order := createTestOrder(t, repos, userID)
+	_, err := repos.OrdersRepo.Accrue(context.Background(), models.AccrueRecord{
+		OrderID: order.ID,
+		UserID:  userID,
+		Number:  order.Number,
+		Amount:  amount,
+	})
+	if err != nil {
+		t.Fatalf("failed to accrue: %s", err)
+	}
+
+	return order
+}
+
+func TestOrdersRepoImpl_Accrue_ConcurrentReplay(t *testing.T) {
+	repos := setupRepos(t)
+	ctx := context.Background()
+
+	user := createTestUser(t, repos, "accrue-")
+	order := createTestOrder(t, repos, user.ID)
+
+	record := models.AccrueRecord{
+		OrderID: order.ID,
+		UserID:  user.ID,
+		Number:  order.Number,
+		Amount:  types.NewMoney(72998, -2),
+	}
+
+	const replays = 16
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		credited int
+	)
+	for i := 0; i < replays; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ok, err := repos.OrdersRepo.Accrue(ctx, record)
+			if err != nil {
+				t.Errorf("failed to accrue: %s", err)
+				return
+			}
+			if ok {
+				mu.Lock()
+				credited++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if credited != 1 {
+		t.Errorf("record credited %d times, want exactly once", credited)
+	}
+
+	balance, err := repos.BalanceRepo.GetOrCreateForUser(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("failed to get balance: %s", err)
+	}
+	if !balance.Current.Equal(record.Amount) {
+		t.Errorf("balance is different: got=%s want=%s", balance.Current, record.Amount)
+	}
+}
+
+func TestOrdersRepoImpl_StatusHistory(t *testing.T) {
+	repos := setupRepos(t)
+	ctx := context.Background()
+
+	user := createTestUser(t, repos, "history-")
+	order := createTestOrder(t, repos, user.ID)
+
+	poll := models.StatusChange{Source: types.SourcePoll}
+	for i := 0; i < 2; i++ {
+		if _, err := repos.OrdersRepo.MarkAsProcessing(ctx, []string{order.ID}, poll); err != nil {
+			t.Fatalf("failed to mark processing: %s", err)
+		}
+	}
+
+	_, err := repos.OrdersRepo.Accrue(ctx, models.AccrueRecord{
+		OrderID: order.ID,
+		UserID:  user.ID,
+		Number:  order.Number,
+		Amount:  types.MoneyFromInt(10),
+		Change:  models.StatusChange{Source: types.SourceWebhook},
+	})
+	if err != nil {
+		t.Fatalf("failed to accrue: %s", err)
+	}
+
+	// A late poll result must neither change the status nor the history.
+	if _, err := repos.OrdersRepo.MarkAsInvalid(ctx, []string{order.ID}, poll); err != nil {
+		t.Fatalf("failed to mark invalid: %s", err)
+	}
+
+	history, err := repos.OrderHistoryRepo.List(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("failed to get history: %s", err)
+	}
+
+	want := []struct {
+		status string
+		source types.StatusSource
+	}{
+		{types.OrderNew.String(), types.SourceUser},
+		{types.OrderProcessing.String(), types.SourcePoll},
+		{types.OrderProcessed.String(), types.SourceWebhook},
+	}
+	if len(*history) != len(want) {
+		t.Fatalf("history length is different: got=%d want=%d", len(*history), len(want))
+	}
+	for i, entry := range *history {
+		if entry.Status != want[i].status || entry.Source != want[i].source.String() {
+			t.Errorf(
+				"entry #%d is different: got=%s/%s want=%s/%s",
+				i, entry.Status, entry.Source, want[i].status, want[i].source,
+			)
+		}
+	}
+}
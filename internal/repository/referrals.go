@@ -0,0 +1,287 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"gophermart/internal/exceptions"
+	"gophermart/internal/models"
+	"gophermart/internal/types"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+type ReferralsRepoImpl struct {
+	repos *Repos
+}
+
+func NewReferralsRepo(repos *Repos) *ReferralsRepoImpl {
+	return &ReferralsRepoImpl{repos: repos}
+}
+
+// UserReferrals returns the referral code of the user with the users who
+// signed up with it, newest first, and the bonuses earned for them.
+func (r *ReferralsRepoImpl) UserReferrals(
+	ctx context.Context,
+	userID string,
+	limit int,
+	offset int,
+) (*models.ReferralsRead, error) {
+	qu, _, err := goqu.
+		Select("referral_code").
+		From(usersTName).
+		Where(goqu.C("user_id").Eq(userID)).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	read := models.ReferralsRead{Referrals: []models.Referral{}}
+	if err := r.repos.DB.GetContext(ctx, &read.ReferralCode, qu); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, exceptions.ErrUserNotFound
+		}
+		return nil, errors.Wrapf(err, "failed to get referral code")
+	}
+
+	qu, _, err = goqu.
+		Select(goqu.L("COALESCE(SUM(bonus), 0)")).
+		From(referralsTName).
+		Where(
+			goqu.C("referrer_id").Eq(userID),
+			goqu.C("status").Eq(types.ReferralRewarded.String()),
+		).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	if err := r.repos.DB.GetContext(ctx, &read.Earned, qu); err != nil {
+		return nil, errors.Wrapf(err, "failed to sum referral bonuses")
+	}
+
+	qu, _, err = goqu.
+		Select(
+			goqu.I("r.referral_id"),
+			goqu.I("r.referrer_id"),
+			goqu.I("r.referee_id"),
+			goqu.I("u.login"),
+			goqu.I("r.status"),
+			goqu.I("r.order_id"),
+			goqu.I("r.bonus"),
+			goqu.I("r.created_at"),
+			goqu.I("r.settled_at"),
+		).
+		From(goqu.T(referralsTName).As("r")).
+		Join(goqu.T(usersTName).As("u"), goqu.On(goqu.I("u.user_id").Eq(goqu.I("r.referee_id")))).
+		Where(goqu.I("r.referrer_id").Eq(userID)).
+		Order(goqu.I("r.created_at").Desc(), goqu.I("r.referral_id").Asc()).
+		Limit(uint(limit)).
+		Offset(uint(offset)).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	if err := r.repos.DB.SelectContext(ctx, &read.Referrals, qu); err != nil {
+		return nil, errors.Wrapf(err, "failed to select referrals")
+	}
+
+	return &read, nil
+}
+
+// createReferral links the new user to the owner of referralCode. It
+// fails with ErrReferralNotAllowed when terms refuse the code now. The
+// referrer row lock serializes signups with one code, so they can't exceed
+// the daily limit between them.
+func createReferral(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	terms models.ReferralTerms,
+	refereeID string,
+	referralCode string,
+) error {
+	qu, _, err := goqu.
+		Select(
+			goqu.C("user_id"),
+			goqu.C("created_at").Gt(nowUTCPlus(-terms.MinReferrerAge)).As("young"),
+		).
+		From(usersTName).
+		Where(
+			goqu.C("referral_code").Eq(referralCode),
+			goqu.C("deleted_at").IsNull(),
+		).
+		ForUpdate(exp.Wait).
+		ToSQL()
+	if err != nil {
+		return errors.Wrapf(err, "failed to build query")
+	}
+
+	var referrer struct {
+		ID    string `db:"user_id"`
+		Young bool   `db:"young"`
+	}
+	if err := tx.GetContext(ctx, &referrer, qu); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return exceptions.ErrReferralCodeNotFound
+		}
+		return errors.Wrapf(err, "failed to get referrer")
+	}
+
+	if terms.MinReferrerAge > 0 && referrer.Young {
+		return exceptions.ErrReferralNotAllowed
+	}
+
+	if terms.DailySignups > 0 {
+		qu, _, err = goqu.
+			Select(goqu.COUNT("*")).
+			From(referralsTName).
+			Where(
+				goqu.C("referrer_id").Eq(referrer.ID),
+				goqu.C("created_at").Gte(nowUTCPlus(-24*time.Hour)),
+			).
+			ToSQL()
+		if err != nil {
+			return errors.Wrapf(err, "failed to build query")
+		}
+
+		var signups int
+		if err := tx.GetContext(ctx, &signups, qu); err != nil {
+			return errors.Wrapf(err, "failed to count referrals")
+		}
+
+		if signups >= terms.DailySignups {
+			return exceptions.ErrReferralNotAllowed
+		}
+	}
+
+	qu, _, err = goqu.
+		Insert(referralsTName).
+		Rows(goqu.Record{
+			"referrer_id": referrer.ID,
+			"referee_id":  refereeID,
+			"status":      types.ReferralPending.String(),
+			"created_at":  nowUTC,
+		}).
+		ToSQL()
+	if err != nil {
+		return errors.Wrapf(err, "failed to build query")
+	}
+
+	if _, err := tx.ExecContext(ctx, qu); err != nil {
+		return errors.Wrapf(err, "failed to insert referral")
+	}
+
+	return nil
+}
+
+// applyReferral settles the pending referral of the order owner on their
+// first order credited with points. Both parties get terms.Bonus unless
+// the referrer has already been rewarded terms.MaxRewards times, then the
+// referral is rejected. Either way it happens once, as the referral is no
+// longer pending afterwards.
+func applyReferral(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	terms models.ReferralTerms,
+	order *models.Order,
+	amount types.Money,
+) error {
+	if !terms.Bonus.IsPositive() || !amount.IsPositive() {
+		return nil
+	}
+
+	qu, _, err := goqu.
+		Select("referral_id", "referrer_id").
+		From(referralsTName).
+		Where(
+			goqu.C("referee_id").Eq(order.UserID),
+			goqu.C("status").Eq(types.ReferralPending.String()),
+		).
+		ToSQL()
+	if err != nil {
+		return errors.Wrapf(err, "failed to build query")
+	}
+
+	var referral models.Referral
+	if err := tx.GetContext(ctx, &referral, qu); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return errors.Wrapf(err, "failed to get referral")
+	}
+
+	// Referrals of the referrer are locked altogether, so referees accrued
+	// at the same time can't exceed the cap between them.
+	qu, _, err = goqu.
+		Select("referral_id", "status").
+		From(referralsTName).
+		Where(goqu.C("referrer_id").Eq(referral.ReferrerID)).
+		Order(goqu.I("referral_id").Asc()).
+		ForUpdate(goqu.Wait).
+		ToSQL()
+	if err != nil {
+		return errors.Wrapf(err, "failed to build query")
+	}
+
+	var referrals []models.Referral
+	if err := tx.SelectContext(ctx, &referrals, qu); err != nil {
+		return errors.Wrapf(err, "failed to lock referrals")
+	}
+
+	rewarded := 0
+	for _, r := range referrals {
+		switch {
+		case r.ID == referral.ID && r.Status != types.ReferralPending.String():
+			// Settled by a concurrent accrual.
+			return nil
+		case r.Status == types.ReferralRewarded.String():
+			rewarded++
+		}
+	}
+
+	status := types.ReferralRewarded
+	if terms.MaxRewards > 0 && rewarded >= terms.MaxRewards {
+		status = types.ReferralRejected
+	}
+
+	record := goqu.Record{
+		"status":     status.String(),
+		"order_id":   order.ID,
+		"settled_at": nowUTC,
+	}
+	if status == types.ReferralRewarded {
+		record["bonus"] = terms.Bonus
+	}
+
+	qu, _, err = goqu.
+		Update(referralsTName).
+		Set(record).
+		Where(goqu.C("referral_id").Eq(referral.ID)).
+		ToSQL()
+	if err != nil {
+		return errors.Wrapf(err, "failed to build query")
+	}
+
+	if _, err := tx.ExecContext(ctx, qu); err != nil {
+		return errors.Wrapf(err, "failed to settle referral")
+	}
+
+	if status != types.ReferralRewarded {
+		return nil
+	}
+
+	_, err = ledgerPost(
+		ctx,
+		tx,
+		models.NewReferralBonusTransaction(referral.ID, referral.ReferrerID, order.UserID, terms.Bonus),
+	)
+	if err != nil {
+		return errors.Wrapf(err, "failed to post referral bonus")
+	}
+
+	return nil
+}
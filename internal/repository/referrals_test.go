@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"gophermart/internal/exceptions"
+	"gophermart/internal/models"
+	"gophermart/internal/types"
+	"testing"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/pkg/errors"
+)
+
+func TestReferralsRepoImpl_Rewards(t *testing.T) {
+	repos := setupRepos(t)
+	ctx := context.Background()
+
+	repos.Referrals = models.ReferralTerms{Bonus: types.MoneyFromInt(10), MaxRewards: 1}
+
+	createUser := func(referralCode string) (*models.User, error) {
+		authUser := newTestUser(t, "referrals-")
+		authUser.ReferredWith = referralCode
+		return repos.UsersRepo.Create(ctx, authUser)
+	}
+	accrue := func(userID string) {
+		accrueTestOrder(t, repos, userID, types.MoneyFromInt(100))
+	}
+	current := func(userID string) types.Money {
+		balance, err := repos.BalanceRepo.GetOrCreateForUser(ctx, userID)
+		if err != nil {
+			t.Fatalf("failed to get balance: %s", err)
+		}
+		return balance.Current
+	}
+
+	if _, err := createUser("UNKNOWN0"); !errors.Is(err, exceptions.ErrReferralCodeNotFound) {
+		t.Errorf("error is different: got=%v want=%v", err, exceptions.ErrReferralCodeNotFound)
+	}
+
+	referrer, err := createUser("")
+	if err != nil {
+		t.Fatalf("failed to create referrer: %s", err)
+	}
+	referee, err := createUser(referrer.ReferralCode)
+	if err != nil {
+		t.Fatalf("failed to create referee: %s", err)
+	}
+	late, err := createUser(referrer.ReferralCode)
+	if err != nil {
+		t.Fatalf("failed to create referee: %s", err)
+	}
+
+	// Only the first order pays, the second referee is over the cap.
+	accrue(referee.ID)
+	accrue(referee.ID)
+	accrue(late.ID)
+
+	if got := current(referrer.ID); !got.Equal(types.MoneyFromInt(10)) {
+		t.Errorf("referrer current is different: got=%s want=10", got)
+	}
+	if got := current(referee.ID); !got.Equal(types.MoneyFromInt(210)) {
+		t.Errorf("referee current is different: got=%s want=210", got)
+	}
+	if got := current(late.ID); !got.Equal(types.MoneyFromInt(100)) {
+		t.Errorf("late referee current is different: got=%s want=100", got)
+	}
+
+	read, err := repos.ReferralsRepo.UserReferrals(ctx, referrer.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("failed to get referrals: %s", err)
+	}
+	if !read.Earned.Equal(types.MoneyFromInt(10)) {
+		t.Errorf("earned is different: got=%s want=10", read.Earned)
+	}
+	statuses := map[string]string{}
+	for _, referral := range read.Referrals {
+		statuses[referral.Login] = referral.Status
+	}
+	if statuses[referee.Login] != types.ReferralRewarded.String() {
+		t.Errorf("referee status is different: got=%s want=%s", statuses[referee.Login], types.ReferralRewarded)
+	}
+	if statuses[late.Login] != types.ReferralRejected.String() {
+		t.Errorf("late referee status is different: got=%s want=%s", statuses[late.Login], types.ReferralRejected)
+	}
+}
+
+func TestReferralsRepoImpl_Guards(t *testing.T) {
+	repos := setupRepos(t)
+	ctx := context.Background()
+
+	repos.Referrals = models.ReferralTerms{
+		Bonus:          types.MoneyFromInt(10),
+		MinReferrerAge: time.Hour,
+		DailySignups:   1,
+	}
+
+	createUser := func(referralCode string) (*models.User, error) {
+		authUser := newTestUser(t, "referrals-")
+		authUser.ReferredWith = referralCode
+		return repos.UsersRepo.Create(ctx, authUser)
+	}
+
+	referrer, err := createUser("")
+	if err != nil {
+		t.Fatalf("failed to create referrer: %s", err)
+	}
+
+	// A second account made right away can't use the code.
+	if _, err := createUser(referrer.ReferralCode); !errors.Is(err, exceptions.ErrReferralNotAllowed) {
+		t.Errorf("error is different: got=%v want=%v", err, exceptions.ErrReferralNotAllowed)
+	}
+
+	qu, _, err := goqu.
+		Update(usersTName).
+		Set(goqu.Record{"created_at": nowUTCPlus(-2 * time.Hour)}).
+		Where(goqu.C("user_id").Eq(referrer.ID)).
+		ToSQL()
+	if err != nil {
+		t.Fatalf("failed to build query: %s", err)
+	}
+	if _, err := repos.DB.ExecContext(ctx, qu); err != nil {
+		t.Fatalf("failed to age referrer: %s", err)
+	}
+
+	if _, err := createUser(referrer.ReferralCode); err != nil {
+		t.Fatalf("failed to create referee: %s", err)
+	}
+
+	// The code has signed up as many users today as it may.
+	if _, err := createUser(referrer.ReferralCode); !errors.Is(err, exceptions.ErrReferralNotAllowed) {
+		t.Errorf("error is different: got=%v want=%v", err, exceptions.ErrReferralNotAllowed)
+	}
+}
@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"gophermart/internal/exceptions"
+	"gophermart/internal/log"
+	"gophermart/internal/models"
+	"gophermart/internal/types"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+type TransfersRepoImpl struct {
+	repos *Repos
+}
+
+func NewTransfersRepo(repos *Repos) *TransfersRepoImpl {
+	return &TransfersRepoImpl{repos: repos}
+}
+
+// Create moves the transfer sum from the sender to the user with
+// recipientLogin in one transaction. The sender may not go below zero
+// nor above limits, which are checked under the sender balance lock.
+func (r *TransfersRepoImpl) Create(
+	ctx context.Context,
+	model *models.Transfer,
+	recipientLogin string,
+	limits models.TransferLimits,
+) (*models.Transfer, error) {
+	tx, err := r.repos.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to begin transaction")
+	}
+	defer rollback(tx)
+
+	qu, _, err := goqu.
+		Select("user_id").
+		From(usersTName).
+		Where(
+			goqu.C("login").Eq(recipientLogin),
+			goqu.C("deleted_at").IsNull(),
+		).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	err = tx.GetContext(ctx, &model.RecipientID, qu)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, exceptions.ErrRecipientNotFound
+		}
+		return nil, errors.Wrapf(err, "failed to get recipient")
+	}
+
+	if model.RecipientID == model.SenderID {
+		return nil, exceptions.ErrTransferToSelf
+	}
+
+	// Balances are locked in user_id order, so opposite transfers
+	// between the same users don't deadlock.
+	balances, err := ledgerPost(
+		ctx,
+		tx,
+		models.NewTransferTransaction(model.SenderID, model.RecipientID, model.ID, model.Sum),
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to post transfer")
+	}
+
+	if balances[model.SenderID].Current.IsNegative() {
+		return nil, exceptions.ErrBalanceIsNegative
+	}
+
+	if err := checkWithdrawalsAllowed(ctx, tx, model.SenderID); err != nil {
+		return nil, err
+	}
+
+	if err := checkTransferLimits(ctx, tx, model, limits); err != nil {
+		return nil, err
+	}
+
+	model.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	qu, _, err = goqu.
+		Insert(transfersTName).
+		Rows(model).
+		Returning(&models.Transfer{}).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	var transfer models.Transfer
+	err = tx.QueryRowxContext(ctx, qu).StructScan(&transfer)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to insert transfer")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrapf(err, "failed to commit")
+	}
+
+	return &transfer, nil
+}
+
+// UserTransfers returns transfers the user sent or received, newest first.
+func (r *TransfersRepoImpl) UserTransfers(
+	ctx context.Context,
+	userID string,
+	limit int,
+	offset int,
+) (*[]models.TransferRead, error) {
+	sent := goqu.
+		Select(
+			goqu.I("t.transfer_id"),
+			goqu.V(types.TransferOut.String()).As("direction"),
+			goqu.I("u.login").As("counterparty"),
+			goqu.I("t.sum"),
+			goqu.I("t.created_at"),
+		).
+		From(goqu.T(transfersTName).As("t")).
+		Join(goqu.T(usersTName).As("u"), goqu.On(goqu.I("u.user_id").Eq(goqu.I("t.recipient_id")))).
+		Where(goqu.I("t.sender_id").Eq(userID))
+
+	received := goqu.
+		Select(
+			goqu.I("t.transfer_id"),
+			goqu.V(types.TransferIn.String()).As("direction"),
+			goqu.I("u.login").As("counterparty"),
+			goqu.I("t.sum"),
+			goqu.I("t.created_at"),
+		).
+		From(goqu.T(transfersTName).As("t")).
+		Join(goqu.T(usersTName).As("u"), goqu.On(goqu.I("u.user_id").Eq(goqu.I("t.sender_id")))).
+		Where(goqu.I("t.recipient_id").Eq(userID))
+
+	qu, _, err := goqu.
+		From(sent.UnionAll(received).As("transfers")).
+		Order(goqu.I("created_at").Desc(), goqu.I("transfer_id").Asc()).
+		Limit(uint(limit)).
+		Offset(uint(offset)).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	rows, err := r.repos.DB.QueryxContext(ctx, qu)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read transfers error during querying")
+	}
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			log.Error(ctx, "failed to close rows", err)
+		}
+	}()
+
+	transfers := []models.TransferRead{}
+	for rows.Next() {
+		transfer := models.TransferRead{}
+		err := rows.StructScan(&transfer)
+		if err != nil {
+			return nil, errors.Wrapf(err, "read transfers error during scan rows")
+		}
+		transfers = append(transfers, transfer)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrapf(err, "read transfers error during querying")
+	}
+
+	return &transfers, nil
+}
+
+func checkTransferLimits(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	model *models.Transfer,
+	limits models.TransferLimits,
+) error {
+	if limits.DailySum.IsZero() && limits.DailyCount == 0 {
+		return nil
+	}
+
+	qu, _, err := goqu.
+		Select(
+			goqu.COUNT("*").As("count"),
+			goqu.L("COALESCE(SUM(sum), 0)").As("sum"),
+		).
+		From(transfersTName).
+		Where(
+			goqu.C("sender_id").Eq(model.SenderID),
+			goqu.C("created_at").Gte(goqu.L("date_trunc('day', (now() AT TIME ZONE 'UTC'))")),
+		).
+		ToSQL()
+	if err != nil {
+		return errors.Wrapf(err, "failed to build query")
+	}
+
+	var sent struct {
+		Count int         `db:"count"`
+		Sum   types.Money `db:"sum"`
+	}
+	if err := tx.GetContext(ctx, &sent, qu); err != nil {
+		return errors.Wrapf(err, "failed to sum transfers of the day")
+	}
+
+	if limits.DailyCount > 0 && sent.Count+1 > limits.DailyCount {
+		return exceptions.ErrTransferLimitExceeded
+	}
+	if limits.DailySum.IsPositive() && sent.Sum.Add(model.Sum).GreaterThan(limits.DailySum) {
+		return exceptions.ErrTransferLimitExceeded
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"gophermart/internal/exceptions"
+	"gophermart/internal/models"
+	"gophermart/internal/types"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+func TestTransfersRepoImpl_Create(t *testing.T) {
+	repos := setupRepos(t)
+	ctx := context.Background()
+
+	sender := createTestUser(t, repos, "transfer-")
+	recipient := createTestUser(t, repos, "transfer-")
+	accrueTestOrder(t, repos, sender.ID, types.MoneyFromInt(100))
+
+	limits := models.TransferLimits{DailySum: types.MoneyFromInt(50), DailyCount: 5}
+
+	tests := []struct {
+		name    string
+		login   string
+		sum     types.Money
+		wantErr error
+	}{
+		{"Test #1 Success", recipient.Login, types.MoneyFromInt(30), nil},
+		{"Test #2 Unknown recipient", "unknown-" + uuid.NewString(), types.MoneyFromInt(1), exceptions.ErrRecipientNotFound},
+		{"Test #3 To oneself", sender.Login, types.MoneyFromInt(1), exceptions.ErrTransferToSelf},
+		{"Test #4 Daily sum", recipient.Login, types.MoneyFromInt(30), exceptions.ErrTransferLimitExceeded},
+		{"Test #5 Overdraft", recipient.Login, types.MoneyFromInt(500), exceptions.ErrBalanceIsNegative},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := repos.TransfersRepo.Create(ctx, models.NewTransfer(sender.ID, tt.sum), tt.login, limits)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error is different: got=%v want=%v", err, tt.wantErr)
+			}
+		})
+	}
+
+	for _, tt := range []struct {
+		user      *models.User
+		current   types.Money
+		direction types.TransferDirection
+	}{
+		{sender, types.MoneyFromInt(70), types.TransferOut},
+		{recipient, types.MoneyFromInt(30), types.TransferIn},
+	} {
+		balance, err := repos.BalanceRepo.GetOrCreateForUser(ctx, tt.user.ID)
+		if err != nil {
+			t.Fatalf("failed to get balance: %s", err)
+		}
+		if !balance.Current.Equal(tt.current) {
+			t.Errorf("current is different: got=%s want=%s", balance.Current, tt.current)
+		}
+
+		transfers, err := repos.TransfersRepo.UserTransfers(ctx, tt.user.ID, models.MaxPageLimit, 0)
+		if err != nil {
+			t.Fatalf("failed to get transfers: %s", err)
+		}
+		if len(*transfers) != 1 || (*transfers)[0].Direction != tt.direction.String() {
+			t.Errorf("transfers are different: %+v", *transfers)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"gophermart/internal/exceptions"
 	"gophermart/internal/models"
 
 	"github.com/doug-martin/goqu/v9"
@@ -16,16 +18,25 @@ func NewUsersRepo(repos *Repos) *UsersRepoImpl {
 	return &UsersRepoImpl{repos: repos}
 }
 
+// Create inserts the user and, when they signed up with a referral code,
+// links them to its owner in the same transaction.
 func (r *UsersRepoImpl) Create(
 	ctx context.Context,
 	model *models.AuthUser,
 ) (*models.User, error) {
+	tx, err := r.repos.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to begin transaction")
+	}
+	defer rollback(tx)
+
 	qu, _, err := goqu.
 		Insert(usersTName).
 		Rows(model).
 		Returning(
 			"user_id",
 			"login",
+			"referral_code",
 			"created_at",
 			"updated_at",
 			"deleted_at",
@@ -36,10 +47,48 @@ func (r *UsersRepoImpl) Create(
 	}
 
 	var user models.User
-	err = r.repos.DB.QueryRowxContext(ctx, qu).StructScan(&user)
+	err = tx.QueryRowxContext(ctx, qu).StructScan(&user)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to insert")
 	}
 
+	if model.ReferredWith != "" {
+		if err := createReferral(ctx, tx, r.repos.Referrals, user.ID, model.ReferredWith); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrapf(err, "failed to commit")
+	}
+
 	return &user, nil
 }
+
+func (r *UsersRepoImpl) IsAdmin(
+	ctx context.Context,
+	userID string,
+) (bool, error) {
+	qu, _, err := goqu.
+		Select(goqu.C("is_admin")).
+		From(usersTName).
+		Where(
+			goqu.C("user_id").Eq(userID),
+			goqu.C("deleted_at").IsNull(),
+		).
+		ToSQL()
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to build query")
+	}
+
+	var isAdmin bool
+	err = r.repos.DB.QueryRowxContext(ctx, qu).Scan(&isAdmin)
+	switch {
+	case err == nil:
+		return isAdmin, nil
+	case errors.Is(err, sql.ErrNoRows):
+		return false, exceptions.ErrUserNotFound
+	default:
+		return false, errors.Wrapf(err, "failed to query database")
+	}
+}
@@ -2,13 +2,18 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"gophermart/internal/exceptions"
 	"gophermart/internal/log"
 	"gophermart/internal/models"
+	"gophermart/internal/types"
+	"gophermart/internal/withdrawals"
 	"time"
 
 	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
 )
 
@@ -20,6 +25,11 @@ func NewWithdrawalsRepo(repos *Repos) *WithdrawlsRepoImpl {
 	return &WithdrawlsRepoImpl{repos: repos}
 }
 
+// Create posts the withdrawal and its fee in one transaction. The daily
+// and monthly caps of the withdrawal policy are checked under the balance
+// lock, so concurrent withdrawals can't exceed them between them.
+// Withdrawals the policy wants reviewed only hold the sum and the fee
+// and stay PENDING_REVIEW until Review.
 func (r *WithdrawlsRepoImpl) Create(
 	ctx context.Context,
 	model *models.Withdrawal,
@@ -29,83 +39,137 @@ func (r *WithdrawlsRepoImpl) Create(
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to begin transaction")
 	}
-	defer func() {
-		if err := tx.Rollback(); err != nil {
-			log.Debug(context.Background(), fmt.Sprintf("failed to rollback: %s", err))
+	defer rollback(tx)
+
+	policy := &r.repos.WithdrawalPolicy
+	model.Fee = policy.Fee(model.Sum)
+
+	lots := models.WithdrawalLots(model.ID)
+	txns := []*models.LedgerTransaction{
+		models.NewWithdrawalTransaction(model.UserID, model.Order, lots, model.Sum),
+	}
+	if model.Fee.IsPositive() {
+		txns = append(txns, models.NewWithdrawalFeeTransaction(model.UserID, model.Order, lots, model.Fee))
+	}
+	if policy.NeedsReview(model.Sum) {
+		model.Status = types.WithdrawalPendingReview.String()
+		txns = []*models.LedgerTransaction{
+			models.NewHoldTransaction(model.UserID, model.Order, lots, model.Sum.Add(model.Fee)),
 		}
-	}()
+	}
 
 	// Making balance update
-	qu, _, err := goqu.
-		Update(balanceTName).
-		Set(
-			map[string]interface{}{
-				"current":   goqu.L(fmt.Sprintf("current - %f", model.Sum)),
-				"withdrawn": goqu.L(fmt.Sprintf("withdrawn + %f", model.Sum)),
-			},
-		).
-		Where(goqu.C("user_id").Eq(model.UserID)).
-		Returning(
-			"balance_id",
-			"user_id",
-			"current",
-			"withdrawn",
-			"created_at",
-			"updated_at",
-		).
-		ToSQL()
+	var balances map[string]*models.Balance
+	for _, txn := range txns {
+		balances, err = ledgerPost(ctx, tx, txn)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to post %s", txn.Kind)
+		}
+	}
+
+	if balances[model.UserID].Current.IsNegative() {
+		return nil, exceptions.ErrBalanceIsNegative
+	}
+
+	if err := checkWithdrawalsAllowed(ctx, tx, model.UserID); err != nil {
+		return nil, err
+	}
+
+	if err := checkWithdrawalUsage(ctx, tx, policy, model.UserID, model.Sum); err != nil {
+		return nil, err
+	}
+
+	// Inserting withdrawal
+	withdrawal, err := insertWithdrawal(ctx, tx, model)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to build query")
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to commit")
 	}
 
-	row := tx.QueryRowxContext(ctx, qu)
-	if row.Err() != nil {
-		return nil, errors.Wrapf(row.Err(), "update balance error during execute query")
+	return withdrawal, nil
+}
+
+// checkWithdrawalUsage applies the daily and monthly caps of policy to a
+// withdrawal of sum. It must run under the balance lock of the user.
+func checkWithdrawalUsage(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	policy *withdrawals.Policy,
+	userID string,
+	sum types.Money,
+) error {
+	if !policy.HasCaps() {
+		return nil
 	}
 
-	var balance models.Balance
-	err = row.StructScan(&balance)
+	usage, err := withdrawalUsage(ctx, tx, userID)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to scan balance")
+		return err
 	}
 
-	if balance.Current < 0 {
-		err := tx.Rollback()
-		if err != nil {
-			return nil, errors.Wrapf(err, "failed to rollback transaction")
-		}
+	return policy.CheckUsage(sum, usage)
+}
 
-		return nil, exceptions.ErrBalanceIsNegative
+// withdrawalUsage sums what the user has withdrawn in the current UTC day
+// and month. Pending withdrawals count, rejected ones and reversed sums
+// don't.
+func withdrawalUsage(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	userID string,
+) (withdrawals.Usage, error) {
+	qu, _, err := goqu.
+		Select(
+			goqu.L("COALESCE(SUM(sum - reversed) FILTER (WHERE processed_at >= date_trunc('day', (now() AT TIME ZONE 'UTC'))), 0)").As("daily"),
+			goqu.L("COALESCE(SUM(sum - reversed), 0)").As("monthly"),
+		).
+		From(withdrawalsTName).
+		Where(
+			goqu.C("user_id").Eq(userID),
+			goqu.C("status").Neq(types.WithdrawalRejected.String()),
+			goqu.C("processed_at").Gte(goqu.L("date_trunc('month', (now() AT TIME ZONE 'UTC'))")),
+		).
+		ToSQL()
+	if err != nil {
+		return withdrawals.Usage{}, errors.Wrapf(err, "failed to build query")
 	}
 
-	// Inserting withdrawal
+	var usage struct {
+		Daily   types.Money `db:"daily"`
+		Monthly types.Money `db:"monthly"`
+	}
+	if err := tx.GetContext(ctx, &usage, qu); err != nil {
+		return withdrawals.Usage{}, errors.Wrapf(err, "failed to sum withdrawals")
+	}
+
+	return withdrawals.Usage{Daily: usage.Daily, Monthly: usage.Monthly}, nil
+}
+
+func insertWithdrawal(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	model *models.Withdrawal,
+) (*models.Withdrawal, error) {
 	model.ProcessedAt = time.Now().UTC().Format(time.RFC3339)
-	qu, _, err = goqu.
+	qu, _, err := goqu.
 		Insert(withdrawalsTName).
 		Rows(model).
-		Returning(
-			"withdrawal_id",
-			"user_id",
-			"order",
-			"sum",
-			"processed_at",
-		).
+		Returning(&models.Withdrawal{}).
 		ToSQL()
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to build query")
 	}
 
 	var withdrawal models.Withdrawal
-	err = r.repos.DB.QueryRowxContext(ctx, qu).StructScan(&withdrawal)
+	err = tx.QueryRowxContext(ctx, qu).StructScan(&withdrawal)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to insert")
 	}
 
-	err = tx.Commit()
-	if err != nil {
-		return nil, errors.Wrapf(err, "failed to commit")
-	}
-
 	return &withdrawal, nil
 }
 
@@ -148,5 +212,318 @@ func (r *WithdrawlsRepoImpl) UserWithdrawals(
 		}
 	}()
 
+	reversals, err := selectReversals(ctx, r.repos.DB, goqu.C("user_id").Eq(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	byWithdrawal := make(map[string][]models.WithdrawalReversal)
+	for _, reversal := range reversals {
+		byWithdrawal[reversal.WithdrawalID] = append(byWithdrawal[reversal.WithdrawalID], reversal)
+	}
+	for i := range withdrawals {
+		withdrawals[i].Reversals = byWithdrawal[withdrawals[i].ID]
+	}
+
 	return &withdrawals, nil
 }
+
+// Reverse returns reversal.Sum of a withdrawal to the user, or what is left
+// of it when the sum is zero, and posts it to the ledger in one transaction.
+// The fee is refunded once the withdrawal is reversed in full.
+// ownerID restricts the reversal to withdrawals of that user and window,
+// if positive, to withdrawals made within it; admins pass neither.
+func (r *WithdrawlsRepoImpl) Reverse(
+	ctx context.Context,
+	reversal *models.WithdrawalReversal,
+	ownerID string,
+	window time.Duration,
+) (*models.Withdrawal, error) {
+	tx, err := r.repos.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to begin transaction")
+	}
+	defer rollback(tx)
+
+	where := []exp.Expression{goqu.C("withdrawal_id").Eq(reversal.WithdrawalID)}
+	if ownerID != "" {
+		where = append(where, goqu.C("user_id").Eq(ownerID))
+	}
+
+	qu, _, err := goqu.
+		Select(&models.Withdrawal{}).
+		From(withdrawalsTName).
+		Where(where...).
+		ForUpdate(exp.Wait).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	var withdrawal models.Withdrawal
+	err = tx.QueryRowxContext(ctx, qu).StructScan(&withdrawal)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, exceptions.ErrWithdrawalNotFound
+		}
+		return nil, errors.Wrapf(err, "failed to get withdrawal")
+	}
+
+	if window > 0 {
+		processedAt, err := time.Parse(time.RFC3339Nano, withdrawal.ProcessedAt)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse processed_at")
+		}
+		if time.Since(processedAt) > window {
+			return nil, exceptions.ErrReversalWindowExpired
+		}
+	}
+
+	reversible := withdrawal.Reversible()
+	if !reversible.IsPositive() {
+		return nil, exceptions.ErrWithdrawalAlreadyReversed
+	}
+	if reversal.Sum.IsZero() {
+		reversal.Sum = reversible
+	}
+	if reversal.Sum.GreaterThan(reversible) {
+		return nil, exceptions.ErrReversalExceedsWithdrawal
+	}
+
+	status := types.WithdrawalPartiallyReversed
+	if reversal.Sum.Equal(reversible) {
+		status = types.WithdrawalReversed
+	}
+	if !withdrawals.CanTransition(types.WithdrawalStatus(withdrawal.Status), status) {
+		return nil, exceptions.ErrWithdrawalIsNotSettled
+	}
+
+	qu, _, err = goqu.
+		Update(withdrawalsTName).
+		Set(
+			map[string]interface{}{
+				"status":   status.String(),
+				"reversed": goqu.L("reversed + ?", reversal.Sum),
+			},
+		).
+		Where(goqu.C("withdrawal_id").Eq(withdrawal.ID)).
+		Returning(&models.Withdrawal{}).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	err = tx.QueryRowxContext(ctx, qu).StructScan(&withdrawal)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to update withdrawal")
+	}
+
+	reversal.UserID = withdrawal.UserID
+	reversal.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	qu, _, err = goqu.
+		Insert(reversalsTName).
+		Rows(reversal).
+		Returning(&models.WithdrawalReversal{}).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	var stored models.WithdrawalReversal
+	err = tx.QueryRowxContext(ctx, qu).StructScan(&stored)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to insert reversal")
+	}
+
+	_, err = ledgerPost(
+		ctx,
+		tx,
+		models.NewReversalTransaction(withdrawal.UserID, withdrawal.Order, models.WithdrawalLots(withdrawal.ID), reversal.Sum),
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to post reversal")
+	}
+
+	if status == types.WithdrawalReversed && withdrawal.Fee.IsPositive() {
+		_, err = ledgerPost(
+			ctx,
+			tx,
+			models.NewFeeRefundTransaction(withdrawal.UserID, withdrawal.Order, models.WithdrawalLots(withdrawal.ID), withdrawal.Fee),
+		)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to post fee refund")
+		}
+	}
+
+	reversals, err := selectReversals(ctx, tx, goqu.C("withdrawal_id").Eq(withdrawal.ID))
+	if err != nil {
+		return nil, err
+	}
+	withdrawal.Reversals = reversals
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrapf(err, "failed to commit")
+	}
+
+	return &withdrawal, nil
+}
+
+// PendingReviews returns withdrawals waiting for review, oldest first.
+func (r *WithdrawlsRepoImpl) PendingReviews(
+	ctx context.Context,
+	limit int,
+	offset int,
+) (*[]models.Withdrawal, error) {
+	qu, _, err := goqu.
+		Select(&models.Withdrawal{}).
+		From(withdrawalsTName).
+		Where(goqu.C("status").Eq(types.WithdrawalPendingReview.String())).
+		Order(goqu.I("processed_at").Asc(), goqu.I("withdrawal_id").Asc()).
+		Limit(uint(limit)).
+		Offset(uint(offset)).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	pending := []models.Withdrawal{}
+	if err := r.repos.DB.SelectContext(ctx, &pending, qu); err != nil {
+		return nil, errors.Wrapf(err, "failed to select pending withdrawals")
+	}
+
+	return &pending, nil
+}
+
+// Review settles a PENDING_REVIEW withdrawal, spending its held sum and
+// fee, or rejects it, returning them to the user. A settled withdrawal is
+// processed at approval, so the reversal window and the caps count from it.
+func (r *WithdrawlsRepoImpl) Review(
+	ctx context.Context,
+	review *models.WithdrawalReview,
+) (*models.Withdrawal, error) {
+	tx, err := r.repos.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to begin transaction")
+	}
+	defer rollback(tx)
+
+	qu, _, err := goqu.
+		Select(&models.Withdrawal{}).
+		From(withdrawalsTName).
+		Where(goqu.C("withdrawal_id").Eq(review.WithdrawalID)).
+		ForUpdate(exp.Wait).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	var withdrawal models.Withdrawal
+	err = tx.QueryRowxContext(ctx, qu).StructScan(&withdrawal)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, exceptions.ErrWithdrawalNotFound
+		}
+		return nil, errors.Wrapf(err, "failed to get withdrawal")
+	}
+
+	if !withdrawal.IsPendingReview() ||
+		!withdrawals.CanTransition(types.WithdrawalStatus(withdrawal.Status), review.Status) {
+		return nil, exceptions.ErrWithdrawalIsNotPending
+	}
+
+	txns := []*models.LedgerTransaction{
+		models.NewReleaseTransaction(
+			withdrawal.UserID,
+			withdrawal.Order,
+			models.WithdrawalLots(withdrawal.ID),
+			withdrawal.Sum.Add(withdrawal.Fee),
+		),
+	}
+	if review.Status == types.WithdrawalCompleted {
+		txns = []*models.LedgerTransaction{
+			models.NewCaptureTransaction(withdrawal.UserID, withdrawal.Order, withdrawal.Sum),
+		}
+		if withdrawal.Fee.IsPositive() {
+			txns = append(txns, models.NewCaptureFeeTransaction(withdrawal.UserID, withdrawal.Order, withdrawal.Fee))
+		}
+	}
+
+	for _, txn := range txns {
+		if _, err := ledgerPost(ctx, tx, txn); err != nil {
+			return nil, errors.Wrapf(err, "failed to post %s", txn.Kind)
+		}
+	}
+
+	record := goqu.Record{
+		"status":        review.Status.String(),
+		"reviewed_by":   review.ReviewerID,
+		"reviewed_at":   nowUTC,
+		"review_reason": review.Reason,
+	}
+	if review.Status == types.WithdrawalCompleted {
+		record["processed_at"] = nowUTC
+	}
+
+	qu, _, err = goqu.
+		Update(withdrawalsTName).
+		Set(record).
+		Where(goqu.C("withdrawal_id").Eq(withdrawal.ID)).
+		Returning(&models.Withdrawal{}).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	err = tx.QueryRowxContext(ctx, qu).StructScan(&withdrawal)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to update withdrawal")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrapf(err, "failed to commit")
+	}
+
+	return &withdrawal, nil
+}
+
+func selectReversals(
+	ctx context.Context,
+	q sqlx.QueryerContext,
+	where exp.Expression,
+) ([]models.WithdrawalReversal, error) {
+	qu, _, err := goqu.
+		Select(&models.WithdrawalReversal{}).
+		From(reversalsTName).
+		Where(where).
+		Order(goqu.I("created_at").Asc()).
+		ToSQL()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build query")
+	}
+
+	rows, err := q.QueryxContext(ctx, qu)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read reversals error during querying")
+	}
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			log.Error(ctx, "failed to close rows", err)
+		}
+	}()
+
+	reversals := []models.WithdrawalReversal{}
+	for rows.Next() {
+		reversal := models.WithdrawalReversal{}
+		if err := rows.StructScan(&reversal); err != nil {
+			return nil, errors.Wrapf(err, "read reversals error during scan rows")
+		}
+		reversals = append(reversals, reversal)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrapf(err, "read reversals error during querying")
+	}
+
+	return reversals, nil
+}
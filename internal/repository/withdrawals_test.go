@@ -0,0 +1,289 @@
+package repository
+
+import (
+	"context"
+	"gophermart/internal/exceptions"
+	"gophermart/internal/models"
+	"gophermart/internal/types"
+	"gophermart/internal/withdrawals"
+	"testing"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+func TestWithdrawlsRepoImpl_Reverse(t *testing.T) {
+	repos := setupRepos(t)
+	ctx := context.Background()
+
+	user := createTestUser(t, repos, "reversal-")
+
+	accrueTestOrder(t, repos, user.ID, types.MoneyFromInt(100))
+
+	withdrawal, err := repos.WithdrawalsRepo.Create(
+		ctx,
+		models.NewWithdrawal(user.ID, "2377225624", types.MoneyFromInt(40)),
+	)
+	if err != nil {
+		t.Fatalf("failed to withdraw: %s", err)
+	}
+
+	tests := []struct {
+		name       string
+		ownerID    string
+		sum        types.Money
+		wantErr    error
+		wantStatus types.WithdrawalStatus
+	}{
+		{"Test #1 Foreign user", uuid.NewString(), types.MoneyFromInt(10), exceptions.ErrWithdrawalNotFound, ""},
+		{"Test #2 Partial", user.ID, types.MoneyFromInt(15), nil, types.WithdrawalPartiallyReversed},
+		{"Test #3 Exceeds", user.ID, types.MoneyFromInt(30), exceptions.ErrReversalExceedsWithdrawal, ""},
+		{"Test #4 Rest", user.ID, types.Money{}, nil, types.WithdrawalReversed},
+		{"Test #5 Already reversed", "", types.MoneyFromInt(1), exceptions.ErrWithdrawalAlreadyReversed, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reversal := models.NewWithdrawalReversal(withdrawal.ID, user.ID, tt.sum, "")
+			reversed, err := repos.WithdrawalsRepo.Reverse(ctx, reversal, tt.ownerID, time.Hour)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error is different: got=%v want=%v", err, tt.wantErr)
+			}
+			if err == nil && reversed.Status != tt.wantStatus.String() {
+				t.Errorf("status is different: got=%s want=%s", reversed.Status, tt.wantStatus)
+			}
+		})
+	}
+
+	balance, err := repos.BalanceRepo.GetOrCreateForUser(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("failed to get balance: %s", err)
+	}
+	if !balance.Current.Equal(types.MoneyFromInt(100)) || !balance.Withdrawn.IsZero() {
+		t.Errorf("balance is different: got=%s/%s want=100/0", balance.Current, balance.Withdrawn)
+	}
+
+	withdrawals, err := repos.WithdrawalsRepo.UserWithdrawals(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("failed to get withdrawals: %s", err)
+	}
+	if len(*withdrawals) != 1 || len((*withdrawals)[0].Reversals) != 2 {
+		t.Errorf("reversals are not attached: %+v", *withdrawals)
+	}
+}
+
+func TestWithdrawlsRepoImpl_Create_Policy(t *testing.T) {
+	repos := setupRepos(t)
+	ctx := context.Background()
+	repos.WithdrawalPolicy = withdrawals.Policy{
+		DailySum:   types.MoneyFromInt(50),
+		FeePercent: decimal.NewFromInt(10),
+		FeeFixed:   types.MoneyFromInt(1),
+	}
+
+	user := createTestUser(t, repos, "policy-")
+
+	accrueTestOrder(t, repos, user.ID, types.MoneyFromInt(100))
+
+	tests := []struct {
+		name     string
+		sum      types.Money
+		wantCode withdrawals.Code
+		wantFee  types.Money
+	}{
+		{"Test #1 Within cap", types.MoneyFromInt(30), "", types.MoneyFromInt(4)},
+		{"Test #2 Daily cap", types.MoneyFromInt(21), withdrawals.CodeDailyLimitExceeded, types.Money{}},
+		{"Test #3 Up to cap", types.MoneyFromInt(20), "", types.MoneyFromInt(3)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withdrawal, err := repos.WithdrawalsRepo.Create(
+				ctx,
+				models.NewWithdrawal(user.ID, "2377225624", tt.sum),
+			)
+
+			var violation *withdrawals.Violation
+			if errors.As(err, &violation) {
+				if violation.Code != tt.wantCode {
+					t.Errorf("code is different: got=%s want=%s", violation.Code, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to withdraw: %s", err)
+			}
+			if tt.wantCode != "" {
+				t.Fatalf("withdrawal is not refused: want=%s", tt.wantCode)
+			}
+			if !withdrawal.Fee.Equal(tt.wantFee) {
+				t.Errorf("fee is different: got=%s want=%s", withdrawal.Fee, tt.wantFee)
+			}
+		})
+	}
+
+	balance, err := repos.BalanceRepo.GetOrCreateForUser(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("failed to get balance: %s", err)
+	}
+	if !balance.Current.Equal(types.MoneyFromInt(43)) {
+		t.Errorf("balance is different: got=%s want=43", balance.Current)
+	}
+}
+
+func TestWithdrawlsRepoImpl_Review(t *testing.T) {
+	repos := setupRepos(t)
+	ctx := context.Background()
+	repos.WithdrawalPolicy = withdrawals.Policy{
+		FeeFixed:        types.MoneyFromInt(1),
+		ReviewThreshold: types.MoneyFromInt(30),
+	}
+
+	admin := createTestUser(t, repos, "reviewer")
+	user := createTestUser(t, repos, "review")
+
+	accrueTestOrder(t, repos, user.ID, types.MoneyFromInt(100))
+
+	approved, err := repos.WithdrawalsRepo.Create(ctx, models.NewWithdrawal(user.ID, "2377225624", types.MoneyFromInt(40)))
+	if err != nil {
+		t.Fatalf("failed to withdraw: %s", err)
+	}
+	rejected, err := repos.WithdrawalsRepo.Create(ctx, models.NewWithdrawal(user.ID, "2377225624", types.MoneyFromInt(50)))
+	if err != nil {
+		t.Fatalf("failed to withdraw: %s", err)
+	}
+	if !approved.IsPendingReview() || !rejected.IsPendingReview() {
+		t.Fatalf("withdrawals are not pending review: %s/%s", approved.Status, rejected.Status)
+	}
+
+	_, err = repos.WithdrawalsRepo.Reverse(ctx, models.NewWithdrawalReversal(approved.ID, admin.ID, types.Money{}, ""), "", 0)
+	if !errors.Is(err, exceptions.ErrWithdrawalIsNotSettled) {
+		t.Errorf("error is different: got=%v want=%v", err, exceptions.ErrWithdrawalIsNotSettled)
+	}
+
+	tests := []struct {
+		name       string
+		id         string
+		status     types.WithdrawalStatus
+		wantErr    error
+		wantStatus types.WithdrawalStatus
+	}{
+		{"Test #1 Unknown", uuid.NewString(), types.WithdrawalCompleted, exceptions.ErrWithdrawalNotFound, ""},
+		{"Test #2 Approve", approved.ID, types.WithdrawalCompleted, nil, types.WithdrawalCompleted},
+		{"Test #3 Approve again", approved.ID, types.WithdrawalCompleted, exceptions.ErrWithdrawalIsNotPending, ""},
+		{"Test #4 Reject", rejected.ID, types.WithdrawalRejected, nil, types.WithdrawalRejected},
+		{"Test #5 Approve rejected", rejected.ID, types.WithdrawalCompleted, exceptions.ErrWithdrawalIsNotPending, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			review := models.NewWithdrawalReview(tt.id, admin.ID, tt.status, "checked")
+			reviewed, err := repos.WithdrawalsRepo.Review(ctx, review)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error is different: got=%v want=%v", err, tt.wantErr)
+			}
+			if err == nil && reviewed.Status != tt.wantStatus.String() {
+				t.Errorf("status is different: got=%s want=%s", reviewed.Status, tt.wantStatus)
+			}
+		})
+	}
+
+	balance, err := repos.BalanceRepo.GetOrCreateForUser(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("failed to get balance: %s", err)
+	}
+	if !balance.Current.Equal(types.MoneyFromInt(59)) || !balance.Withdrawn.Equal(types.MoneyFromInt(40)) || !balance.Held.IsZero() {
+		t.Errorf("balance is different: got=%s/%s/%s want=59/40/0", balance.Current, balance.Withdrawn, balance.Held)
+	}
+
+	list, err := repos.WithdrawalsRepo.UserWithdrawals(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("failed to get withdrawals: %s", err)
+	}
+	if len(*list) != 2 {
+		t.Errorf("withdrawals are different: %+v", *list)
+	}
+}
+
+func TestWithdrawlsRepoImpl_Review_LongerThanWindow(t *testing.T) {
+	repos := setupRepos(t)
+	ctx := context.Background()
+	repos.WithdrawalPolicy = withdrawals.Policy{ReviewThreshold: types.MoneyFromInt(30)}
+
+	admin := createTestUser(t, repos, "reviewer")
+	user := createTestUser(t, repos, "review")
+
+	accrueTestOrder(t, repos, user.ID, types.MoneyFromInt(100))
+
+	withdrawal, err := repos.WithdrawalsRepo.Create(ctx, models.NewWithdrawal(user.ID, "2377225624", types.MoneyFromInt(40)))
+	if err != nil {
+		t.Fatalf("failed to withdraw: %s", err)
+	}
+
+	// The review took longer than the reversal window.
+	qu, _, err := goqu.
+		Update(withdrawalsTName).
+		Set(goqu.Record{"processed_at": nowUTCPlus(-2 * time.Hour)}).
+		Where(goqu.C("withdrawal_id").Eq(withdrawal.ID)).
+		ToSQL()
+	if err != nil {
+		t.Fatalf("failed to build query: %s", err)
+	}
+	if _, err := repos.DB.ExecContext(ctx, qu); err != nil {
+		t.Fatalf("failed to age withdrawal: %s", err)
+	}
+
+	review := models.NewWithdrawalReview(withdrawal.ID, admin.ID, types.WithdrawalCompleted, "checked")
+	if _, err := repos.WithdrawalsRepo.Review(ctx, review); err != nil {
+		t.Fatalf("failed to review: %s", err)
+	}
+
+	reversal := models.NewWithdrawalReversal(withdrawal.ID, user.ID, types.Money{}, "")
+	reversed, err := repos.WithdrawalsRepo.Reverse(ctx, reversal, user.ID, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to reverse within the window after approval: %s", err)
+	}
+	if !reversed.Reversed.Equal(types.MoneyFromInt(40)) {
+		t.Errorf("reversed is different: got=%s want=40", reversed.Reversed)
+	}
+}
+
+func TestWithdrawlsRepoImpl_Reverse_Fee(t *testing.T) {
+	repos := setupRepos(t)
+	ctx := context.Background()
+	repos.WithdrawalPolicy = withdrawals.Policy{
+		DailySum: types.MoneyFromInt(50),
+		FeeFixed: types.MoneyFromInt(1),
+	}
+
+	user := createTestUser(t, repos, "reversal-fee-")
+
+	accrueTestOrder(t, repos, user.ID, types.MoneyFromInt(100))
+
+	withdrawal, err := repos.WithdrawalsRepo.Create(ctx, models.NewWithdrawal(user.ID, "2377225624", types.MoneyFromInt(40)))
+	if err != nil {
+		t.Fatalf("failed to withdraw: %s", err)
+	}
+
+	// A partial reversal keeps the fee, the full one refunds it.
+	for _, sum := range []types.Money{types.MoneyFromInt(10), {}} {
+		reversal := models.NewWithdrawalReversal(withdrawal.ID, user.ID, sum, "")
+		if _, err := repos.WithdrawalsRepo.Reverse(ctx, reversal, user.ID, time.Hour); err != nil {
+			t.Fatalf("failed to reverse: %s", err)
+		}
+	}
+
+	balance, err := repos.BalanceRepo.GetOrCreateForUser(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("failed to get balance: %s", err)
+	}
+	if !balance.Current.Equal(types.MoneyFromInt(100)) {
+		t.Errorf("balance is different: got=%s want=100", balance.Current)
+	}
+
+	// The reversed sum no longer counts against the daily cap.
+	_, err = repos.WithdrawalsRepo.Create(ctx, models.NewWithdrawal(user.ID, "2377225624", types.MoneyFromInt(50)))
+	if err != nil {
+		t.Errorf("failed to withdraw up to the cap: %s", err)
+	}
+}
@@ -0,0 +1,15 @@
+package types
+
+// AdjustmentReason tells why an admin changed a balance by hand.
+type AdjustmentReason string
+
+const (
+	AdjustmentGoodwill     AdjustmentReason = "GOODWILL"
+	AdjustmentCompensation AdjustmentReason = "COMPENSATION"
+	AdjustmentCorrection   AdjustmentReason = "CORRECTION"
+	AdjustmentFraud        AdjustmentReason = "FRAUD"
+)
+
+func (t AdjustmentReason) String() string {
+	return string(t)
+}
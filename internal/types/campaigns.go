@@ -0,0 +1,25 @@
+package types
+
+// CampaignKind tells how a campaign adds to an accrual: MULTIPLIER
+// multiplies the accrual system amount, FIXED adds a number of points.
+type CampaignKind string
+
+const (
+	CampaignMultiplier CampaignKind = "MULTIPLIER"
+	CampaignFixed      CampaignKind = "FIXED"
+)
+
+func (t CampaignKind) String() string {
+	return string(t)
+}
+
+type CampaignBonusStatus string
+
+const (
+	CampaignBonusApplied  CampaignBonusStatus = "APPLIED"
+	CampaignBonusReversed CampaignBonusStatus = "REVERSED"
+)
+
+func (t CampaignBonusStatus) String() string {
+	return string(t)
+}
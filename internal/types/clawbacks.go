@@ -0,0 +1,30 @@
+package types
+
+import "github.com/pkg/errors"
+
+// ClawbackPolicy tells what to do when taking back a revoked accrual
+// would take the balance below zero. ALLOW_DEBT takes it all anyway,
+// PARTIAL takes what is left and forgives the rest, BLOCK_WITHDRAWALS
+// takes what is left and blocks withdrawals until later accruals
+// recover the rest.
+type ClawbackPolicy string
+
+const (
+	ClawbackAllowDebt        ClawbackPolicy = "ALLOW_DEBT"
+	ClawbackPartial          ClawbackPolicy = "PARTIAL"
+	ClawbackBlockWithdrawals ClawbackPolicy = "BLOCK_WITHDRAWALS"
+)
+
+func (t ClawbackPolicy) String() string {
+	return string(t)
+}
+
+func (t *ClawbackPolicy) UnmarshalText(text []byte) error {
+	switch policy := ClawbackPolicy(text); policy {
+	case ClawbackAllowDebt, ClawbackPartial, ClawbackBlockWithdrawals:
+		*t = policy
+		return nil
+	default:
+		return errors.Errorf("unknown clawback policy: %s", text)
+	}
+}
@@ -0,0 +1,68 @@
+package types
+
+// LedgerAccount is an account of the points ledger. USER_* accounts
+// belong to a user and are projected into bll_balance, SYSTEM_* accounts
+// are the counterparties points come from and go to.
+type LedgerAccount string
+
+const (
+	AccountUserCurrent    LedgerAccount = "USER_CURRENT"
+	AccountUserWithdrawn  LedgerAccount = "USER_WITHDRAWN"
+	AccountUserHeld       LedgerAccount = "USER_HELD"
+	AccountSystemAccruals LedgerAccount = "SYSTEM_ACCRUALS"
+	AccountSystemOpening  LedgerAccount = "SYSTEM_OPENING"
+	AccountSystemExpired  LedgerAccount = "SYSTEM_EXPIRED"
+	AccountSystemBonuses  LedgerAccount = "SYSTEM_BONUSES"
+	// AccountSystemAdjustments is where manual admin corrections come from.
+	AccountSystemAdjustments LedgerAccount = "SYSTEM_ADJUSTMENTS"
+	// AccountSystemFees collects withdrawal fees.
+	AccountSystemFees LedgerAccount = "SYSTEM_FEES"
+)
+
+func (t LedgerAccount) String() string {
+	return string(t)
+}
+
+func (t LedgerAccount) IsUser() bool {
+	return t == AccountUserCurrent || t == AccountUserWithdrawn || t == AccountUserHeld
+}
+
+// LedgerKind tells what business operation a ledger transaction records.
+type LedgerKind string
+
+const (
+	LedgerAccrual    LedgerKind = "ACCRUAL"
+	LedgerWithdrawal LedgerKind = "WITHDRAWAL"
+	LedgerReversal   LedgerKind = "REVERSAL"
+	LedgerHold       LedgerKind = "HOLD"
+	LedgerRelease    LedgerKind = "RELEASE"
+	LedgerExpiry     LedgerKind = "EXPIRY"
+	LedgerTierBonus  LedgerKind = "TIER_BONUS"
+	LedgerTransfer   LedgerKind = "TRANSFER"
+	LedgerCampaign   LedgerKind = "CAMPAIGN_BONUS"
+	LedgerReferral   LedgerKind = "REFERRAL_BONUS"
+	LedgerAdjustment LedgerKind = "ADJUSTMENT"
+	LedgerClawback   LedgerKind = "CLAWBACK"
+	LedgerFee        LedgerKind = "WITHDRAWAL_FEE"
+	// LedgerFeeRefund returns the fee of a fully reversed withdrawal.
+	LedgerFeeRefund LedgerKind = "WITHDRAWAL_FEE_REFUND"
+	// LedgerCampaignReversal takes back a campaign bonus.
+	LedgerCampaignReversal LedgerKind = "CAMPAIGN_BONUS_REVERSAL"
+	// LedgerOpening carries balances that existed before the ledger.
+	LedgerOpening LedgerKind = "OPENING"
+)
+
+func (t LedgerKind) String() string {
+	return string(t)
+}
+
+// OpensLots tells credits of the kind bring new points, which start a new
+// point lot. Other credits give back points spent before.
+func (t LedgerKind) OpensLots() bool {
+	switch t {
+	case LedgerAccrual, LedgerTierBonus, LedgerCampaign, LedgerReferral, LedgerAdjustment, LedgerOpening:
+		return true
+	default:
+		return false
+	}
+}
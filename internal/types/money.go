@@ -0,0 +1,139 @@
+package types
+
+import (
+	"database/sql/driver"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// Money is an exact amount of loyalty points. It is stored in numeric
+// columns and encoded as a plain JSON number, so arithmetic never drifts
+// the way float64 does.
+type Money struct {
+	d decimal.Decimal
+}
+
+func NewMoney(value int64, exp int32) Money {
+	return Money{d: decimal.New(value, exp)}
+}
+
+func MoneyFromInt(value int64) Money {
+	return Money{d: decimal.NewFromInt(value)}
+}
+
+// MoneyFromFloat is meant for literals and the accrual simulator,
+// amounts coming from clients and the database are parsed exactly.
+func MoneyFromFloat(value float64) Money {
+	return Money{d: decimal.NewFromFloat(value)}
+}
+
+func ParseMoney(value string) (Money, error) {
+	d, err := decimal.NewFromString(value)
+	if err != nil {
+		return Money{}, errors.Wrapf(err, "failed to parse money")
+	}
+
+	return Money{d: d}, nil
+}
+
+func (m Money) Add(other Money) Money {
+	return Money{d: m.d.Add(other.d)}
+}
+
+func (m Money) Sub(other Money) Money {
+	return Money{d: m.d.Sub(other.d)}
+}
+
+func (m Money) Neg() Money {
+	return Money{d: m.d.Neg()}
+}
+
+// MulRatio multiplies the amount by ratio, e.g. a percentage divided by 100.
+func (m Money) MulRatio(ratio decimal.Decimal) Money {
+	return Money{d: m.d.Mul(ratio)}
+}
+
+// Round rounds the amount half away from zero to the given number of decimal places.
+func (m Money) Round(places int32) Money {
+	return Money{d: m.d.Round(places)}
+}
+
+func (m Money) Cmp(other Money) int {
+	return m.d.Cmp(other.d)
+}
+
+func (m Money) Equal(other Money) bool {
+	return m.d.Equal(other.d)
+}
+
+func (m Money) LessThan(other Money) bool {
+	return m.d.LessThan(other.d)
+}
+
+func (m Money) GreaterThan(other Money) bool {
+	return m.d.GreaterThan(other.d)
+}
+
+func (m Money) IsZero() bool {
+	return m.d.IsZero()
+}
+
+func (m Money) IsNegative() bool {
+	return m.d.IsNegative()
+}
+
+func (m Money) IsPositive() bool {
+	return m.d.IsPositive()
+}
+
+func (m Money) Decimal() decimal.Decimal {
+	return m.d
+}
+
+func (m Money) String() string {
+	return m.d.String()
+}
+
+// MarshalJSON keeps amounts JSON numbers, as they were with float64.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.d.String()), nil
+}
+
+// UnmarshalJSON accepts numbers as well as quoted numbers.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*m = Money{}
+		return nil
+	}
+
+	if err := m.d.UnmarshalJSON(data); err != nil {
+		return errors.Wrapf(err, "failed to parse money")
+	}
+
+	return nil
+}
+
+// UnmarshalText lets amounts be read from the environment.
+func (m *Money) UnmarshalText(text []byte) error {
+	parsed, err := ParseMoney(string(text))
+	if err != nil {
+		return err
+	}
+	*m = parsed
+
+	return nil
+}
+
+func (m *Money) Scan(src interface{}) error {
+	if err := m.d.Scan(src); err != nil {
+		return errors.Wrapf(err, "failed to scan money")
+	}
+
+	return nil
+}
+
+// Value makes goqu interpolate amounts as exact numeric literals.
+func (m Money) Value() (driver.Value, error) {
+	return m.d.String(), nil
+}
@@ -0,0 +1,124 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+	"testing/quick"
+)
+
+// cents turns a random int into an amount with two decimal places.
+func cents(v int32) Money {
+	return NewMoney(int64(v), -2)
+}
+
+func TestMoney_AddSubNoDrift(t *testing.T) {
+	property := func(start int32, ops []int32) bool {
+		balance := cents(start)
+		for _, op := range ops {
+			balance = balance.Add(cents(op))
+		}
+		for _, op := range ops {
+			balance = balance.Sub(cents(op))
+		}
+
+		return balance.Equal(cents(start))
+	}
+
+	if err := quick.Check(property, &quick.Config{MaxCount: 1000}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMoney_RepeatedAccruals(t *testing.T) {
+	property := func(v int32, n uint8) bool {
+		amount := cents(v)
+
+		sum := Money{}
+		for i := 0; i < int(n); i++ {
+			sum = sum.Add(amount)
+		}
+
+		return sum.Equal(NewMoney(int64(v)*int64(n), -2))
+	}
+
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+
+	// 0.1 has no exact float64 representation, a thousand of them must still be 100.
+	sum := Money{}
+	for i := 0; i < 1000; i++ {
+		sum = sum.Add(MoneyFromFloat(0.1))
+	}
+	if !sum.Equal(MoneyFromInt(100)) {
+		t.Errorf("sum is different: got=%s want=100", sum)
+	}
+}
+
+func TestMoney_JSONRoundTrip(t *testing.T) {
+	property := func(v int64, exp uint8) bool {
+		amount := NewMoney(v, -int32(exp%10))
+
+		raw, err := json.Marshal(amount)
+		if err != nil || raw[0] == '"' {
+			return false
+		}
+
+		var decoded Money
+		if err := json.Unmarshal(raw, &decoded); err != nil {
+			return false
+		}
+
+		return decoded.Equal(amount)
+	}
+
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMoney_SQLRoundTrip(t *testing.T) {
+	property := func(v int64, exp uint8) bool {
+		amount := NewMoney(v, -int32(exp%10))
+
+		value, err := amount.Value()
+		if err != nil {
+			return false
+		}
+
+		var scanned Money
+		if err := scanned.Scan([]byte(value.(string))); err != nil {
+			return false
+		}
+
+		return scanned.Equal(amount)
+	}
+
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMoney_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want Money
+	}{
+		{"Test #1 Number", `729.98`, NewMoney(72998, -2)},
+		{"Test #2 Integer", `500`, MoneyFromInt(500)},
+		{"Test #3 Quoted", `"0.1"`, NewMoney(1, -1)},
+		{"Test #4 Null", `null`, Money{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Money
+			if err := json.Unmarshal([]byte(tt.raw), &got); err != nil {
+				t.Fatalf("failed to unmarshal: %s", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("amount is different: got=%s want=%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -7,8 +7,30 @@ const (
 	OrderProcessing OrderStatus = "PROCESSING"
 	OrderInvalid    OrderStatus = "INVALID"
 	OrderProcessed  OrderStatus = "PROCESSED"
+	// OrderRevoked is a processed order whose accrual was taken back.
+	OrderRevoked OrderStatus = "REVOKED"
 )
 
 func (t OrderStatus) String() string {
 	return string(t)
 }
+
+func (t OrderStatus) IsFinal() bool {
+	return t == OrderProcessed || t == OrderInvalid || t == OrderRevoked
+}
+
+// StatusSource tells what moved an order to its status.
+type StatusSource string
+
+const (
+	SourceUser    StatusSource = "USER"
+	SourcePoll    StatusSource = "POLL"
+	SourceWebhook StatusSource = "WEBHOOK"
+	SourceAdmin   StatusSource = "ADMIN"
+	// SourceBackfill marks statuses reached before history was recorded.
+	SourceBackfill StatusSource = "BACKFILL"
+)
+
+func (t StatusSource) String() string {
+	return string(t)
+}
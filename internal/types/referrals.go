@@ -0,0 +1,18 @@
+package types
+
+// ReferralStatus is where a referral is on its way to the bonus. PENDING
+// waits for the first processed order of the referee, which either
+// REWARDS both parties or gets REJECTED by the fraud guards. A rewarded
+// referral is REVERSED when its order is revoked.
+type ReferralStatus string
+
+const (
+	ReferralPending  ReferralStatus = "PENDING"
+	ReferralRewarded ReferralStatus = "REWARDED"
+	ReferralRejected ReferralStatus = "REJECTED"
+	ReferralReversed ReferralStatus = "REVERSED"
+)
+
+func (t ReferralStatus) String() string {
+	return string(t)
+}
@@ -0,0 +1,44 @@
+package types
+
+// WithdrawalStatus is the state of a withdrawal. PENDING_REVIEW ones keep
+// their points held until an admin settles or rejects them.
+type WithdrawalStatus string
+
+const (
+	WithdrawalPendingReview     WithdrawalStatus = "PENDING_REVIEW"
+	WithdrawalCompleted         WithdrawalStatus = "COMPLETED"
+	WithdrawalRejected          WithdrawalStatus = "REJECTED"
+	WithdrawalReversed          WithdrawalStatus = "REVERSED"
+	WithdrawalPartiallyReversed WithdrawalStatus = "PARTIALLY_REVERSED"
+)
+
+func (t WithdrawalStatus) String() string {
+	return string(t)
+}
+
+// HoldStatus is the state of points reserved for a later withdrawal.
+// Only ACTIVE holds keep points out of the available balance.
+type HoldStatus string
+
+const (
+	HoldActive   HoldStatus = "ACTIVE"
+	HoldCaptured HoldStatus = "CAPTURED"
+	HoldReleased HoldStatus = "RELEASED"
+	HoldExpired  HoldStatus = "EXPIRED"
+)
+
+func (t HoldStatus) String() string {
+	return string(t)
+}
+
+// TransferDirection tells whether the user sent or received a transfer.
+type TransferDirection string
+
+const (
+	TransferIn  TransferDirection = "IN"
+	TransferOut TransferDirection = "OUT"
+)
+
+func (t TransferDirection) String() string {
+	return string(t)
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"gophermart/internal/models"
 	"io"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/pkg/errors"
@@ -39,6 +40,7 @@ func (v *AuthValidatorImpl) ValidateUserRegister(body io.ReadCloser) (*models.Au
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to get new auth user")
 	}
+	user.ReferredWith = strings.ToUpper(strings.TrimSpace(userRegister.ReferralCode))
 
 	return user, nil
 }
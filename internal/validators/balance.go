@@ -1,6 +1,15 @@
 package validators
 
-import "github.com/go-playground/validator/v10"
+import (
+	"encoding/json"
+	"gophermart/internal/models"
+	"io"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
 
 type BalanceValidatorImpl struct {
 	validate *validator.Validate
@@ -11,3 +20,87 @@ func NewBalanceValidator() *BalanceValidatorImpl {
 		validate: validator.New(validator.WithRequiredStructEnabled()),
 	}
 }
+
+func (v *BalanceValidatorImpl) ValidatePage(r *http.Request) (*models.Page, error) {
+	return ParsePage(r)
+}
+
+func (v *BalanceValidatorImpl) ValidateUserIDFromPath(r *http.Request) (string, error) {
+	return ParseUUIDFromPath(r, "id")
+}
+
+// ValidateTransfer returns the transfer and the recipient login.
+func (v *BalanceValidatorImpl) ValidateTransfer(
+	userID string,
+	body io.ReadCloser,
+) (*models.Transfer, string, error) {
+	transferCreate := &models.TransferCreate{}
+
+	err := json.NewDecoder(body).Decode(transferCreate)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "failed to parse transfer json")
+	}
+
+	if err := v.validate.Struct(transferCreate); err != nil {
+		return nil, "", errors.Wrapf(err, "failed to validate transfer")
+	}
+
+	if !transferCreate.Sum.IsPositive() {
+		return nil, "", errors.New("transfer sum is not positive")
+	}
+
+	return models.NewTransfer(userID, transferCreate.Sum), transferCreate.Login, nil
+}
+
+// ValidateAdjustment returns the adjustment adminID makes to the user
+// from the path.
+func (v *BalanceValidatorImpl) ValidateAdjustment(
+	adminID string,
+	r *http.Request,
+) (*models.Adjustment, error) {
+	userID, err := ParseUUIDFromPath(r, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	adjustmentCreate := &models.AdjustmentCreate{}
+
+	err = json.NewDecoder(r.Body).Decode(adjustmentCreate)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse adjustment json")
+	}
+
+	if err := v.validate.Struct(adjustmentCreate); err != nil {
+		return nil, errors.Wrapf(err, "failed to validate adjustment")
+	}
+
+	if adjustmentCreate.Amount.IsZero() {
+		return nil, errors.New("adjustment amount is zero")
+	}
+
+	return models.NewAdjustment(adminID, userID, adjustmentCreate), nil
+}
+
+// ValidateAdjustmentsFilter reads user_id and admin_id from the query.
+func (v *BalanceValidatorImpl) ValidateAdjustmentsFilter(r *http.Request) (*models.AdjustmentsFilter, error) {
+	filter := &models.AdjustmentsFilter{}
+
+	query := r.URL.Query()
+	for name, field := range map[string]*string{
+		"user_id":  &filter.UserID,
+		"admin_id": &filter.AdminID,
+	} {
+		raw := query.Get(name)
+		if raw == "" {
+			continue
+		}
+
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse %s", name)
+		}
+		*field = id.String()
+	}
+
+	return filter, nil
+}
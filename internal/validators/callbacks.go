@@ -0,0 +1,90 @@
+package validators
+
+import (
+	"bytes"
+	"encoding/json"
+	"gophermart/internal/crypto"
+	"gophermart/internal/exceptions"
+	"gophermart/internal/models"
+	"gophermart/pkg/clients/accrual"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/pkg/errors"
+)
+
+const (
+	CallbackTimestampHeader = "X-Accrual-Timestamp"
+	CallbackSignatureHeader = "X-Accrual-Signature"
+)
+
+type CallbacksValidatorImpl struct {
+	validate  *validator.Validate
+	secret    string
+	tolerance time.Duration
+}
+
+func NewCallbacksValidator(secret string, tolerance time.Duration) *CallbacksValidatorImpl {
+	return &CallbacksValidatorImpl{
+		validate:  validator.New(validator.WithRequiredStructEnabled()),
+		secret:    secret,
+		tolerance: tolerance,
+	}
+}
+
+// ValidateCallback checks the signature of the raw body and its unix timestamp
+// before decoding it, so unsigned and stale deliveries are never parsed.
+func (v *CallbacksValidatorImpl) ValidateCallback(r *http.Request) (*models.AccrualCallback, error) {
+	if v.secret == "" {
+		return nil, exceptions.ErrCallbacksDisabled
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read body")
+	}
+
+	timestamp := r.Header.Get(CallbackTimestampHeader)
+	signature := r.Header.Get(CallbackSignatureHeader)
+	if !crypto.VerifyCallback(v.secret, timestamp, body, signature) {
+		return nil, exceptions.ErrWrongCallbackSignature
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse timestamp")
+	}
+
+	skew := time.Since(time.Unix(unix, 0))
+	if skew > v.tolerance || skew < -v.tolerance {
+		return nil, exceptions.ErrCallbackExpired
+	}
+
+	callbackCreate := &models.AccrualCallbackCreate{}
+	if err := json.NewDecoder(bytes.NewReader(body)).Decode(callbackCreate); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse callback json")
+	}
+
+	if err := v.validate.Struct(callbackCreate); err != nil {
+		return nil, errors.Wrapf(err, "failed to validate callback")
+	}
+
+	if callbackCreate.Accrual.IsNegative() {
+		return nil, errors.Errorf("negative accrual: %s", callbackCreate.Accrual)
+	}
+
+	// Hex decoding ignores case, so the same delivery may come with any
+	// case of the signature; it is remembered in lower case.
+	return &models.AccrualCallback{
+		Signature: strings.ToLower(signature),
+		Order: accrual.OrderRead{
+			Order:   callbackCreate.Order,
+			Status:  callbackCreate.Status,
+			Accrual: callbackCreate.Accrual,
+		},
+	}, nil
+}
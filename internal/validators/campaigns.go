@@ -0,0 +1,53 @@
+package validators
+
+import (
+	"encoding/json"
+	"gophermart/internal/models"
+	"gophermart/internal/types"
+	"io"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/pkg/errors"
+)
+
+type CampaignsValidatorImpl struct {
+	validate *validator.Validate
+}
+
+func NewCampaignsValidator() *CampaignsValidatorImpl {
+	return &CampaignsValidatorImpl{
+		validate: validator.New(validator.WithRequiredStructEnabled()),
+	}
+}
+
+func (v *CampaignsValidatorImpl) ValidatePage(r *http.Request) (*models.Page, error) {
+	return ParsePage(r)
+}
+
+func (v *CampaignsValidatorImpl) ValidateIDFromPath(r *http.Request) (string, error) {
+	return ParseUUIDFromPath(r, "id")
+}
+
+func (v *CampaignsValidatorImpl) ValidateCampaign(body io.ReadCloser) (*models.Campaign, error) {
+	campaignCreate := &models.CampaignCreate{}
+
+	err := json.NewDecoder(body).Decode(campaignCreate)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse campaign json")
+	}
+
+	if err := v.validate.Struct(campaignCreate); err != nil {
+		return nil, errors.Wrapf(err, "failed to validate campaign")
+	}
+
+	one := types.MoneyFromInt(1)
+	if campaignCreate.Multiplier != nil && !campaignCreate.Multiplier.GreaterThan(one) {
+		return nil, errors.New("campaign multiplier is not greater than 1")
+	}
+	if campaignCreate.Points != nil && !campaignCreate.Points.IsPositive() {
+		return nil, errors.New("campaign points are not positive")
+	}
+
+	return models.NewCampaign(campaignCreate), nil
+}
@@ -1,7 +1,14 @@
 package validators
 
 import (
+	"gophermart/internal/models"
+	"net/http"
+	"strconv"
 	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
 )
 
 const DefaultPasswordLength int = 8
@@ -21,7 +28,7 @@ func VerifyPassword(s string) bool {
 		case unicode.IsUpper(c):
 			upper = true
 			chars++
-		case unicode.IsLetter(c) || c == ' ':
+		case unicode.IsLetter(c) || unicode.IsPunct(c) || unicode.IsSymbol(c) || c == ' ':
 			chars++
 		default:
 			return false
@@ -30,3 +37,42 @@ func VerifyPassword(s string) bool {
 	properLength = chars >= DefaultPasswordLength
 	return number && upper && properLength
 }
+
+func ParsePage(r *http.Request) (*models.Page, error) {
+	page := &models.Page{Limit: models.DefaultPageLimit}
+
+	query := r.URL.Query()
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			return nil, errors.Errorf("wrong limit: %s", raw)
+		}
+		page.Limit = min(limit, models.MaxPageLimit)
+	}
+
+	if raw := query.Get("offset"); raw != "" {
+		offset, err := strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			return nil, errors.Errorf("wrong offset: %s", raw)
+		}
+		page.Offset = offset
+	}
+
+	return page, nil
+}
+
+func ParseUUIDFromPath(r *http.Request, name string) (string, error) {
+	vars := mux.Vars(r)
+
+	raw, ok := vars[name]
+	if !ok {
+		return "", errors.Errorf("failed to retrieve %s", name)
+	}
+
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse %s", name)
+	}
+
+	return id.String(), nil
+}
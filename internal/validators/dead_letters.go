@@ -0,0 +1,26 @@
+package validators
+
+import (
+	"gophermart/internal/models"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type DeadLettersValidatorImpl struct {
+	validate *validator.Validate
+}
+
+func NewDeadLettersValidator() *DeadLettersValidatorImpl {
+	return &DeadLettersValidatorImpl{
+		validate: validator.New(validator.WithRequiredStructEnabled()),
+	}
+}
+
+func (v *DeadLettersValidatorImpl) ValidatePage(r *http.Request) (*models.Page, error) {
+	return ParsePage(r)
+}
+
+func (v *DeadLettersValidatorImpl) ValidateIDFromPath(r *http.Request) (string, error) {
+	return ParseUUIDFromPath(r, "id")
+}
@@ -4,6 +4,7 @@ import (
 	"gophermart/internal/models"
 	"io"
 	"net/http"
+	"time"
 )
 
 type AuthValidator interface {
@@ -16,8 +17,38 @@ type OrderValidator interface {
 	ValidateOrderFromPath(r *http.Request) (*uint64, error)
 }
 
-type BalanceValidator interface{}
+type BalanceValidator interface {
+	ValidatePage(r *http.Request) (*models.Page, error)
+	ValidateUserIDFromPath(r *http.Request) (string, error)
+	ValidateTransfer(userID string, body io.ReadCloser) (*models.Transfer, string, error)
+	ValidateAdjustment(adminID string, r *http.Request) (*models.Adjustment, error)
+	ValidateAdjustmentsFilter(r *http.Request) (*models.AdjustmentsFilter, error)
+}
 
 type WithdrawalsValidator interface {
 	ValidateOrderCreate(userID string, body io.ReadCloser) (*models.Withdrawal, error)
+	ValidateReversal(actorID string, r *http.Request) (*models.WithdrawalReversal, error)
+	ValidateReview(adminID string, r *http.Request) (*models.WithdrawalReview, error)
+	ValidatePage(r *http.Request) (*models.Page, error)
+	ValidateHoldCreate(userID string, body io.ReadCloser, ttl time.Duration) (*models.Hold, error)
+	ValidateHoldIDFromPath(r *http.Request) (string, error)
+}
+
+type DeadLettersValidator interface {
+	ValidatePage(r *http.Request) (*models.Page, error)
+	ValidateIDFromPath(r *http.Request) (string, error)
+}
+
+type CampaignsValidator interface {
+	ValidatePage(r *http.Request) (*models.Page, error)
+	ValidateIDFromPath(r *http.Request) (string, error)
+	ValidateCampaign(body io.ReadCloser) (*models.Campaign, error)
+}
+
+type ReferralsValidator interface {
+	ValidatePage(r *http.Request) (*models.Page, error)
+}
+
+type CallbacksValidator interface {
+	ValidateCallback(r *http.Request) (*models.AccrualCallback, error)
 }
@@ -0,0 +1,22 @@
+package validators
+
+import (
+	"gophermart/internal/models"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type ReferralsValidatorImpl struct {
+	validate *validator.Validate
+}
+
+func NewReferralsValidator() *ReferralsValidatorImpl {
+	return &ReferralsValidatorImpl{
+		validate: validator.New(validator.WithRequiredStructEnabled()),
+	}
+}
+
+func (v *ReferralsValidatorImpl) ValidatePage(r *http.Request) (*models.Page, error) {
+	return ParsePage(r)
+}
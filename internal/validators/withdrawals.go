@@ -5,6 +5,8 @@ import (
 	"gophermart/internal/exceptions"
 	"gophermart/internal/models"
 	"io"
+	"net/http"
+	"time"
 
 	"github.com/ShiraazMoollatjie/goluhn"
 	"github.com/go-playground/validator/v10"
@@ -44,3 +46,109 @@ func (v *WithdrawalsValidatorImpl) ValidateOrderCreate(
 
 	return withdrawal, nil
 }
+
+// ValidateReversal reads the withdrawal from the path and the optional body.
+// An empty body reverses the whole remaining sum.
+func (v *WithdrawalsValidatorImpl) ValidateReversal(
+	actorID string,
+	r *http.Request,
+) (*models.WithdrawalReversal, error) {
+	withdrawalID, err := ParseUUIDFromPath(r, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	reversalCreate := &models.WithdrawalReversalCreate{}
+
+	err = json.NewDecoder(r.Body).Decode(reversalCreate)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, errors.Wrapf(err, "failed to parse reversal json")
+	}
+
+	if err := v.validate.Struct(reversalCreate); err != nil {
+		return nil, errors.Wrapf(err, "failed to validate reversal")
+	}
+
+	if reversalCreate.Sum.IsNegative() {
+		return nil, errors.New("reversal sum is negative")
+	}
+
+	reversal := models.NewWithdrawalReversal(
+		withdrawalID,
+		actorID,
+		reversalCreate.Sum,
+		reversalCreate.Reason,
+	)
+
+	return reversal, nil
+}
+
+// ValidateReview reads the withdrawal from the path and the optional
+// reason from the body. The controller decides the status.
+func (v *WithdrawalsValidatorImpl) ValidateReview(
+	adminID string,
+	r *http.Request,
+) (*models.WithdrawalReview, error) {
+	withdrawalID, err := ParseUUIDFromPath(r, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	reviewCreate := &models.WithdrawalReviewCreate{}
+
+	err = json.NewDecoder(r.Body).Decode(reviewCreate)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, errors.Wrapf(err, "failed to parse review json")
+	}
+
+	if err := v.validate.Struct(reviewCreate); err != nil {
+		return nil, errors.Wrapf(err, "failed to validate review")
+	}
+
+	review := models.NewWithdrawalReview(
+		withdrawalID,
+		adminID,
+		"",
+		reviewCreate.Reason,
+	)
+
+	return review, nil
+}
+
+func (v *WithdrawalsValidatorImpl) ValidatePage(r *http.Request) (*models.Page, error) {
+	return ParsePage(r)
+}
+
+func (v *WithdrawalsValidatorImpl) ValidateHoldCreate(
+	userID string,
+	body io.ReadCloser,
+	ttl time.Duration,
+) (*models.Hold, error) {
+	holdCreate := &models.HoldCreate{}
+
+	err := json.NewDecoder(body).Decode(holdCreate)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse hold json")
+	}
+
+	if err := goluhn.Validate(holdCreate.Order); err != nil {
+		return nil, exceptions.ErrWrongOrderNumber
+	}
+
+	if !holdCreate.Sum.IsPositive() {
+		return nil, errors.New("hold sum is not positive")
+	}
+
+	hold := models.NewHold(
+		userID,
+		holdCreate.Order,
+		holdCreate.Sum,
+		ttl,
+	)
+
+	return hold, nil
+}
+
+func (v *WithdrawalsValidatorImpl) ValidateHoldIDFromPath(r *http.Request) (string, error) {
+	return ParseUUIDFromPath(r, "id")
+}
@@ -0,0 +1,100 @@
+package withdrawals
+
+import (
+	"fmt"
+	"gophermart/internal/types"
+
+	"github.com/shopspring/decimal"
+)
+
+// Code is the machine-readable reason a withdrawal was refused.
+type Code string
+
+const (
+	CodeInvalidAmount        Code = "WITHDRAWAL_INVALID_AMOUNT"
+	CodeBelowMinimum         Code = "WITHDRAWAL_BELOW_MINIMUM"
+	CodeAboveMaximum         Code = "WITHDRAWAL_ABOVE_MAXIMUM"
+	CodeDailyLimitExceeded   Code = "WITHDRAWAL_DAILY_LIMIT_EXCEEDED"
+	CodeMonthlyLimitExceeded Code = "WITHDRAWAL_MONTHLY_LIMIT_EXCEEDED"
+)
+
+// Violation is a withdrawal policy rule the withdrawal breaks.
+type Violation struct {
+	Code    Code   `json:"code"`
+	Message string `json:"message"`
+}
+
+func (v *Violation) Error() string {
+	return fmt.Sprintf("%s: %s", v.Code, v.Message)
+}
+
+// IsLimit tells the violation comes from a cap on the user's usage
+// rather than from the amount itself.
+func (v *Violation) IsLimit() bool {
+	return v.Code == CodeDailyLimitExceeded || v.Code == CodeMonthlyLimitExceeded
+}
+
+// Usage is what the user has withdrawn in the current UTC day and month.
+type Usage struct {
+	Daily   types.Money
+	Monthly types.Money
+}
+
+// Policy are the rules withdrawals follow. Zero amounts disable a rule.
+// The fee is FeePercent of the sum plus FeeFixed, charged on top of the
+// sum; minimums, maximums and caps apply to the sum alone. Withdrawals
+// above ReviewThreshold wait for an admin to review them.
+type Policy struct {
+	Min             types.Money
+	Max             types.Money
+	DailySum        types.Money
+	MonthlySum      types.Money
+	FeePercent      decimal.Decimal
+	FeeFixed        types.Money
+	ReviewThreshold types.Money
+}
+
+// Check applies the rules on the amount of a single withdrawal.
+func (p *Policy) Check(sum types.Money) error {
+	switch {
+	case !sum.IsPositive():
+		return &Violation{CodeInvalidAmount, "withdrawal sum must be positive"}
+	case p.Min.IsPositive() && sum.LessThan(p.Min):
+		return &Violation{CodeBelowMinimum, fmt.Sprintf("withdrawal sum is below the minimum of %s", p.Min)}
+	case p.Max.IsPositive() && sum.GreaterThan(p.Max):
+		return &Violation{CodeAboveMaximum, fmt.Sprintf("withdrawal sum is above the maximum of %s", p.Max)}
+	}
+
+	return nil
+}
+
+// CheckUsage applies the daily and monthly caps given what the user has
+// already withdrawn.
+func (p *Policy) CheckUsage(sum types.Money, usage Usage) error {
+	switch {
+	case p.DailySum.IsPositive() && usage.Daily.Add(sum).GreaterThan(p.DailySum):
+		return &Violation{CodeDailyLimitExceeded, fmt.Sprintf("withdrawals exceed the daily limit of %s", p.DailySum)}
+	case p.MonthlySum.IsPositive() && usage.Monthly.Add(sum).GreaterThan(p.MonthlySum):
+		return &Violation{CodeMonthlyLimitExceeded, fmt.Sprintf("withdrawals exceed the monthly limit of %s", p.MonthlySum)}
+	}
+
+	return nil
+}
+
+// HasCaps tells CheckUsage has anything to check.
+func (p *Policy) HasCaps() bool {
+	return p.DailySum.IsPositive() || p.MonthlySum.IsPositive()
+}
+
+// Fee is charged on top of sum, rounded to cents.
+func (p *Policy) Fee(sum types.Money) types.Money {
+	fee := sum.MulRatio(p.FeePercent.Div(decimal.NewFromInt(100))).Add(p.FeeFixed)
+
+	return fee.Round(2)
+}
+
+// NeedsReview tells the withdrawal of sum must be reviewed before it
+// settles.
+func (p *Policy) NeedsReview(sum types.Money) bool {
+	return p.ReviewThreshold.IsPositive() && sum.GreaterThan(p.ReviewThreshold)
+}
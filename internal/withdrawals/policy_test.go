@@ -0,0 +1,94 @@
+package withdrawals
+
+import (
+	"gophermart/internal/types"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+func TestPolicy_Check(t *testing.T) {
+	policy := &Policy{Min: types.MoneyFromInt(10), Max: types.MoneyFromInt(500)}
+
+	tests := []struct {
+		name string
+		sum  types.Money
+		want Code
+	}{
+		{"zero", types.Money{}, CodeInvalidAmount},
+		{"negative", types.MoneyFromInt(-5), CodeInvalidAmount},
+		{"below minimum", types.MoneyFromInt(9), CodeBelowMinimum},
+		{"minimum", types.MoneyFromInt(10), ""},
+		{"maximum", types.MoneyFromInt(500), ""},
+		{"above maximum", types.NewMoney(50001, -2), CodeAboveMaximum},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Check(tt.sum)
+			if got := code(err); got != tt.want {
+				t.Errorf("code is different: got=%q want=%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicy_CheckUsage(t *testing.T) {
+	policy := &Policy{DailySum: types.MoneyFromInt(100), MonthlySum: types.MoneyFromInt(1000)}
+
+	tests := []struct {
+		name  string
+		sum   types.Money
+		usage Usage
+		want  Code
+	}{
+		{"within", types.MoneyFromInt(50), Usage{types.MoneyFromInt(50), types.MoneyFromInt(500)}, ""},
+		{"daily", types.MoneyFromInt(51), Usage{types.MoneyFromInt(50), types.MoneyFromInt(500)}, CodeDailyLimitExceeded},
+		{"monthly", types.MoneyFromInt(50), Usage{types.Money{}, types.MoneyFromInt(960)}, CodeMonthlyLimitExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.CheckUsage(tt.sum, tt.usage)
+			if got := code(err); got != tt.want {
+				t.Errorf("code is different: got=%q want=%q", got, tt.want)
+			}
+		})
+	}
+
+	if (&Policy{}).CheckUsage(types.MoneyFromInt(1e6), Usage{}) != nil {
+		t.Errorf("zero policy has caps")
+	}
+}
+
+func TestPolicy_Fee(t *testing.T) {
+	policy := &Policy{FeePercent: decimal.RequireFromString("1.5"), FeeFixed: types.MoneyFromInt(1)}
+
+	if got := policy.Fee(types.NewMoney(12345, -2)); !got.Equal(types.NewMoney(285, -2)) {
+		t.Errorf("fee is different: got=%s want=2.85", got)
+	}
+	if got := (&Policy{}).Fee(types.MoneyFromInt(100)); !got.IsZero() {
+		t.Errorf("fee is different: got=%s want=0", got)
+	}
+}
+
+func TestPolicy_NeedsReview(t *testing.T) {
+	policy := &Policy{ReviewThreshold: types.MoneyFromInt(1000)}
+
+	if policy.NeedsReview(types.MoneyFromInt(1000)) {
+		t.Errorf("withdrawal at the threshold needs review")
+	}
+	if !policy.NeedsReview(types.NewMoney(100001, -2)) {
+		t.Errorf("withdrawal above the threshold doesn't need review")
+	}
+	if (&Policy{}).NeedsReview(types.MoneyFromInt(1e6)) {
+		t.Errorf("zero policy reviews withdrawals")
+	}
+}
+
+func code(err error) Code {
+	var violation *Violation
+	if errors.As(err, &violation) {
+		return violation.Code
+	}
+	return ""
+}
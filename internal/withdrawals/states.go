@@ -0,0 +1,31 @@
+package withdrawals
+
+import "gophermart/internal/types"
+
+// transitions lists the statuses a withdrawal may move to. Partially
+// reversed withdrawals may be reversed again until nothing is left.
+var transitions = map[types.WithdrawalStatus][]types.WithdrawalStatus{
+	types.WithdrawalPendingReview: {
+		types.WithdrawalCompleted,
+		types.WithdrawalRejected,
+	},
+	types.WithdrawalCompleted: {
+		types.WithdrawalPartiallyReversed,
+		types.WithdrawalReversed,
+	},
+	types.WithdrawalPartiallyReversed: {
+		types.WithdrawalPartiallyReversed,
+		types.WithdrawalReversed,
+	},
+}
+
+// CanTransition tells a withdrawal in status from may move to status to.
+func CanTransition(from types.WithdrawalStatus, to types.WithdrawalStatus) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+
+	return false
+}
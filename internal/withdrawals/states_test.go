@@ -0,0 +1,31 @@
+package withdrawals
+
+import (
+	"gophermart/internal/types"
+	"testing"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		name string
+		from types.WithdrawalStatus
+		to   types.WithdrawalStatus
+		want bool
+	}{
+		{"approve", types.WithdrawalPendingReview, types.WithdrawalCompleted, true},
+		{"reject", types.WithdrawalPendingReview, types.WithdrawalRejected, true},
+		{"reverse pending", types.WithdrawalPendingReview, types.WithdrawalReversed, false},
+		{"reject completed", types.WithdrawalCompleted, types.WithdrawalRejected, false},
+		{"reverse completed", types.WithdrawalCompleted, types.WithdrawalReversed, true},
+		{"reverse again", types.WithdrawalPartiallyReversed, types.WithdrawalPartiallyReversed, true},
+		{"approve rejected", types.WithdrawalRejected, types.WithdrawalCompleted, false},
+		{"reverse reversed", types.WithdrawalReversed, types.WithdrawalReversed, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CanTransition(tt.from, tt.to); got != tt.want {
+				t.Errorf("transition is different: got=%v want=%v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.bll_orders ADD COLUMN IF NOT EXISTS check_attempts integer DEFAULT 0 NOT NULL;
+ALTER TABLE public.bll_orders ADD COLUMN IF NOT EXISTS next_check_at timestamp without time zone DEFAULT current_timestamp NULL;
+
+UPDATE public.bll_orders SET next_check_at = NULL WHERE status NOT IN ('NEW', 'PROCESSING');
+
+CREATE INDEX IF NOT EXISTS ix__bll_orders__status__next_check_at ON public.bll_orders (status, next_check_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS public.ix__bll_orders__status__next_check_at;
+ALTER TABLE public.bll_orders DROP COLUMN IF EXISTS next_check_at;
+ALTER TABLE public.bll_orders DROP COLUMN IF EXISTS check_attempts;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.bll_orders ADD COLUMN IF NOT EXISTS lease_owner varchar NULL;
+ALTER TABLE public.bll_orders ADD COLUMN IF NOT EXISTS lease_expires_at timestamp without time zone NULL;
+
+CREATE INDEX IF NOT EXISTS ix__bll_orders__lease_owner ON public.bll_orders (lease_owner);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS public.ix__bll_orders__lease_owner;
+ALTER TABLE public.bll_orders DROP COLUMN IF EXISTS lease_expires_at;
+ALTER TABLE public.bll_orders DROP COLUMN IF EXISTS lease_owner;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Balance updates are applied by user_id, so the earliest row of a user
+-- has seen every movement. Later duplicates are dropped.
+DELETE FROM public.bll_balance b
+USING (
+	SELECT
+		balance_id,
+		row_number() OVER (PARTITION BY user_id ORDER BY created_at, balance_id) AS rn
+	FROM public.bll_balance
+) d
+WHERE b.balance_id = d.balance_id AND d.rn > 1;
+
+CREATE UNIQUE INDEX IF NOT EXISTS ux__bll_balance__user_id ON public.bll_balance (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS ux__bll_orders__number ON public.bll_orders (number);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS public.ux__bll_orders__number;
+DROP INDEX IF EXISTS public.ux__bll_balance__user_id;
+-- +goose StatementEnd
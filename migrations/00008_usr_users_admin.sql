@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Admins are granted manually: UPDATE public.usr_users SET is_admin = true WHERE login = '...';
+ALTER TABLE public.usr_users ADD COLUMN IF NOT EXISTS is_admin boolean DEFAULT false NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE public.usr_users DROP COLUMN IF EXISTS is_admin;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS public.bll_dead_letters (
+	dead_letter_id uuid DEFAULT gen_random_uuid() NOT NULL,
+	order_id uuid NOT NULL,
+	user_id uuid NOT NULL,
+	number varchar NOT NULL,
+	last_error varchar NOT NULL,
+	attempts integer DEFAULT 0 NOT NULL,
+	first_failed_at timestamp without time zone DEFAULT current_timestamp NOT NULL,
+	last_failed_at timestamp without time zone DEFAULT current_timestamp NOT NULL,
+	CONSTRAINT bll_dead_letters_pk PRIMARY KEY (dead_letter_id),
+	CONSTRAINT bll_dead_letters_unique UNIQUE (order_id)
+);
+
+ALTER TABLE public.bll_dead_letters ADD CONSTRAINT fk__bll_dead_letters__order_id__bll_orders FOREIGN KEY (order_id) REFERENCES public.bll_orders(order_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS public.bll_dead_letters;
+-- +goose StatementEnd
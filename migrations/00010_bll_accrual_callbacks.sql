@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS public.bll_accrual_callbacks (
+	signature varchar NOT NULL,
+	number varchar NOT NULL,
+	received_at timestamp without time zone DEFAULT current_timestamp NOT NULL,
+	CONSTRAINT bll_accrual_callbacks_pk PRIMARY KEY (signature)
+);
+
+CREATE INDEX IF NOT EXISTS ix__bll_accrual_callbacks__received_at ON public.bll_accrual_callbacks (received_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS public.bll_accrual_callbacks;
+-- +goose StatementEnd
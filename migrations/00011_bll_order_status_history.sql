@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS public.bll_order_status_history (
+	history_id uuid DEFAULT gen_random_uuid() NOT NULL,
+	order_id uuid NOT NULL,
+	from_status varchar NULL,
+	status varchar NOT NULL,
+	source varchar NOT NULL,
+	response jsonb NULL,
+	changed_at timestamp without time zone DEFAULT current_timestamp NOT NULL,
+	CONSTRAINT bll_order_status_history_pk PRIMARY KEY (history_id)
+);
+
+ALTER TABLE public.bll_order_status_history ADD CONSTRAINT fk__bll_order_status_history__order_id__bll_orders FOREIGN KEY (order_id) REFERENCES public.bll_orders(order_id);
+
+CREATE INDEX IF NOT EXISTS ix__bll_order_status_history__order_id ON public.bll_order_status_history (order_id, changed_at);
+
+-- Existing orders get their upload, and the status they reached
+-- before history was kept as of the migration time.
+INSERT INTO public.bll_order_status_history (order_id, from_status, status, source, changed_at)
+SELECT order_id, NULL, 'NEW', 'USER', uploaded_at FROM public.bll_orders;
+
+INSERT INTO public.bll_order_status_history (order_id, from_status, status, source, changed_at)
+SELECT order_id, 'NEW', status, 'BACKFILL', (now() AT TIME ZONE 'UTC') FROM public.bll_orders WHERE status <> 'NEW';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS public.bll_order_status_history;
+-- +goose StatementEnd
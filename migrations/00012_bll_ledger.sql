@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS public.bll_ledger (
+	entry_id uuid DEFAULT gen_random_uuid() NOT NULL,
+	transaction_id uuid NOT NULL,
+	user_id uuid NULL,
+	account varchar NOT NULL,
+	kind varchar NOT NULL,
+	amount numeric NOT NULL,
+	reference varchar NULL,
+	created_at timestamp without time zone DEFAULT current_timestamp NOT NULL,
+	CONSTRAINT bll_ledger_pk PRIMARY KEY (entry_id)
+);
+
+ALTER TABLE public.bll_ledger ADD CONSTRAINT fk__bll_ledger__user_id__usr_users FOREIGN KEY (user_id) REFERENCES public.usr_users(user_id);
+
+CREATE INDEX IF NOT EXISTS ix__bll_ledger__user_id ON public.bll_ledger (user_id, created_at);
+CREATE INDEX IF NOT EXISTS ix__bll_ledger__transaction_id ON public.bll_ledger (transaction_id);
+
+-- Opening balances: one transaction per user carrying the balance
+-- accumulated before the ledger, so ledger sums match bll_balance.
+WITH opening AS (
+	SELECT gen_random_uuid() AS transaction_id, user_id, current, withdrawn
+	FROM public.bll_balance
+	WHERE current <> 0 OR withdrawn <> 0
+)
+INSERT INTO public.bll_ledger (transaction_id, user_id, account, kind, amount, created_at)
+SELECT transaction_id, user_id, 'USER_CURRENT', 'OPENING', current, (now() AT TIME ZONE 'UTC')
+FROM opening WHERE current <> 0
+UNION ALL
+SELECT transaction_id, user_id, 'USER_WITHDRAWN', 'OPENING', withdrawn, (now() AT TIME ZONE 'UTC')
+FROM opening WHERE withdrawn <> 0
+UNION ALL
+SELECT transaction_id, NULL, 'SYSTEM_OPENING', 'OPENING', -(current + withdrawn), (now() AT TIME ZONE 'UTC')
+FROM opening WHERE current + withdrawn <> 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS public.bll_ledger;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS public.usr_idempotency_keys (
+	user_id uuid NOT NULL,
+	key varchar NOT NULL,
+	fingerprint varchar NOT NULL,
+	status_code integer NULL,
+	content_type varchar NULL,
+	response text NULL,
+	created_at timestamp without time zone DEFAULT current_timestamp NOT NULL,
+	expires_at timestamp without time zone NOT NULL,
+	CONSTRAINT usr_idempotency_keys_pk PRIMARY KEY (user_id, key)
+);
+
+ALTER TABLE public.usr_idempotency_keys ADD CONSTRAINT fk__usr_idempotency_keys__user_id__usr_users FOREIGN KEY (user_id) REFERENCES public.usr_users(user_id);
+
+CREATE INDEX IF NOT EXISTS ix__usr_idempotency_keys__expires_at ON public.usr_idempotency_keys (expires_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS public.usr_idempotency_keys;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.wdr_withdrawals ADD COLUMN IF NOT EXISTS status varchar DEFAULT 'COMPLETED' NOT NULL;
+ALTER TABLE public.wdr_withdrawals ADD COLUMN IF NOT EXISTS reversed numeric DEFAULT 0 NOT NULL;
+
+CREATE TABLE IF NOT EXISTS public.wdr_reversals (
+	reversal_id uuid DEFAULT gen_random_uuid() NOT NULL,
+	withdrawal_id uuid NOT NULL,
+	user_id uuid NOT NULL,
+	sum numeric NOT NULL,
+	reason varchar NULL,
+	reversed_by uuid NOT NULL,
+	created_at timestamp without time zone DEFAULT current_timestamp NOT NULL,
+	CONSTRAINT wdr_reversals_pk PRIMARY KEY (reversal_id)
+);
+
+ALTER TABLE public.wdr_reversals ADD CONSTRAINT fk__wdr_reversals__withdrawal_id__wdr_withdrawals FOREIGN KEY (withdrawal_id) REFERENCES public.wdr_withdrawals(withdrawal_id);
+ALTER TABLE public.wdr_reversals ADD CONSTRAINT fk__wdr_reversals__user_id__usr_users FOREIGN KEY (user_id) REFERENCES public.usr_users(user_id);
+
+CREATE INDEX IF NOT EXISTS ix__wdr_reversals__user_id ON public.wdr_reversals (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS public.wdr_reversals;
+ALTER TABLE public.wdr_withdrawals DROP COLUMN IF EXISTS reversed;
+ALTER TABLE public.wdr_withdrawals DROP COLUMN IF EXISTS status;
+-- +goose StatementEnd
@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.bll_balance ADD COLUMN IF NOT EXISTS held numeric DEFAULT 0 NOT NULL;
+
+CREATE TABLE IF NOT EXISTS public.wdr_holds (
+	hold_id uuid DEFAULT gen_random_uuid() NOT NULL,
+	user_id uuid NOT NULL,
+	"order" varchar NOT NULL,
+	sum numeric NOT NULL,
+	status varchar NOT NULL,
+	withdrawal_id uuid NULL,
+	expires_at timestamp without time zone NOT NULL,
+	created_at timestamp without time zone DEFAULT current_timestamp NOT NULL,
+	updated_at timestamp without time zone DEFAULT current_timestamp NOT NULL,
+	CONSTRAINT wdr_holds_pk PRIMARY KEY (hold_id)
+);
+
+ALTER TABLE public.wdr_holds ADD CONSTRAINT fk__wdr_holds__user_id__usr_users FOREIGN KEY (user_id) REFERENCES public.usr_users(user_id);
+ALTER TABLE public.wdr_holds ADD CONSTRAINT fk__wdr_holds__withdrawal_id__wdr_withdrawals FOREIGN KEY (withdrawal_id) REFERENCES public.wdr_withdrawals(withdrawal_id);
+
+CREATE INDEX IF NOT EXISTS ix__wdr_holds__user_id ON public.wdr_holds (user_id);
+CREATE INDEX IF NOT EXISTS ix__wdr_holds__expires_at ON public.wdr_holds (expires_at) WHERE status = 'ACTIVE';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS public.wdr_holds;
+ALTER TABLE public.bll_balance DROP COLUMN IF EXISTS held;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS public.bll_point_lots (
+	lot_id uuid DEFAULT gen_random_uuid() NOT NULL,
+	user_id uuid NOT NULL,
+	amount numeric NOT NULL,
+	remaining numeric NOT NULL,
+	accrued_at timestamp without time zone DEFAULT current_timestamp NOT NULL,
+	CONSTRAINT bll_point_lots_pk PRIMARY KEY (lot_id)
+);
+
+ALTER TABLE public.bll_point_lots ADD CONSTRAINT fk__bll_point_lots__user_id__usr_users FOREIGN KEY (user_id) REFERENCES public.usr_users(user_id);
+
+CREATE INDEX IF NOT EXISTS ix__bll_point_lots__user_id ON public.bll_point_lots (user_id, accrued_at) WHERE remaining > 0;
+CREATE INDEX IF NOT EXISTS ix__bll_point_lots__accrued_at ON public.bll_point_lots (accrued_at) WHERE remaining > 0;
+
+-- Points accrued before lots were tracked start their life now,
+-- so they are the oldest lot of every user and are spent first.
+INSERT INTO public.bll_point_lots (user_id, amount, remaining, accrued_at)
+SELECT user_id, current, current, (now() AT TIME ZONE 'UTC')
+FROM public.bll_balance
+WHERE current > 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS public.bll_point_lots;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.usr_users ADD COLUMN IF NOT EXISTS tier varchar NULL;
+ALTER TABLE public.usr_users ADD COLUMN IF NOT EXISTS tier_updated_at timestamp without time zone NULL;
+
+CREATE INDEX IF NOT EXISTS ix__bll_ledger__accruals ON public.bll_ledger (user_id, created_at) WHERE kind = 'ACCRUAL' AND account = 'USER_CURRENT';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS public.ix__bll_ledger__accruals;
+ALTER TABLE public.usr_users DROP COLUMN IF EXISTS tier_updated_at;
+ALTER TABLE public.usr_users DROP COLUMN IF EXISTS tier;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS public.bll_transfers (
+	transfer_id uuid DEFAULT gen_random_uuid() NOT NULL,
+	sender_id uuid NOT NULL,
+	recipient_id uuid NOT NULL,
+	sum numeric NOT NULL,
+	created_at timestamp without time zone DEFAULT current_timestamp NOT NULL,
+	CONSTRAINT bll_transfers_pk PRIMARY KEY (transfer_id)
+);
+
+ALTER TABLE public.bll_transfers ADD CONSTRAINT fk__bll_transfers__sender_id__usr_users FOREIGN KEY (sender_id) REFERENCES public.usr_users(user_id);
+ALTER TABLE public.bll_transfers ADD CONSTRAINT fk__bll_transfers__recipient_id__usr_users FOREIGN KEY (recipient_id) REFERENCES public.usr_users(user_id);
+
+CREATE INDEX IF NOT EXISTS ix__bll_transfers__sender_id ON public.bll_transfers (sender_id, created_at);
+CREATE INDEX IF NOT EXISTS ix__bll_transfers__recipient_id ON public.bll_transfers (recipient_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS public.bll_transfers;
+-- +goose StatementEnd
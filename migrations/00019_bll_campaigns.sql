@@ -0,0 +1,43 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS public.bll_campaigns (
+	campaign_id uuid DEFAULT gen_random_uuid() NOT NULL,
+	name varchar NOT NULL,
+	kind varchar NOT NULL,
+	multiplier numeric NULL,
+	points numeric NULL,
+	starts_at timestamp without time zone NOT NULL,
+	ends_at timestamp without time zone NULL,
+	max_orders integer NULL,
+	tier varchar NULL,
+	active boolean DEFAULT true NOT NULL,
+	created_at timestamp without time zone DEFAULT current_timestamp NOT NULL,
+	updated_at timestamp without time zone DEFAULT current_timestamp NOT NULL,
+	CONSTRAINT bll_campaigns_pk PRIMARY KEY (campaign_id)
+);
+
+CREATE TABLE IF NOT EXISTS public.bll_campaign_bonuses (
+	bonus_id uuid DEFAULT gen_random_uuid() NOT NULL,
+	campaign_id uuid NOT NULL,
+	user_id uuid NOT NULL,
+	order_id uuid NOT NULL,
+	amount numeric NOT NULL,
+	status varchar NOT NULL,
+	created_at timestamp without time zone DEFAULT current_timestamp NOT NULL,
+	reversed_at timestamp without time zone NULL,
+	CONSTRAINT bll_campaign_bonuses_pk PRIMARY KEY (bonus_id),
+	CONSTRAINT bll_campaign_bonuses_campaign_order_unique UNIQUE (campaign_id, order_id)
+);
+
+ALTER TABLE public.bll_campaign_bonuses ADD CONSTRAINT fk__bll_campaign_bonuses__campaign_id__bll_campaigns FOREIGN KEY (campaign_id) REFERENCES public.bll_campaigns(campaign_id);
+ALTER TABLE public.bll_campaign_bonuses ADD CONSTRAINT fk__bll_campaign_bonuses__user_id__usr_users FOREIGN KEY (user_id) REFERENCES public.usr_users(user_id);
+ALTER TABLE public.bll_campaign_bonuses ADD CONSTRAINT fk__bll_campaign_bonuses__order_id__bll_orders FOREIGN KEY (order_id) REFERENCES public.bll_orders(order_id);
+
+CREATE INDEX IF NOT EXISTS ix__bll_campaigns__active ON public.bll_campaigns (starts_at) WHERE active;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS public.bll_campaign_bonuses;
+DROP TABLE IF EXISTS public.bll_campaigns;
+-- +goose StatementEnd
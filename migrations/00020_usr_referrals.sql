@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.usr_users ADD COLUMN IF NOT EXISTS referral_code varchar NULL;
+UPDATE public.usr_users SET referral_code = upper(substr(md5(user_id::text), 1, 8)) WHERE referral_code IS NULL;
+ALTER TABLE public.usr_users ALTER COLUMN referral_code SET NOT NULL;
+ALTER TABLE public.usr_users ADD CONSTRAINT usr_users_referral_code_unique UNIQUE (referral_code);
+
+CREATE TABLE IF NOT EXISTS public.usr_referrals (
+	referral_id uuid DEFAULT gen_random_uuid() NOT NULL,
+	referrer_id uuid NOT NULL,
+	referee_id uuid NOT NULL,
+	status varchar NOT NULL,
+	order_id uuid NULL,
+	bonus numeric NULL,
+	created_at timestamp without time zone DEFAULT current_timestamp NOT NULL,
+	settled_at timestamp without time zone NULL,
+	CONSTRAINT usr_referrals_pk PRIMARY KEY (referral_id),
+	CONSTRAINT usr_referrals_referee_unique UNIQUE (referee_id),
+	CONSTRAINT usr_referrals_not_self CHECK (referrer_id <> referee_id)
+);
+
+ALTER TABLE public.usr_referrals ADD CONSTRAINT fk__usr_referrals__referrer_id__usr_users FOREIGN KEY (referrer_id) REFERENCES public.usr_users(user_id);
+ALTER TABLE public.usr_referrals ADD CONSTRAINT fk__usr_referrals__referee_id__usr_users FOREIGN KEY (referee_id) REFERENCES public.usr_users(user_id);
+ALTER TABLE public.usr_referrals ADD CONSTRAINT fk__usr_referrals__order_id__bll_orders FOREIGN KEY (order_id) REFERENCES public.bll_orders(order_id);
+
+CREATE INDEX IF NOT EXISTS ix__usr_referrals__referrer_id ON public.usr_referrals (referrer_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS public.usr_referrals;
+ALTER TABLE public.usr_users DROP CONSTRAINT IF EXISTS usr_users_referral_code_unique;
+ALTER TABLE public.usr_users DROP COLUMN IF EXISTS referral_code;
+-- +goose StatementEnd
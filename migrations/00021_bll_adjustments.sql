@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS public.bll_adjustments (
+	adjustment_id uuid DEFAULT gen_random_uuid() NOT NULL,
+	user_id uuid NOT NULL,
+	admin_id uuid NOT NULL,
+	amount numeric NOT NULL,
+	reason varchar NOT NULL,
+	comment varchar NOT NULL,
+	created_at timestamp without time zone DEFAULT current_timestamp NOT NULL,
+	CONSTRAINT bll_adjustments_pk PRIMARY KEY (adjustment_id)
+);
+
+ALTER TABLE public.bll_adjustments ADD CONSTRAINT fk__bll_adjustments__user_id__usr_users FOREIGN KEY (user_id) REFERENCES public.usr_users(user_id);
+ALTER TABLE public.bll_adjustments ADD CONSTRAINT fk__bll_adjustments__admin_id__usr_users FOREIGN KEY (admin_id) REFERENCES public.usr_users(user_id);
+
+CREATE INDEX IF NOT EXISTS ix__bll_adjustments__user_id ON public.bll_adjustments (user_id, created_at);
+CREATE INDEX IF NOT EXISTS ix__bll_adjustments__admin_id ON public.bll_adjustments (admin_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS public.bll_adjustments;
+-- +goose StatementEnd
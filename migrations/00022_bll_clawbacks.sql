@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS public.bll_clawbacks (
+	clawback_id uuid DEFAULT gen_random_uuid() NOT NULL,
+	order_id uuid NOT NULL,
+	user_id uuid NOT NULL,
+	number varchar NOT NULL,
+	amount numeric NOT NULL,
+	recovered numeric NOT NULL,
+	policy varchar NOT NULL,
+	source varchar NOT NULL,
+	created_at timestamp without time zone DEFAULT current_timestamp NOT NULL,
+	updated_at timestamp without time zone DEFAULT current_timestamp NOT NULL,
+	CONSTRAINT bll_clawbacks_pk PRIMARY KEY (clawback_id),
+	CONSTRAINT bll_clawbacks_order_unique UNIQUE (order_id)
+);
+
+ALTER TABLE public.bll_clawbacks ADD CONSTRAINT fk__bll_clawbacks__order_id__bll_orders FOREIGN KEY (order_id) REFERENCES public.bll_orders(order_id);
+ALTER TABLE public.bll_clawbacks ADD CONSTRAINT fk__bll_clawbacks__user_id__usr_users FOREIGN KEY (user_id) REFERENCES public.usr_users(user_id);
+
+CREATE INDEX IF NOT EXISTS ix__bll_clawbacks__outstanding ON public.bll_clawbacks (user_id, created_at) WHERE recovered < amount AND policy = 'BLOCK_WITHDRAWALS';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS public.bll_clawbacks;
+-- +goose StatementEnd
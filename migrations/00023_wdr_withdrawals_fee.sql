@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.wdr_withdrawals ADD COLUMN IF NOT EXISTS fee numeric DEFAULT 0 NOT NULL;
+
+CREATE INDEX IF NOT EXISTS ix__wdr_withdrawals__user_id_processed_at ON public.wdr_withdrawals (user_id, processed_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS public.ix__wdr_withdrawals__user_id_processed_at;
+ALTER TABLE public.wdr_withdrawals DROP COLUMN IF EXISTS fee;
+-- +goose StatementEnd
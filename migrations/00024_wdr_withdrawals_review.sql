@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.wdr_withdrawals ADD COLUMN IF NOT EXISTS reviewed_by uuid NULL;
+ALTER TABLE public.wdr_withdrawals ADD COLUMN IF NOT EXISTS reviewed_at timestamp without time zone NULL;
+ALTER TABLE public.wdr_withdrawals ADD COLUMN IF NOT EXISTS review_reason varchar NULL;
+
+ALTER TABLE public.wdr_withdrawals ADD CONSTRAINT fk__wdr_withdrawals__reviewed_by__usr_users FOREIGN KEY (reviewed_by) REFERENCES public.usr_users(user_id);
+
+CREATE INDEX IF NOT EXISTS ix__wdr_withdrawals__pending_review ON public.wdr_withdrawals (processed_at) WHERE status = 'PENDING_REVIEW';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS public.ix__wdr_withdrawals__pending_review;
+ALTER TABLE public.wdr_withdrawals DROP CONSTRAINT IF EXISTS fk__wdr_withdrawals__reviewed_by__usr_users;
+ALTER TABLE public.wdr_withdrawals DROP COLUMN IF EXISTS review_reason;
+ALTER TABLE public.wdr_withdrawals DROP COLUMN IF EXISTS reviewed_at;
+ALTER TABLE public.wdr_withdrawals DROP COLUMN IF EXISTS reviewed_by;
+-- +goose StatementEnd
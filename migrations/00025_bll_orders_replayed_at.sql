@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.bll_orders ADD COLUMN IF NOT EXISTS replayed_at timestamp without time zone NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE public.bll_orders DROP COLUMN IF EXISTS replayed_at;
+-- +goose StatementEnd
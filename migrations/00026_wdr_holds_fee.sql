@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.wdr_holds ADD COLUMN IF NOT EXISTS fee numeric DEFAULT 0 NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE public.wdr_holds DROP COLUMN IF EXISTS fee;
+-- +goose StatementEnd
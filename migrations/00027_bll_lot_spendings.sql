@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS public.bll_lot_spendings (
+	spending_id uuid DEFAULT gen_random_uuid() NOT NULL,
+	lot_id uuid NOT NULL,
+	spent_for varchar NOT NULL,
+	amount numeric NOT NULL,
+	returned numeric DEFAULT 0 NOT NULL,
+	created_at timestamp without time zone DEFAULT current_timestamp NOT NULL,
+	CONSTRAINT bll_lot_spendings_pk PRIMARY KEY (spending_id)
+);
+
+ALTER TABLE public.bll_lot_spendings ADD CONSTRAINT fk__bll_lot_spendings__lot_id__bll_point_lots FOREIGN KEY (lot_id) REFERENCES public.bll_point_lots(lot_id);
+
+CREATE INDEX IF NOT EXISTS ix__bll_lot_spendings__spent_for ON public.bll_lot_spendings (spent_for) WHERE returned < amount;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS public.bll_lot_spendings;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE public.bll_clawbacks DROP CONSTRAINT IF EXISTS bll_clawbacks_order_unique;
+ALTER TABLE public.bll_clawbacks ADD CONSTRAINT bll_clawbacks_order_user_unique UNIQUE (order_id, user_id);
+
+ALTER TABLE public.usr_referrals ADD COLUMN IF NOT EXISTS reversed_at timestamp without time zone NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE public.usr_referrals DROP COLUMN IF EXISTS reversed_at;
+
+ALTER TABLE public.bll_clawbacks DROP CONSTRAINT IF EXISTS bll_clawbacks_order_user_unique;
+ALTER TABLE public.bll_clawbacks ADD CONSTRAINT bll_clawbacks_order_unique UNIQUE (order_id);
+-- +goose StatementEnd
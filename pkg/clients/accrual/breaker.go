@@ -0,0 +1,99 @@
+package accrual
+
+import (
+	"sync"
+	"time"
+)
+
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "CLOSED"
+	BreakerOpen     BreakerState = "OPEN"
+	BreakerHalfOpen BreakerState = "HALF_OPEN"
+)
+
+// circuitBreaker opens after threshold consecutive failures and rejects calls
+// for openTimeout. Then a single probe call is let through in the half-open
+// state: its success closes the breaker, its failure opens it again.
+type circuitBreaker struct {
+	mu          sync.Mutex
+	state       BreakerState
+	failures    int
+	threshold   int
+	openTimeout time.Duration
+	openedAt    time.Time
+	probing     bool
+	onChange    func(from, to BreakerState)
+}
+
+func newCircuitBreaker(threshold int, openTimeout time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		state:       BreakerClosed,
+		threshold:   threshold,
+		openTimeout: openTimeout,
+	}
+}
+
+func (b *circuitBreaker) Allow(now time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if wait := b.openedAt.Add(b.openTimeout).Sub(now); wait > 0 {
+			return &CircuitOpenError{RetryAfter: wait}
+		}
+		b.setState(BreakerHalfOpen)
+		b.probing = true
+		return nil
+	case BreakerHalfOpen:
+		if b.probing {
+			return &CircuitOpenError{RetryAfter: b.openTimeout}
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+func (b *circuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.probing = false
+	b.setState(BreakerClosed)
+}
+
+func (b *circuitBreaker) Failure(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.openedAt = now
+		b.setState(BreakerOpen)
+	}
+}
+
+func (b *circuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+func (b *circuitBreaker) setState(state BreakerState) {
+	if b.state == state {
+		return
+	}
+
+	from := b.state
+	b.state = state
+	if b.onChange != nil {
+		b.onChange(from, state)
+	}
+}
@@ -0,0 +1,51 @@
+package accrual
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	b := newCircuitBreaker(2, 10*time.Second)
+
+	b.Failure(now)
+	if b.State() != BreakerClosed {
+		t.Fatalf("breaker opened before threshold: %s", b.State())
+	}
+
+	b.Failure(now)
+	if b.State() != BreakerOpen {
+		t.Fatalf("breaker didn't open on threshold: %s", b.State())
+	}
+
+	var circuitOpenErr *CircuitOpenError
+	if err := b.Allow(now.Add(time.Second)); !errors.As(err, &circuitOpenErr) {
+		t.Fatalf("open breaker allowed a call: %v", err)
+	}
+
+	if err := b.Allow(now.Add(11 * time.Second)); err != nil {
+		t.Fatalf("breaker didn't let a probe through: %s", err)
+	}
+	if b.State() != BreakerHalfOpen {
+		t.Fatalf("breaker isn't half-open: %s", b.State())
+	}
+	if err := b.Allow(now.Add(11 * time.Second)); err == nil {
+		t.Fatal("half-open breaker let a second probe through")
+	}
+
+	b.Failure(now.Add(12 * time.Second))
+	if b.State() != BreakerOpen {
+		t.Fatalf("failed probe didn't reopen the breaker: %s", b.State())
+	}
+
+	if err := b.Allow(now.Add(23 * time.Second)); err != nil {
+		t.Fatalf("breaker didn't let a probe through: %s", err)
+	}
+	b.Success()
+	if b.State() != BreakerClosed {
+		t.Fatalf("successful probe didn't close the breaker: %s", b.State())
+	}
+}
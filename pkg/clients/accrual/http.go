@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"gophermart/internal/log"
+	"net/http"
 	"strings"
 	"time"
 
@@ -14,6 +15,29 @@ import (
 type AccrualHTTPClient struct {
 	client  *resty.Client
 	baseURL string
+	limiter *tokenBucket
+	breaker *circuitBreaker
+}
+
+type Option func(c *AccrualHTTPClient)
+
+// WithRateLimit limits outgoing requests to rps per second with bursts up to burst.
+func WithRateLimit(rps float64, burst int) Option {
+	return func(c *AccrualHTTPClient) {
+		if rps > 0 {
+			c.limiter = newTokenBucket(rps, burst)
+		}
+	}
+}
+
+// WithCircuitBreaker stops calling the accrual system for openTimeout
+// after threshold consecutive failures.
+func WithCircuitBreaker(threshold int, openTimeout time.Duration) Option {
+	return func(c *AccrualHTTPClient) {
+		if threshold > 0 {
+			c.breaker = newCircuitBreaker(threshold, openTimeout)
+		}
+	}
 }
 
 func NewAccrualClient(
@@ -22,6 +46,7 @@ func NewAccrualClient(
 	retryCount int,
 	retryWaitTime time.Duration,
 	retryMaxWaitTime time.Duration,
+	opts ...Option,
 ) *AccrualHTTPClient {
 	if !strings.HasPrefix(baseURL, "http") {
 		baseURL = "http://" + baseURL
@@ -37,9 +62,59 @@ func NewAccrualClient(
 		SetRetryWaitTime(retryWaitTime).
 		SetRetryMaxWaitTime(retryMaxWaitTime)
 
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.breaker != nil {
+		c.breaker.onChange = func(from, to BreakerState) {
+			log.Warn(ctx, fmt.Sprintf("accrual circuit breaker: %s -> %s", from, to))
+		}
+	}
+
 	return c
 }
 
+// BreakerState reports the circuit breaker state, CLOSED when it is disabled.
+func (c *AccrualHTTPClient) BreakerState() BreakerState {
+	if c.breaker == nil {
+		return BreakerClosed
+	}
+
+	return c.breaker.State()
+}
+
+// execute runs the request through the rate limiter and the circuit breaker.
+// Transport errors and 5xx responses count as breaker failures.
+func (c *AccrualHTTPClient) execute(
+	ctx context.Context,
+	send func() (*resty.Response, error),
+) (*resty.Response, error) {
+	if c.limiter != nil {
+		if err := c.limiter.Wait(ctx); err != nil {
+			return nil, errors.Wrapf(err, "failed to wait for rate limiter")
+		}
+	}
+
+	if c.breaker != nil {
+		if err := c.breaker.Allow(time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := send()
+
+	if c.breaker != nil {
+		if err != nil || resp.StatusCode() >= http.StatusInternalServerError {
+			c.breaker.Failure(time.Now())
+		} else {
+			c.breaker.Success()
+		}
+	}
+
+	return resp, err
+}
+
 func (c *AccrualHTTPClient) CreateGoods(
 	ctx context.Context,
 	schema GoodsCreate,
@@ -48,14 +123,15 @@ func (c *AccrualHTTPClient) CreateGoods(
 		SetContext(ctx).
 		SetBody(&schema)
 
-	resp, err := request.Post(c.baseURL + "/api/goods")
+	resp, err := c.execute(ctx, func() (*resty.Response, error) {
+		return request.Post(c.baseURL + "/api/goods")
+	})
 	if err != nil {
 		return errors.Wrapf(err, "failed to create goods")
 	}
 
 	if resp.IsError() {
-		return errors.Wrapf(
-			err,
+		return errors.Errorf(
 			"failed to create goods: status=%s body=%s",
 			resp.Status(),
 			resp.Body(),
@@ -82,14 +158,15 @@ func (c *AccrualHTTPClient) CreateOrder(
 		SetContext(ctx).
 		SetBody(&schema)
 
-	resp, err := request.Post(c.baseURL + "/api/orders")
+	resp, err := c.execute(ctx, func() (*resty.Response, error) {
+		return request.Post(c.baseURL + "/api/orders")
+	})
 	if err != nil {
 		return errors.Wrapf(err, "failed to create order")
 	}
 
 	if resp.IsError() {
-		return errors.Wrapf(
-			err,
+		return errors.Errorf(
 			"failed to create order: status=%s body=%s",
 			resp.Status(),
 			resp.Body(),
@@ -118,14 +195,21 @@ func (c *AccrualHTTPClient) GetOrder(
 		SetContext(ctx).
 		SetResult(&orderModel)
 
-	resp, err := request.Get(fmt.Sprintf("%s/api/orders/%s", c.baseURL, order))
+	resp, err := c.execute(ctx, func() (*resty.Response, error) {
+		return request.Get(fmt.Sprintf("%s/api/orders/%s", c.baseURL, order))
+	})
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to get order")
 	}
 
+	if resp.StatusCode() == http.StatusTooManyRequests {
+		return nil, &RateLimitError{
+			RetryAfter: parseRetryAfter(resp.Header().Get("Retry-After"), time.Now()),
+		}
+	}
+
 	if resp.IsError() {
-		return nil, errors.Wrapf(
-			err,
+		return nil, errors.Errorf(
 			"failed to get order: status=%s body=%s",
 			resp.Status(),
 			resp.Body(),
@@ -1,5 +1,7 @@
 package accrual
 
+import "gophermart/internal/types"
+
 type GoodsCreate struct {
 	Match      string `json:"match"`
 	Reward     int    `json:"reward"`
@@ -17,7 +19,7 @@ type OrderCreate struct {
 }
 
 type OrderRead struct {
-	Order   string  `json:"order"`
-	Status  string  `json:"status"`
-	Accrual float64 `json:"accrual"`
+	Order   string      `json:"order"`
+	Status  string      `json:"status"`
+	Accrual types.Money `json:"accrual"`
 }